@@ -0,0 +1,116 @@
+/*
+Copyright 2023 SUSE.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+
+	"sigs.k8s.io/yaml"
+
+	configv1alpha1 "github.com/Danil-Grigorev/rancher-bind/pkg/apis/config/v1alpha1"
+)
+
+// bindConfigFlags binds the flags overriding the fields of the defaulted component config file.
+func bindConfigFlags(fs *flag.FlagSet, c *configv1alpha1.BackendConfiguration) {
+	fs.IntVar(c.Workers.APIServiceNamespace, "apiservicenamespace-workers", *c.Workers.APIServiceNamespace,
+		"The worker count of the APIServiceNamespace controller.")
+	fs.IntVar(c.Workers.APIServiceExport, "apiserviceexport-workers", *c.Workers.APIServiceExport,
+		"The worker count of the APIServiceExport controller.")
+	fs.IntVar(c.Workers.APIServiceExportRequest, "apiserviceexportrequest-workers", *c.Workers.APIServiceExportRequest,
+		"The worker count of the APIServiceExportRequest controller.")
+	fs.DurationVar(&c.InformerResync.Duration, "informer-resync", c.InformerResync.Duration,
+		"The resync period of the shared informers.")
+	fs.StringVar((*string)(&c.ConsumerScope), "consumer-scope", string(c.ConsumerScope),
+		"The informer scope allowed to consumers, Cluster or Namespaced.")
+	fs.StringVar((*string)(&c.ExportInformerScope), "export-informer-scope", string(c.ExportInformerScope),
+		"The informer scope of the APIServiceExports created for the consumer export requests, Cluster or Namespaced.")
+	fs.StringVar(&c.NamespacePrefix, "namespace-prefix", c.NamespacePrefix,
+		"The prefix of the namespaces created for each consumer.")
+	fs.DurationVar(&c.ConsumerGracePeriod.Duration, "consumer-grace-period", c.ConsumerGracePeriod.Duration,
+		"How long the rancher objects of a deleted consumer are kept before they are removed.")
+	fs.DurationVar(&c.Heartbeat.StaleAfter.Duration, "heartbeat-stale-after", c.Heartbeat.StaleAfter.Duration,
+		"How long a ClusterBinding can go without a konnector heartbeat before it is marked stale.")
+	fs.DurationVar(&c.Heartbeat.SuspendAfter.Duration, "heartbeat-suspend-after", c.Heartbeat.SuspendAfter.Duration,
+		"How long a ClusterBinding can go without a konnector heartbeat before the consumer rancher users are disabled, zero never suspends.")
+	fs.StringVar((*string)(&c.Projects.Mode), "project-mode", string(c.Projects.Mode),
+		"The rancher projects of the consumer service namespaces, None, PerConsumer or Shared.")
+	fs.StringVar(&c.Projects.ProjectID, "project-id", c.Projects.ProjectID,
+		"The local:<project> ID of the project holding the service namespaces of all consumers with the Shared project mode.")
+	fs.BoolVar(&c.FleetWorkspaces, "fleet-workspaces", c.FleetWorkspaces,
+		"Make each consumer service namespace a fleet workspace, so rancher provisions the clusters created through kube-bind.")
+	fs.Var((*float32Value)(c.ClientConnection.QPS), "kube-api-qps",
+		"The QPS of all the clients talking to the kube-apiserver.")
+	fs.IntVar(c.ClientConnection.Burst, "kube-api-burst", *c.ClientConnection.Burst,
+		"The burst of all the clients talking to the kube-apiserver.")
+}
+
+// loadConfig returns the validated component config read from the file, overridden by the
+// config flags set on the command line. Without a file the defaults are used.
+func loadConfig(path string, fs *flag.FlagSet) (*configv1alpha1.BackendConfiguration, error) {
+	c := &configv1alpha1.BackendConfiguration{}
+	if path != "" {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("unable to read config file: %w", err)
+		}
+		if err := yaml.UnmarshalStrict(data, c); err != nil {
+			return nil, fmt.Errorf("unable to decode config file %s: %w", path, err)
+		}
+		if c.APIVersion == "" || c.Kind == "" {
+			return nil, fmt.Errorf("config file %s must set apiVersion %s and kind %s", path,
+				configv1alpha1.GroupVersion.String(), configv1alpha1.BackendConfigurationKind)
+		}
+	}
+	configv1alpha1.SetDefaults(c)
+
+	overrides := flag.NewFlagSet("config", flag.ContinueOnError)
+	bindConfigFlags(overrides, c)
+	var err error
+	fs.Visit(func(f *flag.Flag) {
+		if overrides.Lookup(f.Name) != nil && err == nil {
+			err = overrides.Set(f.Name, f.Value.String())
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if errs := configv1alpha1.Validate(c); len(errs) > 0 {
+		return nil, fmt.Errorf("invalid config: %w", errs.ToAggregate())
+	}
+
+	return c, nil
+}
+
+// float32Value is a flag.Value setting a float32.
+type float32Value float32
+
+func (f *float32Value) String() string {
+	return strconv.FormatFloat(float64(*f), 'g', -1, 32)
+}
+
+func (f *float32Value) Set(value string) error {
+	v, err := strconv.ParseFloat(value, 32)
+	if err != nil {
+		return err
+	}
+	*f = float32Value(v)
+	return nil
+}
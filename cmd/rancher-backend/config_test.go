@@ -0,0 +1,92 @@
+/*
+Copyright 2023 SUSE.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+	"k8s.io/utils/pointer"
+
+	kubebindv1alpha1 "github.com/kube-bind/kube-bind/pkg/apis/kubebind/v1alpha1"
+
+	configv1alpha1 "github.com/Danil-Grigorev/rancher-bind/pkg/apis/config/v1alpha1"
+)
+
+func TestLoadConfig(t *testing.T) {
+	g := NewWithT(t)
+
+	newFlagSet := func(args ...string) *flag.FlagSet {
+		fs := flag.NewFlagSet("test", flag.ContinueOnError)
+		bindConfigFlags(fs, configv1alpha1.NewDefaultBackendConfiguration())
+		g.Expect(fs.Parse(args)).To(Succeed())
+		return fs
+	}
+
+	c, err := loadConfig("", newFlagSet())
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(c).To(Equal(configv1alpha1.NewDefaultBackendConfiguration()))
+
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	g.Expect(os.WriteFile(path, []byte(`apiVersion: config.rancher.kube-bind.io/v1alpha1
+kind: BackendConfiguration
+workers:
+  apiServiceExportRequest: 4
+informerResync: 1h
+consumerScope: Namespaced
+consumerGracePeriod: 0s
+clientConnection:
+  qps: 20
+`), 0o600)).To(Succeed())
+
+	c, err = loadConfig(path, newFlagSet("--kube-api-qps=7.5", "--apiserviceexport-workers=2", "--fleet-workspaces"))
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(c.Workers).To(Equal(configv1alpha1.WorkersConfiguration{
+		APIServiceNamespace:     pointer.Int(1),
+		APIServiceExport:        pointer.Int(2),
+		APIServiceExportRequest: pointer.Int(4),
+	}))
+	g.Expect(c.InformerResync.Duration).To(Equal(time.Hour))
+	g.Expect(c.ConsumerScope).To(Equal(kubebindv1alpha1.NamespacedScope))
+	g.Expect(c.ExportInformerScope).To(Equal(kubebindv1alpha1.NamespacedScope))
+	g.Expect(c.NamespacePrefix).To(Equal("kube-bind-"))
+	g.Expect(c.ConsumerGracePeriod.Duration).To(BeZero())
+	g.Expect(*c.ClientConnection.QPS).To(BeEquivalentTo(7.5))
+	g.Expect(*c.ClientConnection.Burst).To(Equal(100))
+	g.Expect(c.FleetWorkspaces).To(BeTrue())
+
+	_, err = loadConfig(path, newFlagSet("--apiservicenamespace-workers=0", "--consumer-scope=Everything", "--export-informer-scope=Everything", "--namespace-prefix=Kube_", "--consumer-grace-period=-1m", "--heartbeat-suspend-after=5m", "--project-mode=Shared"))
+	g.Expect(err).To(MatchError(ContainSubstring("workers.apiServiceNamespace")))
+	g.Expect(err).To(MatchError(ContainSubstring("consumerScope")))
+	g.Expect(err).To(MatchError(ContainSubstring("exportInformerScope")))
+	g.Expect(err).To(MatchError(ContainSubstring("namespacePrefix")))
+	g.Expect(err).To(MatchError(ContainSubstring("consumerGracePeriod")))
+	g.Expect(err).To(MatchError(ContainSubstring("heartbeat.suspendAfter")))
+	g.Expect(err).To(MatchError(ContainSubstring("projects.projectID")))
+
+	g.Expect(os.WriteFile(path, []byte("workers:\n  apiServiceExport: 2\n"), 0o600)).To(Succeed())
+	_, err = loadConfig(path, newFlagSet())
+	g.Expect(err).To(MatchError(ContainSubstring("must set apiVersion")))
+
+	g.Expect(os.WriteFile(path, []byte("apiVersion: config.rancher.kube-bind.io/v1alpha1\nkind: BackendConfiguration\nworker: {}\n"), 0o600)).To(Succeed())
+	_, err = loadConfig(path, newFlagSet())
+	g.Expect(err).To(MatchError(ContainSubstring("unknown field")))
+}
@@ -19,21 +19,37 @@ package main
 import (
 	"flag"
 	"os"
+	"strings"
 
 	// Import all Kubernetes client auth plugins (e.g. Azure, GCP, OIDC, etc.)
 	// to ensure that exec-entrypoint and run can make use of them.
 	_ "k8s.io/client-go/plugin/pkg/client/auth"
 
+	corev1 "k8s.io/api/core/v1"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/runtime"
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
 	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/rest"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/healthz"
 	"sigs.k8s.io/controller-runtime/pkg/log/zap"
 	"sigs.k8s.io/controller-runtime/pkg/metrics/server"
 	"sigs.k8s.io/controller-runtime/pkg/webhook"
 
+	kubebindv1alpha1 "github.com/kube-bind/kube-bind/pkg/apis/kubebind/v1alpha1"
+
+	"github.com/Danil-Grigorev/rancher-bind/internal/backend"
 	"github.com/Danil-Grigorev/rancher-bind/internal/controller"
+	rancherwebhook "github.com/Danil-Grigorev/rancher-bind/internal/webhook"
+	configv1alpha1 "github.com/Danil-Grigorev/rancher-bind/pkg/apis/config/v1alpha1"
+	managementv3 "github.com/Danil-Grigorev/rancher-bind/pkg/apis/rancher/management/v3"
+	provisioningv1 "github.com/Danil-Grigorev/rancher-bind/pkg/apis/rancher/provisioning/v1"
+	rancherv1alpha1 "github.com/Danil-Grigorev/rancher-bind/pkg/apis/rancherbind/v1alpha1"
+	"github.com/Danil-Grigorev/rancher-bind/pkg/kubectl/bind-kubeconfig/plugin"
 	//+kubebuilder:scaffold:imports
 )
 
@@ -44,6 +60,11 @@ var (
 
 func init() {
 	utilruntime.Must(clientgoscheme.AddToScheme(scheme))
+	utilruntime.Must(apiextensionsv1.AddToScheme(scheme))
+	utilruntime.Must(managementv3.AddToScheme(scheme))
+	utilruntime.Must(provisioningv1.AddToScheme(scheme))
+	utilruntime.Must(rancherv1alpha1.AddToScheme(scheme))
+	utilruntime.Must(kubebindv1alpha1.AddToScheme(scheme))
 
 	//+kubebuilder:scaffold:scheme
 }
@@ -52,11 +73,42 @@ func main() {
 	var metricsAddr string
 	var enableLeaderElection bool
 	var probeAddr string
+	var configFile string
+	var exportedGroups string
+	var policyWebhookConfiguration string
+	backendOpts := backend.NewOptions()
 	flag.StringVar(&metricsAddr, "metrics-bind-address", ":8080", "The address the metric endpoint binds to.")
 	flag.StringVar(&probeAddr, "health-probe-bind-address", ":8081", "The address the probe endpoint binds to.")
 	flag.BoolVar(&enableLeaderElection, "leader-elect", false,
 		"Enable leader election for controller manager. "+
 			"Enabling this will ensure there is only one active controller manager.")
+	flag.StringVar(&configFile, "config", "",
+		"The BackendConfiguration file. The config flags set on the command line override its fields.")
+	bindConfigFlags(flag.CommandLine, configv1alpha1.NewDefaultBackendConfiguration())
+	flag.StringVar(&backendOpts.ListenAddress, "listen-address", backendOpts.ListenAddress,
+		"The address the kube-bind provider endpoints bind to. Set to empty to disable them.")
+	flag.StringVar(&backendOpts.TLSCertFile, "tls-cert-file", "", "The TLS certificate of the kube-bind provider endpoints.")
+	flag.StringVar(&backendOpts.TLSKeyFile, "tls-key-file", "", "The TLS key of the kube-bind provider endpoints.")
+	flag.BoolVar(&backendOpts.AllowInsecureHTTP, "allow-insecure-http", false,
+		"Serve the kube-bind provider endpoints without TLS, when TLS is terminated in front of the backend.")
+	flag.StringVar(&backendOpts.ExternalAddress, "external-address", "",
+		"The kube-apiserver address handed to consumers. Defaults to the in-cluster address.")
+	flag.StringVar(&backendOpts.ExternalCAFile, "external-ca-file", "", "The CA bundle of the external kube-apiserver address.")
+	flag.StringVar(&backendOpts.ExternalServerName, "external-server-name", "", "The TLS server name of the external kube-apiserver address.")
+	flag.StringVar(&backendOpts.RancherServerURL, "rancher-server-url", "",
+		"The rancher URL users authenticate against. Defaults to the server-url setting.")
+	flag.StringVar(&backendOpts.PrettyName, "pretty-name", backendOpts.PrettyName, "The provider name shown to consumers.")
+	flag.StringVar(&backendOpts.CookieSigningKey, "cookie-signing-key", "",
+		"Base64 encoded session cookie signing key. Loaded from the cookie secret when empty.")
+	flag.StringVar(&backendOpts.CookieEncryptionKey, "cookie-encryption-key", "",
+		"Base64 encoded 16, 24 or 32 bytes session cookie encryption key.")
+	flag.StringVar(&backendOpts.CookieSecret, "cookie-secret", "",
+		"The namespace/name of the Secret holding the session cookie keys shared by the replicas, created when missing.")
+	flag.StringVar(&exportedGroups, "exported-groups", "",
+		"Deprecated: list the resources in a RancherExportCatalog instead. Comma separated rancher API groups offered "+
+			"through the legacy-exported catalog, in addition to CRDs labelled kube-bind.io/exported=true.")
+	flag.StringVar(&policyWebhookConfiguration, "policy-webhook-configuration", rancherwebhook.DefaultPolicyWebhookConfiguration,
+		"The ValidatingWebhookConfiguration of the policy webhook, its rules are kept in sync with the RancherBindPolicies.")
 	opts := zap.Options{
 		Development: true,
 	}
@@ -65,8 +117,38 @@ func main() {
 
 	ctrl.SetLogger(zap.New(zap.UseFlagOptions(&opts)))
 
-	mgr, err := ctrl.NewManager(ctrl.GetConfigOrDie(), ctrl.Options{
+	legacyGroups := []string{}
+	for _, group := range strings.Split(exportedGroups, ",") {
+		if group = strings.TrimSpace(group); group != "" {
+			legacyGroups = append(legacyGroups, group)
+		}
+	}
+	if len(legacyGroups) > 0 {
+		setupLog.Info("--exported-groups is deprecated, list the resources in a RancherExportCatalog instead")
+	}
+
+	backendConfig, err := loadConfig(configFile, flag.CommandLine)
+	if err != nil {
+		setupLog.Error(err, "unable to load config")
+		os.Exit(1)
+	}
+	backendOpts.NamespacePrefix = backendConfig.NamespacePrefix
+	backendOpts.Scope = backendConfig.ConsumerScope
+
+	// All clients, including the kube-bind controllers, share this rest config.
+	restConfig := rest.AddUserAgent(ctrl.GetConfigOrDie(), "rancher-bind")
+	restConfig.QPS = *backendConfig.ClientConnection.QPS
+	restConfig.Burst = *backendConfig.ClientConnection.Burst
+
+	mgr, err := ctrl.NewManager(restConfig, ctrl.Options{
 		Scheme: scheme,
+		Cache: cache.Options{
+			SyncPeriod: &backendConfig.InformerResync.Duration,
+			// rancher keeps thousands of Secrets, only the ones written by the backend are cached.
+			ByObject: map[client.Object]cache.ByObject{
+				&corev1.Secret{}: {Label: labels.SelectorFromSet(labels.Set{plugin.ManagedByLabel: plugin.ManagedByValue})},
+			},
+		},
 		Metrics: server.Options{
 			BindAddress: metricsAddr,
 		},
@@ -93,13 +175,140 @@ func main() {
 		os.Exit(1)
 	}
 
+	config, err := controller.NewConfig(mgr)
+	if err != nil {
+		setupLog.Error(err, "unable to get config")
+		os.Exit(1)
+	}
+
 	if err = (&controller.RancherBindReconciler{
+		Client:  mgr.GetClient(),
+		Scheme:  mgr.GetScheme(),
+		Config:  config,
+		Backend: backendConfig,
+	}).SetupWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create controller", "controller", "RancherBind")
+		os.Exit(1)
+	}
+
+	if err = (&controller.ConsumerGCReconciler{
+		Client:      mgr.GetClient(),
+		GracePeriod: backendConfig.ConsumerGracePeriod.Duration,
+	}).SetupWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create controller", "controller", "ConsumerGC")
+		os.Exit(1)
+	}
+
+	if err = (&controller.HeartbeatReconciler{
+		Client:          mgr.GetClient(),
+		ClusterBindings: config.Informers.ClusterBindings,
+		Recorder:        mgr.GetEventRecorderFor("rancher-bind-heartbeat"),
+		StaleAfter:      backendConfig.Heartbeat.StaleAfter.Duration,
+		SuspendAfter:    backendConfig.Heartbeat.SuspendAfter.Duration,
+	}).SetupWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create controller", "controller", "Heartbeat")
+		os.Exit(1)
+	}
+
+	if backendConfig.Projects.Mode != configv1alpha1.ProjectModeNone {
+		if err = (&controller.ProjectReconciler{
+			Client:    mgr.GetClient(),
+			Mode:      backendConfig.Projects.Mode,
+			ProjectID: backendConfig.Projects.ProjectID,
+		}).SetupWithManager(mgr); err != nil {
+			setupLog.Error(err, "unable to create controller", "controller", "Project")
+			os.Exit(1)
+		}
+	}
+
+	if err = (&controller.ProjectGCReconciler{
+		Client:      mgr.GetClient(),
+		GracePeriod: backendConfig.ConsumerGracePeriod.Duration,
+	}).SetupWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create controller", "controller", "ProjectGC")
+		os.Exit(1)
+	}
+
+	if backendConfig.FleetWorkspaces {
+		if err = (&controller.FleetWorkspaceReconciler{
+			Client: mgr.GetClient(),
+			Scheme: mgr.GetScheme(),
+		}).SetupWithManager(mgr); err != nil {
+			setupLog.Error(err, "unable to create controller", "controller", "FleetWorkspace")
+			os.Exit(1)
+		}
+	}
+
+	if err = (&controller.ConsumerLabelReconciler{
+		Client: mgr.GetClient(),
+	}).SetupWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create controller", "controller", "ConsumerLabel")
+		os.Exit(1)
+	}
+
+	if os.Getenv("ENABLE_WEBHOOKS") != "false" {
+		if err = (&rancherwebhook.PolicyValidator{
+			Client:    mgr.GetClient(),
+			APIReader: mgr.GetAPIReader(),
+			Recorder:  mgr.GetEventRecorderFor("rancher-bind-policy"),
+		}).SetupWithManager(mgr); err != nil {
+			setupLog.Error(err, "unable to create webhook", "webhook", "RancherBindPolicy")
+			os.Exit(1)
+		}
+		if err = (&rancherwebhook.PolicyRulesReconciler{
+			Client:        mgr.GetClient(),
+			APIReader:     mgr.GetAPIReader(),
+			Configuration: policyWebhookConfiguration,
+		}).SetupWithManager(mgr); err != nil {
+			setupLog.Error(err, "unable to create controller", "controller", "PolicyRules")
+			os.Exit(1)
+		}
+		if err = (&rancherwebhook.NamespaceLabeler{}).SetupWithManager(mgr); err != nil {
+			setupLog.Error(err, "unable to create webhook", "webhook", "Namespace")
+			os.Exit(1)
+		}
+	}
+
+	if err = (&controller.KubeconfigRequestReconciler{
 		Client: mgr.GetClient(),
 		Scheme: mgr.GetScheme(),
 	}).SetupWithManager(mgr); err != nil {
-		setupLog.Error(err, "unable to create controller", "controller", "RancherBind")
+		setupLog.Error(err, "unable to create controller", "controller", "KubeconfigRequest")
 		os.Exit(1)
 	}
+
+	if err = (&controller.ExportRequestReconciler{
+		Client: mgr.GetClient(),
+		Scheme: mgr.GetScheme(),
+	}).SetupWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create controller", "controller", "ExportRequest")
+		os.Exit(1)
+	}
+
+	if err = (&controller.LegacyCatalogReconciler{
+		Client:         mgr.GetClient(),
+		ExportedGroups: legacyGroups,
+	}).SetupWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create controller", "controller", "LegacyCatalog")
+		os.Exit(1)
+	}
+
+	if err := mgr.Add(&controller.DefaultCatalog{Client: mgr.GetClient()}); err != nil {
+		setupLog.Error(err, "unable to add default export catalog")
+		os.Exit(1)
+	}
+
+	if backendOpts.ListenAddress != "" {
+		server, err := backend.NewServer(backendOpts, config, mgr.GetClient())
+		if err != nil {
+			setupLog.Error(err, "unable to set up kube-bind provider endpoints")
+			os.Exit(1)
+		}
+		if err := mgr.Add(server); err != nil {
+			setupLog.Error(err, "unable to add kube-bind provider endpoints")
+			os.Exit(1)
+		}
+	}
 	//+kubebuilder:scaffold:builder
 
 	if err := mgr.AddHealthzCheck("healthz", healthz.Ping); err != nil {
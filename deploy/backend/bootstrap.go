@@ -17,9 +17,16 @@ limitations under the License.
 package rancher_backend
 
 import (
+	"bufio"
+	"bytes"
 	"context"
 	"embed"
+	"errors"
+	"fmt"
+	"io"
 
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	kubeyaml "k8s.io/apimachinery/pkg/util/yaml"
 	"k8s.io/client-go/discovery"
 	"k8s.io/client-go/dynamic"
 
@@ -32,3 +39,45 @@ var raw embed.FS
 func Bootstrap(ctx context.Context, discoveryClient discovery.DiscoveryInterface, dynamicClient dynamic.Interface) error {
 	return bootstrap.Bootstrap(ctx, discoveryClient, dynamicClient, nil, raw)
 }
+
+// Manifests returns the backend resources which Bootstrap would create, in file order.
+func Manifests() ([]*unstructured.Unstructured, error) {
+	files, err := raw.ReadDir(".")
+	if err != nil {
+		return nil, err
+	}
+
+	objects := []*unstructured.Unstructured{}
+	for _, f := range files {
+		if f.IsDir() {
+			continue
+		}
+
+		data, err := raw.ReadFile(f.Name())
+		if err != nil {
+			return nil, fmt.Errorf("could not read %s: %w", f.Name(), err)
+		}
+
+		reader := kubeyaml.NewYAMLReader(bufio.NewReader(bytes.NewReader(data)))
+		for {
+			doc, err := reader.Read()
+			if errors.Is(err, io.EOF) {
+				break
+			} else if err != nil {
+				return nil, fmt.Errorf("could not read %s: %w", f.Name(), err)
+			}
+
+			obj := &unstructured.Unstructured{}
+			if err := kubeyaml.Unmarshal(doc, &obj.Object); err != nil {
+				return nil, fmt.Errorf("could not decode %s: %w", f.Name(), err)
+			}
+			if len(obj.Object) == 0 {
+				continue
+			}
+
+			objects = append(objects, obj)
+		}
+	}
+
+	return objects, nil
+}
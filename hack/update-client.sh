@@ -0,0 +1,55 @@
+#!/usr/bin/env bash
+
+# Copyright 2023 SUSE.
+#
+# Licensed under the Apache License, Version 2.0 (the "License");
+# you may not use this file except in compliance with the License.
+# You may obtain a copy of the License at
+#
+#     http://www.apache.org/licenses/LICENSE-2.0
+#
+# Unless required by applicable law or agreed to in writing, software
+# distributed under the License is distributed on an "AS IS" BASIS,
+# WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+# See the License for the specific language governing permissions and
+# limitations under the License.
+
+set -o errexit
+set -o nounset
+set -o pipefail
+
+REPO_ROOT=$(dirname "${BASH_SOURCE[0]}")/..
+CODEGEN_VERSION=${CODEGEN_VERSION:-$(go list -m -f '{{.Version}}' k8s.io/client-go)}
+MODULE=github.com/Danil-Grigorev/rancher-bind
+BOILERPLATE="${REPO_ROOT}/hack/boilerplate.go.txt"
+
+# Typed clientset, listers and informers for the rancher API groups.
+CLIENT_APIS=${MODULE}/pkg/apis/rancher/management/v3
+CLIENT_PKG=${MODULE}/pkg/client
+OUTPUT_BASE=$(mktemp -d)
+trap 'rm -rf "${OUTPUT_BASE}"' EXIT
+
+go run "k8s.io/code-generator/cmd/client-gen@${CODEGEN_VERSION}" \
+    --go-header-file "${BOILERPLATE}" \
+    --output-base "${OUTPUT_BASE}" \
+    --input-base "" \
+    --input "${CLIENT_APIS}" \
+    --clientset-name versioned \
+    --output-package "${CLIENT_PKG}/clientset"
+
+go run "k8s.io/code-generator/cmd/lister-gen@${CODEGEN_VERSION}" \
+    --go-header-file "${BOILERPLATE}" \
+    --output-base "${OUTPUT_BASE}" \
+    --input-dirs "${CLIENT_APIS}" \
+    --output-package "${CLIENT_PKG}/listers"
+
+go run "k8s.io/code-generator/cmd/informer-gen@${CODEGEN_VERSION}" \
+    --go-header-file "${BOILERPLATE}" \
+    --output-base "${OUTPUT_BASE}" \
+    --input-dirs "${CLIENT_APIS}" \
+    --versioned-clientset-package "${CLIENT_PKG}/clientset/versioned" \
+    --listers-package "${CLIENT_PKG}/listers" \
+    --output-package "${CLIENT_PKG}/informers"
+
+rm -rf "${REPO_ROOT}/pkg/client"
+cp -r "${OUTPUT_BASE}/${CLIENT_PKG}" "${REPO_ROOT}/pkg/client"
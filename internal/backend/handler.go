@@ -0,0 +1,669 @@
+/*
+Copyright 2023 SUSE.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package backend
+
+import (
+	"bytes"
+	"context"
+	_ "embed"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	htmltemplate "html/template"
+	"net/http"
+	"net/url"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/gorilla/securecookie"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	apiextensionslisters "k8s.io/apiextensions-apiserver/pkg/client/listers/apiextensions/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	componentbaseversion "k8s.io/component-base/version"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	"github.com/kube-bind/kube-bind/contrib/example-backend/kubernetes"
+	kubebindv1alpha1 "github.com/kube-bind/kube-bind/pkg/apis/kubebind/v1alpha1"
+	bindversion "github.com/kube-bind/kube-bind/pkg/version"
+
+	"github.com/Danil-Grigorev/rancher-bind/internal/controller"
+	apis "github.com/Danil-Grigorev/rancher-bind/pkg/apis"
+	rancherv1alpha1 "github.com/Danil-Grigorev/rancher-bind/pkg/apis/rancherbind/v1alpha1"
+	managementlisters "github.com/Danil-Grigorev/rancher-bind/pkg/client/listers/management/v3"
+	"github.com/Danil-Grigorev/rancher-bind/pkg/kubectl/bind-kubeconfig/plugin"
+)
+
+// sessionTTL is how long the consumer has to pick the resources after logging in.
+const sessionTTL = time.Hour
+
+var (
+	//go:embed login.gohtml
+	loginPage string
+	//go:embed resources.gohtml
+	resourcesPage string
+
+	loginTemplate     = htmltemplate.Must(htmltemplate.New("login").Parse(loginPage))
+	resourcesTemplate = htmltemplate.Must(htmltemplate.New("resource").Parse(resourcesPage))
+
+	authProviders = []string{
+		plugin.LocalProvider,
+		plugin.ActiveDirectoryProvider,
+		plugin.OpenLDAPProvider,
+		plugin.FreeIPAProvider,
+	}
+)
+
+// See https://developers.google.com/web/fundamentals/performance/optimizing-content-efficiency/http-caching?hl=en
+var noCacheHeaders = map[string]string{
+	"Expires":         time.Unix(0, 0).Format(time.RFC1123),
+	"Cache-Control":   "no-cache, no-store, must-revalidate, max-age=0",
+	"X-Accel-Expires": "0", // https://www.nginx.com/resources/wiki/start/topics/examples/x-accel/
+}
+
+// authCode is the kube-bind request state carried through the rancher login.
+type authCode struct {
+	RedirectURL string
+	SessionID   string
+	ClusterID   string
+}
+
+// session is the state kept in the session cookie between the login and the binding.
+type session struct {
+	CreatedAt time.Time
+	ExpiresOn time.Time
+
+	// Issuer is the rancher server URL the user authenticated against.
+	Issuer   string
+	UserID   string
+	Username string
+
+	RedirectURL string
+	SessionID   string
+	ClusterID   string
+}
+
+// identity is the consumer identity the provider namespace is created for.
+func (s *session) identity() string {
+	return s.UserID + "#" + s.ClusterID
+}
+
+type handler struct {
+	scope              kubebindv1alpha1.Scope
+	providerPrettyName string
+	rancherServerURL   string
+
+	cookieEncryptionKey []byte
+	cookieSigningKey    []byte
+
+	apiextensionsLister apiextensionslisters.CustomResourceDefinitionLister
+	catalogReader       client.Reader
+	settingLister       managementlisters.SettingLister
+	kubeManager         *kubernetes.Manager
+}
+
+func (h *handler) AddRoutes(mux *mux.Router) {
+	mux.HandleFunc("/export", h.handleServiceExport).Methods("GET")
+	mux.HandleFunc("/resources", h.handleResources).Methods("GET")
+	mux.HandleFunc("/authorize", h.handleAuthorize).Methods("GET")
+	mux.HandleFunc("/callback", h.handleCallback).Methods("POST")
+	mux.HandleFunc("/bind", h.handleBind).Methods("GET")
+}
+
+func (h *handler) handleServiceExport(w http.ResponseWriter, r *http.Request) {
+	logger := log.FromContext(r.Context()).WithValues("method", r.Method, "url", r.URL.String())
+
+	scheme := "http"
+	if r.TLS != nil {
+		scheme = "https"
+	}
+
+	ver, err := bindversion.BinaryVersion(componentbaseversion.Get().GitVersion)
+	if err != nil {
+		logger.Error(err, "failed to parse version", "version", componentbaseversion.Get().GitVersion)
+		ver = "v0.0.0"
+	}
+
+	provider := &kubebindv1alpha1.BindingProvider{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: kubebindv1alpha1.GroupVersion,
+			Kind:       "BindingProvider",
+		},
+		Version:            ver,
+		ProviderPrettyName: h.providerPrettyName,
+		AuthenticationMethods: []kubebindv1alpha1.AuthenticationMethod{
+			{
+				Method: "OAuth2CodeGrant",
+				OAuth2CodeGrant: &kubebindv1alpha1.OAuth2CodeGrant{
+					AuthenticatedURL: fmt.Sprintf("%s://%s/authorize", scheme, r.Host),
+				},
+			},
+		},
+	}
+
+	bs, err := json.Marshal(provider)
+	if err != nil {
+		logger.Error(err, "failed to marshal provider")
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(bs) // nolint:errcheck
+}
+
+// prepareNoCache prepares headers for preventing browser caching.
+func prepareNoCache(w http.ResponseWriter) {
+	// Set NoCache headers
+	for k, v := range noCacheHeaders {
+		w.Header().Set(k, v)
+	}
+}
+
+// handleAuthorize renders the rancher login form, carrying the kube-bind request along.
+func (h *handler) handleAuthorize(w http.ResponseWriter, r *http.Request) {
+	logger := log.FromContext(r.Context()).WithValues("method", r.Method, "url", r.URL.String())
+
+	prepareNoCache(w)
+
+	code := authCode{
+		RedirectURL: r.URL.Query().Get("u"),
+		SessionID:   r.URL.Query().Get("s"),
+		ClusterID:   r.URL.Query().Get("c"),
+	}
+	if p := r.URL.Query().Get("p"); p != "" && code.RedirectURL == "" {
+		code.RedirectURL = fmt.Sprintf("http://localhost:%s/callback", p)
+	}
+	if code.RedirectURL == "" || code.SessionID == "" || code.ClusterID == "" {
+		http.Error(w, "missing redirect_url, session_id or cluster_id", http.StatusBadRequest)
+		return
+	}
+	if err := validateRedirectURL(code.RedirectURL); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	h.renderLogin(w, r, code, "")
+	logger.V(1).Info("rendered login form", "session", code.SessionID)
+}
+
+func (h *handler) renderLogin(w http.ResponseWriter, r *http.Request, code authCode, loginError string) {
+	logger := log.FromContext(r.Context())
+
+	bs := bytes.Buffer{}
+	if err := loginTemplate.Execute(&bs, struct {
+		authCode
+		ProviderPrettyName string
+		Providers          []string
+		Error              string
+	}{
+		authCode:           code,
+		ProviderPrettyName: h.providerPrettyName,
+		Providers:          authProviders,
+		Error:              loginError,
+	}); err != nil {
+		logger.Error(err, "failed to execute template")
+		http.Error(w, "internal error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/html")
+	if loginError != "" {
+		w.WriteHeader(http.StatusUnauthorized)
+	}
+	w.Write(bs.Bytes()) // nolint:errcheck
+}
+
+// handleCallback authenticates the user against rancher, either with an API token
+// or with the credentials of a rancher auth provider, and starts the session.
+func (h *handler) handleCallback(w http.ResponseWriter, r *http.Request) {
+	logger := log.FromContext(r.Context()).WithValues("method", r.Method, "url", r.URL.String())
+
+	prepareNoCache(w)
+
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	code := authCode{
+		RedirectURL: r.PostForm.Get("u"),
+		SessionID:   r.PostForm.Get("s"),
+		ClusterID:   r.PostForm.Get("c"),
+	}
+	if code.RedirectURL == "" || code.SessionID == "" || code.ClusterID == "" {
+		http.Error(w, "missing redirect_url, session_id or cluster_id", http.StatusBadRequest)
+		return
+	}
+	if err := validateRedirectURL(code.RedirectURL); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	serverURL, err := h.serverURL()
+	if err != nil {
+		logger.Error(err, "failed to get rancher server url")
+		http.Error(w, "internal error", http.StatusInternalServerError)
+		return
+	}
+
+	token := r.PostForm.Get("token")
+	if token == "" {
+		provider := r.PostForm.Get("provider")
+		if provider == "" {
+			provider = plugin.LocalProvider
+		}
+		login, err := plugin.AuthenticateProviderUser(serverURL, provider, &apis.Login{
+			Username: r.PostForm.Get("username"),
+			Password: r.PostForm.Get("password"),
+		})
+		if err != nil {
+			logger.Info("failed to authenticate", "error", err)
+			h.renderLogin(w, r, code, "Login failed, please check your credentials.")
+			return
+		}
+		token = login.Token
+
+		// The token only identifies the user, the session does not keep it.
+		defer func() {
+			if err := plugin.Logout(serverURL, token); err != nil {
+				logger.Error(err, "failed to log out the login token")
+			}
+		}()
+	}
+
+	user, err := plugin.GetCurrentUser(serverURL, token)
+	if err != nil {
+		logger.Info("failed to identify the token user", "error", err)
+		h.renderLogin(w, r, code, "Unable to authenticate with the provided token.")
+		return
+	}
+
+	state := session{
+		CreatedAt:   time.Now(),
+		ExpiresOn:   time.Now().Add(sessionTTL),
+		Issuer:      serverURL,
+		UserID:      user.ID,
+		Username:    user.Username,
+		RedirectURL: code.RedirectURL,
+		SessionID:   code.SessionID,
+		ClusterID:   code.ClusterID,
+	}
+
+	cookieName := sessionCookieName(code.SessionID)
+	encoded, err := h.cookieCodec().Encode(cookieName, state)
+	if err != nil {
+		logger.Error(err, "failed to encode secure session cookie")
+		http.Error(w, "internal error", http.StatusInternalServerError)
+		return
+	}
+
+	http.SetCookie(w, makeCookie(r, cookieName, encoded, sessionTTL))
+	http.Redirect(w, r, "/resources?s="+url.QueryEscape(code.SessionID), http.StatusFound)
+}
+
+// handleResources lists the rancher CRDs the authenticated user can bind.
+func (h *handler) handleResources(w http.ResponseWriter, r *http.Request) {
+	logger := log.FromContext(r.Context()).WithValues("method", r.Method, "url", r.URL.String())
+
+	prepareNoCache(w)
+
+	state, err := h.session(r)
+	if err != nil {
+		logger.Info("invalid session", "error", err)
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	catalog, err := controller.LoadCatalog(r.Context(), h.catalogReader)
+	if err != nil {
+		logger.Error(err, "failed to load export catalog")
+		http.Error(w, "internal error", http.StatusInternalServerError)
+		return
+	}
+	exported, err := h.exportedResources(catalog)
+	if err != nil {
+		logger.Error(err, "failed to list exported resources")
+		http.Error(w, "internal error", http.StatusInternalServerError)
+		return
+	}
+	bundles, err := h.exportedBundles(catalog)
+	if err != nil {
+		logger.Error(err, "failed to list exported bundles")
+		http.Error(w, "internal error", http.StatusInternalServerError)
+		return
+	}
+
+	bs := bytes.Buffer{}
+	if err := resourcesTemplate.Execute(&bs, struct {
+		SessionID          string
+		ProviderPrettyName string
+		BundleGroup        string
+		Resources          []exportedResource
+		Bundles            []rancherv1alpha1.CatalogBundle
+	}{
+		SessionID:          state.SessionID,
+		ProviderPrettyName: h.providerPrettyName,
+		BundleGroup:        rancherv1alpha1.BundleGroup,
+		Resources:          exported,
+		Bundles:            bundles,
+	}); err != nil {
+		logger.Error(err, "failed to execute template")
+		http.Error(w, "internal error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/html")
+	w.Write(bs.Bytes()) // nolint:errcheck
+}
+
+// handleBind hands the kubeconfig of the consumer APIServiceNamespace back to kubectl bind.
+func (h *handler) handleBind(w http.ResponseWriter, r *http.Request) {
+	logger := log.FromContext(r.Context()).WithValues("method", r.Method, "url", r.URL.String())
+
+	prepareNoCache(w)
+
+	state, err := h.session(r)
+	if err != nil {
+		logger.Info("invalid session", "error", err)
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	group := r.URL.Query().Get("group")
+	resource := r.URL.Query().Get("resource")
+	request, err := h.exportRequest(r.Context(), group, resource)
+	if err != nil {
+		logger.Error(err, "failed to list exported resources")
+		http.Error(w, "internal error", http.StatusInternalServerError)
+		return
+	}
+	if request == nil {
+		http.Error(w, fmt.Sprintf("resource %s.%s is not exported", resource, group), http.StatusBadRequest)
+		return
+	}
+
+	logger = logger.WithValues("user", state.UserID, "resource", resource, "group", group)
+	kfg, err := h.kubeManager.HandleResources(r.Context(), state.identity(), resource, group)
+	if err != nil {
+		logger.Error(err, "failed to handle resources")
+		http.Error(w, "internal error", http.StatusInternalServerError)
+		return
+	}
+
+	// callback response
+	requestBytes, err := json.Marshal(request)
+	if err != nil {
+		logger.Error(err, "failed to marshal request")
+		http.Error(w, "internal error", http.StatusInternalServerError)
+		return
+	}
+	response := kubebindv1alpha1.BindingResponse{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: kubebindv1alpha1.SchemeGroupVersion.String(),
+			Kind:       "BindingResponse",
+		},
+		Authentication: kubebindv1alpha1.BindingResponseAuthentication{
+			OAuth2CodeGrant: &kubebindv1alpha1.BindingResponseAuthenticationOAuth2CodeGrant{
+				SessionID: state.SessionID,
+				ID:        state.Issuer + "/" + state.UserID,
+			},
+		},
+		Kubeconfig: kfg,
+		Requests:   []runtime.RawExtension{{Raw: requestBytes}},
+	}
+	payload, err := json.Marshal(&response)
+	if err != nil {
+		logger.Error(err, "failed to marshal auth response")
+		http.Error(w, "internal error", http.StatusInternalServerError)
+		return
+	}
+
+	if err := validateRedirectURL(state.RedirectURL); err != nil {
+		logger.Info("invalid redirect url", "error", err)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	parsedAuthURL, err := url.Parse(state.RedirectURL)
+	if err != nil {
+		logger.Error(err, "failed to parse redirect url")
+		http.Error(w, "internal error", http.StatusInternalServerError)
+		return
+	}
+
+	values := parsedAuthURL.Query()
+	values.Add("response", base64.StdEncoding.EncodeToString(payload))
+	parsedAuthURL.RawQuery = values.Encode()
+
+	logger.V(1).Info("redirecting to auth callback", "url", state.RedirectURL+"?response=<redacted>")
+	http.Redirect(w, r, parsedAuthURL.String(), http.StatusFound)
+}
+
+// validateRedirectURL accepts only the local callback of kubectl bind, as the kubeconfig of
+// the consumer is handed over in the redirect.
+func validateRedirectURL(redirectURL string) error {
+	parsed, err := url.Parse(redirectURL)
+	if err != nil {
+		return fmt.Errorf("invalid redirect_url: %w", err)
+	}
+	if parsed.Scheme != "http" && parsed.Scheme != "https" {
+		return fmt.Errorf("redirect_url scheme %q is not supported", parsed.Scheme)
+	}
+	switch parsed.Hostname() {
+	case "localhost", "127.0.0.1", "::1":
+		return nil
+	default:
+		return fmt.Errorf("redirect_url has to point to localhost, got %q", parsed.Hostname())
+	}
+}
+
+// serverURL returns the rancher API address, falling back to the server-url setting.
+func (h *handler) serverURL() (string, error) {
+	if h.rancherServerURL != "" {
+		return strings.TrimSuffix(h.rancherServerURL, "/"), nil
+	}
+
+	setting, err := h.settingLister.Get(plugin.ServerURLSetting)
+	if err != nil {
+		return "", err
+	}
+	value := setting.Value
+	if value == "" {
+		value = setting.Default
+	}
+	if value == "" {
+		return "", errors.New("rancher server-url setting is not configured")
+	}
+
+	return strings.TrimSuffix(value, "/"), nil
+}
+
+// exportedResource is a catalog resource served by a CRD on the provider.
+type exportedResource struct {
+	*apiextensionsv1.CustomResourceDefinition
+	Description string
+}
+
+// exportedResources returns the catalog resources with a CRD on the provider, limited to
+// the namespaced ones for the Namespaced scope.
+func (h *handler) exportedResources(catalog *rancherv1alpha1.RancherExportCatalog) ([]exportedResource, error) {
+	exported := []exportedResource{}
+	for _, resource := range catalog.Spec.Resources {
+		crd, err := h.exportableCRD(resource.Group, resource.Resource)
+		if err != nil {
+			return nil, err
+		}
+		if crd == nil {
+			continue
+		}
+		exported = append(exported, exportedResource{CustomResourceDefinition: crd, Description: resource.Description})
+	}
+	sort.SliceStable(exported, func(i, j int) bool {
+		return exported[i].Name < exported[j].Name
+	})
+
+	return exported, nil
+}
+
+// exportedBundles returns the catalog bundles with the CRDs of all their resources
+// exportable on the provider.
+func (h *handler) exportedBundles(catalog *rancherv1alpha1.RancherExportCatalog) ([]rancherv1alpha1.CatalogBundle, error) {
+	exported := []rancherv1alpha1.CatalogBundle{}
+	for _, bundle := range catalog.Spec.Bundles {
+		complete := true
+		for _, resource := range bundle.Resources {
+			crd, err := h.exportableCRD(resource.Group, resource.Resource)
+			if err != nil {
+				return nil, err
+			}
+			if crd == nil {
+				complete = false
+				break
+			}
+		}
+		if complete {
+			exported = append(exported, bundle)
+		}
+	}
+	sort.SliceStable(exported, func(i, j int) bool {
+		return exported[i].Name < exported[j].Name
+	})
+
+	return exported, nil
+}
+
+// exportableCRD returns the CRD of the resource, or nil if there is none or it is out of
+// the backend scope.
+func (h *handler) exportableCRD(group, resource string) (*apiextensionsv1.CustomResourceDefinition, error) {
+	crd, err := h.apiextensionsLister.Get(resource + "." + group)
+	if apierrors.IsNotFound(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	if h.scope != kubebindv1alpha1.ClusterScope && crd.Spec.Scope != apiextensionsv1.NamespaceScoped {
+		return nil, nil
+	}
+
+	return crd, nil
+}
+
+// exportRequest returns the export request kubectl bind creates for the resource, or nil if
+// it is not exported. Bundles are requested with the bundles.rancher.kube-bind.io group and
+// expand into all the bundle resources.
+func (h *handler) exportRequest(ctx context.Context, group, resource string) (*kubebindv1alpha1.APIServiceExportRequestResponse, error) {
+	catalog, err := controller.LoadCatalog(ctx, h.catalogReader)
+	if err != nil {
+		return nil, err
+	}
+
+	request := &kubebindv1alpha1.APIServiceExportRequestResponse{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: kubebindv1alpha1.SchemeGroupVersion.String(),
+			Kind:       "APIServiceExportRequest",
+		},
+		ObjectMeta: kubebindv1alpha1.NameObjectMeta{
+			Name: resource + "." + group,
+		},
+	}
+
+	if group != rancherv1alpha1.BundleGroup {
+		exported, err := h.exportedResources(catalog)
+		if err != nil {
+			return nil, err
+		}
+		if !slices.ContainsFunc(exported, func(e exportedResource) bool {
+			return e.Spec.Group == group && e.Spec.Names.Plural == resource
+		}) {
+			return nil, nil
+		}
+
+		request.Spec.Resources = []kubebindv1alpha1.APIServiceExportRequestResource{
+			{GroupResource: kubebindv1alpha1.GroupResource{Group: group, Resource: resource}},
+		}
+		return request, nil
+	}
+
+	exported, err := h.exportedBundles(catalog)
+	if err != nil {
+		return nil, err
+	}
+	i := slices.IndexFunc(exported, func(b rancherv1alpha1.CatalogBundle) bool {
+		return b.Name == resource
+	})
+	if i < 0 {
+		return nil, nil
+	}
+	bundle := exported[i]
+
+	parameters, err := json.Marshal(&rancherv1alpha1.BundleParameters{Bundle: bundle.Name, Version: bundle.Version})
+	if err != nil {
+		return nil, err
+	}
+	request.Spec.Parameters = &runtime.RawExtension{Raw: parameters}
+	for _, r := range bundle.Resources {
+		request.Spec.Resources = append(request.Spec.Resources, kubebindv1alpha1.APIServiceExportRequestResource{
+			GroupResource: kubebindv1alpha1.GroupResource{Group: r.Group, Resource: r.Resource},
+		})
+	}
+
+	return request, nil
+}
+
+// session decodes and validates the session cookie referenced by the request.
+func (h *handler) session(r *http.Request) (*session, error) {
+	cookieName := sessionCookieName(r.URL.Query().Get("s"))
+	ck, err := r.Cookie(cookieName)
+	if err != nil {
+		return nil, err
+	}
+
+	state := &session{}
+	if err := h.cookieCodec().Decode(cookieName, ck.Value, state); err != nil {
+		return nil, err
+	}
+	if time.Now().After(state.ExpiresOn) {
+		return nil, errors.New("session expired")
+	}
+
+	return state, nil
+}
+
+func (h *handler) cookieCodec() *securecookie.SecureCookie {
+	return securecookie.New(h.cookieSigningKey, h.cookieEncryptionKey).MaxAge(int(sessionTTL.Seconds()))
+}
+
+func sessionCookieName(sessionID string) string {
+	return "kube-bind-" + sessionID
+}
+
+func makeCookie(req *http.Request, name string, value string, expiration time.Duration) *http.Cookie {
+	return &http.Cookie{
+		Name:     name,
+		Value:    value,
+		Path:     "/",
+		HttpOnly: true,
+		Secure:   req.TLS != nil,
+		SameSite: http.SameSiteLaxMode,
+		Expires:  time.Now().Add(expiration),
+	}
+}
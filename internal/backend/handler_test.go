@@ -0,0 +1,258 @@
+/*
+Copyright 2023 SUSE.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package backend
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+	. "github.com/onsi/gomega"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	apiextensionslisters "k8s.io/apiextensions-apiserver/pkg/client/listers/apiextensions/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/cache"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	kubebindv1alpha1 "github.com/kube-bind/kube-bind/pkg/apis/kubebind/v1alpha1"
+
+	apis "github.com/Danil-Grigorev/rancher-bind/pkg/apis"
+	rancherv1alpha1 "github.com/Danil-Grigorev/rancher-bind/pkg/apis/rancherbind/v1alpha1"
+)
+
+func newCRD(group, plural string, scope apiextensionsv1.ResourceScope) *apiextensionsv1.CustomResourceDefinition {
+	return &apiextensionsv1.CustomResourceDefinition{
+		ObjectMeta: metav1.ObjectMeta{Name: plural + "." + group},
+		Spec: apiextensionsv1.CustomResourceDefinitionSpec{
+			Group: group,
+			Names: apiextensionsv1.CustomResourceDefinitionNames{Plural: plural, Singular: strings.TrimSuffix(plural, "s")},
+			Scope: scope,
+		},
+	}
+}
+
+func newTestRouter(t *testing.T, rancherURL string) *mux.Router {
+	router := mux.NewRouter()
+	newTestHandler(t, rancherURL).AddRoutes(router)
+
+	return router
+}
+
+func newTestHandler(t *testing.T, rancherURL string) *handler {
+	indexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})
+	for _, crd := range []*apiextensionsv1.CustomResourceDefinition{
+		newCRD("provisioning.cattle.io", "clusters", apiextensionsv1.NamespaceScoped),
+		newCRD("management.cattle.io", "users", apiextensionsv1.ClusterScoped),
+	} {
+		if err := indexer.Add(crd); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	scheme := runtime.NewScheme()
+	if err := rancherv1alpha1.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	catalog := &rancherv1alpha1.RancherExportCatalog{
+		ObjectMeta: metav1.ObjectMeta{Name: rancherv1alpha1.DefaultCatalogName},
+		Spec: rancherv1alpha1.RancherExportCatalogSpec{
+			Resources: rancherv1alpha1.DefaultCatalogResources,
+			Bundles: append([]rancherv1alpha1.CatalogBundle{{
+				Name:      "clusters",
+				Version:   "v2",
+				Resources: []rancherv1alpha1.BundleResource{{Group: "provisioning.cattle.io", Resource: "clusters"}},
+			}}, rancherv1alpha1.DefaultCatalogBundles...),
+		},
+	}
+
+	return &handler{
+		scope:               kubebindv1alpha1.ClusterScope,
+		providerPrettyName:  "Rancher",
+		rancherServerURL:    rancherURL,
+		cookieSigningKey:    []byte("0123456789abcdef0123456789abcdef"),
+		apiextensionsLister: apiextensionslisters.NewCustomResourceDefinitionLister(indexer),
+		catalogReader:       fake.NewClientBuilder().WithScheme(scheme).WithObjects(catalog).Build(),
+	}
+}
+
+func TestExport(t *testing.T) {
+	g := NewWithT(t)
+
+	rec := httptest.NewRecorder()
+	newTestRouter(t, "").ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "http://backend.example.com/export", nil))
+	g.Expect(rec.Code).To(Equal(http.StatusOK))
+
+	provider := &kubebindv1alpha1.BindingProvider{}
+	g.Expect(json.Unmarshal(rec.Body.Bytes(), provider)).To(Succeed())
+	g.Expect(provider.ProviderPrettyName).To(Equal("Rancher"))
+	g.Expect(provider.AuthenticationMethods).To(HaveLen(1))
+	g.Expect(provider.AuthenticationMethods[0].OAuth2CodeGrant.AuthenticatedURL).To(Equal("http://backend.example.com/authorize"))
+}
+
+func TestLoginSession(t *testing.T) {
+	g := NewWithT(t)
+
+	rancher := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v3/users" || r.Header.Get("Authorization") != "Basic "+base64.StdEncoding.EncodeToString([]byte("token-abcde:secret")) {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		json.NewEncoder(w).Encode(&apis.UserCollection{Data: []apis.User{{ID: "u-abcde", Username: "alice"}}}) // nolint: errcheck
+	}))
+	defer rancher.Close()
+
+	router := newTestRouter(t, rancher.URL)
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/authorize?u=http://localhost:1234/callback&s=sid&c=cid", nil))
+	g.Expect(rec.Code).To(Equal(http.StatusOK))
+	g.Expect(rec.Body.String()).To(ContainSubstring(`name="s" value="sid"`))
+
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/authorize?s=sid", nil))
+	g.Expect(rec.Code).To(Equal(http.StatusBadRequest))
+
+	for _, redirect := range []string{"https://attacker.example.com/callback", "http://localhost@attacker.example.com/callback", "file:///callback"} {
+		rec = httptest.NewRecorder()
+		router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/authorize?s=sid&c=cid&u="+url.QueryEscape(redirect), nil))
+		g.Expect(rec.Code).To(Equal(http.StatusBadRequest), redirect)
+	}
+
+	login := func(token string) *httptest.ResponseRecorder {
+		form := url.Values{"u": {"http://localhost:1234/callback"}, "s": {"sid"}, "c": {"cid"}, "token": {token}}
+		req := httptest.NewRequest(http.MethodPost, "/callback", strings.NewReader(form.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		return rec
+	}
+
+	g.Expect(login("token-abcde:invalid").Code).To(Equal(http.StatusUnauthorized))
+
+	form := url.Values{"u": {"http://attacker.example.com/callback"}, "s": {"sid"}, "c": {"cid"}, "token": {"token-abcde:secret"}}
+	req := httptest.NewRequest(http.MethodPost, "/callback", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	g.Expect(rec.Code).To(Equal(http.StatusBadRequest))
+
+	rec = login("token-abcde:secret")
+	g.Expect(rec.Code).To(Equal(http.StatusFound))
+	g.Expect(rec.Header().Get("Location")).To(Equal("/resources?s=sid"))
+	cookies := rec.Result().Cookies()
+	g.Expect(cookies).To(HaveLen(1))
+
+	req = httptest.NewRequest(http.MethodGet, "/resources?s=sid", nil)
+	req.AddCookie(cookies[0])
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	g.Expect(rec.Code).To(Equal(http.StatusOK))
+	g.Expect(rec.Body.String()).To(ContainSubstring("resource=clusters&group=provisioning.cattle.io"))
+	g.Expect(rec.Body.String()).To(ContainSubstring("Downstream clusters provisioned and managed by rancher."))
+	g.Expect(rec.Body.String()).ToNot(ContainSubstring("management.cattle.io"))
+	g.Expect(rec.Body.String()).To(ContainSubstring("resource=clusters&group=bundles.rancher.kube-bind.io"))
+	g.Expect(rec.Body.String()).ToNot(ContainSubstring("rke2-clusters"))
+
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/resources?s=sid", nil))
+	g.Expect(rec.Code).To(Equal(http.StatusUnauthorized))
+
+	req = httptest.NewRequest(http.MethodGet, "/bind?s=sid&resource=users&group=management.cattle.io", nil)
+	req.AddCookie(cookies[0])
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	g.Expect(rec.Code).To(Equal(http.StatusBadRequest))
+}
+
+func TestPasswordLogin(t *testing.T) {
+	g := NewWithT(t)
+
+	loggedOut := []string{}
+	rancher := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v3-public/localProviders/local":
+			login := &apis.Login{}
+			g.Expect(json.NewDecoder(r.Body).Decode(login)).To(Succeed())
+			if login.Password != "password" {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			json.NewEncoder(w).Encode(&apis.LoginResponse{Token: "token-login:secret"}) // nolint: errcheck
+		case "/v3/users":
+			json.NewEncoder(w).Encode(&apis.UserCollection{Data: []apis.User{{ID: "u-abcde", Username: "alice"}}}) // nolint: errcheck
+		case "/v3/tokens":
+			g.Expect(r.URL.Query().Get("action")).To(Equal("logout"))
+			token, _ := base64.StdEncoding.DecodeString(strings.TrimPrefix(r.Header.Get("Authorization"), "Basic "))
+			loggedOut = append(loggedOut, string(token))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer rancher.Close()
+
+	router := newTestRouter(t, rancher.URL)
+
+	login := func(password string) *httptest.ResponseRecorder {
+		form := url.Values{"u": {"http://127.0.0.1:1234/callback"}, "s": {"sid"}, "c": {"cid"}, "username": {"alice"}, "password": {password}}
+		req := httptest.NewRequest(http.MethodPost, "/callback", strings.NewReader(form.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		return rec
+	}
+
+	g.Expect(login("invalid").Code).To(Equal(http.StatusUnauthorized))
+	g.Expect(loggedOut).To(BeEmpty())
+
+	g.Expect(login("password").Code).To(Equal(http.StatusFound))
+	g.Expect(loggedOut).To(ConsistOf("token-login:secret"))
+}
+
+func TestExportRequest(t *testing.T) {
+	g := NewWithT(t)
+
+	h := newTestHandler(t, "")
+
+	request, err := h.exportRequest(context.Background(), "provisioning.cattle.io", "clusters")
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(request.ObjectMeta.Name).To(Equal("clusters.provisioning.cattle.io"))
+	g.Expect(request.Spec.Parameters).To(BeNil())
+
+	request, err = h.exportRequest(context.Background(), rancherv1alpha1.BundleGroup, "clusters")
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(request.ObjectMeta.Name).To(Equal("clusters.bundles.rancher.kube-bind.io"))
+	g.Expect(request.Spec.Resources).To(ConsistOf(kubebindv1alpha1.APIServiceExportRequestResource{
+		GroupResource: kubebindv1alpha1.GroupResource{Group: "provisioning.cattle.io", Resource: "clusters"},
+	}))
+	g.Expect(string(request.Spec.Parameters.Raw)).To(MatchJSON(`{"bundle":"clusters","version":"v2"}`))
+
+	// The machine config CRDs of the default bundle are missing.
+	request, err = h.exportRequest(context.Background(), rancherv1alpha1.BundleGroup, "rke2-clusters")
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(request).To(BeNil())
+
+	request, err = h.exportRequest(context.Background(), "management.cattle.io", "users")
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(request).To(BeNil())
+}
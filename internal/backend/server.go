@@ -0,0 +1,318 @@
+/*
+Copyright 2023 SUSE.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package backend
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+
+	"github.com/kube-bind/kube-bind/contrib/example-backend/kubernetes"
+	kubebindv1alpha1 "github.com/kube-bind/kube-bind/pkg/apis/kubebind/v1alpha1"
+
+	"github.com/Danil-Grigorev/rancher-bind/internal/controller"
+)
+
+//+kubebuilder:rbac:groups=core,resources=serviceaccounts,verbs=get;list;watch;create;update;patch;delete
+
+// Keys of the session cookie Secret.
+const (
+	CookieSigningKeyName    = "signing-key"
+	CookieEncryptionKeyName = "encryption-key"
+)
+
+const (
+	cookieSigningKeyLength    = 64
+	cookieEncryptionKeyLength = 32
+)
+
+// Options configure the kube-bind provider HTTP endpoints.
+type Options struct {
+	// ListenAddress is the address the HTTP server binds to. An empty value disables the server.
+	ListenAddress string
+	// TLSCertFile and TLSKeyFile enable TLS on the listener. They are required unless
+	// AllowInsecureHTTP is set, as the login form posts the rancher credentials.
+	TLSCertFile string
+	TLSKeyFile  string
+	// AllowInsecureHTTP serves the endpoints without TLS, for TLS terminated in front of the backend.
+	AllowInsecureHTTP bool
+
+	// ExternalAddress is the kube-apiserver address written into the consumer kubeconfig.
+	ExternalAddress string
+	// ExternalCAFile is the CA bundle of the external kube-apiserver address.
+	ExternalCAFile string
+	// ExternalServerName overrides the TLS server name of the external address.
+	ExternalServerName string
+
+	// RancherServerURL is the rancher API users authenticate against. Defaults to the server-url setting.
+	RancherServerURL string
+	// NamespacePrefix prefixes the provider namespaces created for each consumer.
+	NamespacePrefix string
+	// PrettyName is the provider name shown to the consumer.
+	PrettyName string
+	// Scope limits the listed CRDs to namespaced ones for the Namespaced scope.
+	Scope kubebindv1alpha1.Scope
+
+	// CookieSigningKey and CookieEncryptionKey are base64 encoded session cookie keys.
+	CookieSigningKey    string
+	CookieEncryptionKey string
+	// CookieSecret is the namespace/name of the Secret the replicas share the session cookie
+	// keys through, created with random keys when missing. It is used when no signing key is
+	// set. Without either, a random signing key is generated, which is not shared between
+	// replicas and invalidates sessions on restart.
+	CookieSecret string
+}
+
+// NewOptions returns Options with the defaults set.
+func NewOptions() *Options {
+	return &Options{
+		ListenAddress:   ":8090",
+		NamespacePrefix: "kube-bind-",
+		PrettyName:      "Rancher",
+		Scope:           kubebindv1alpha1.ClusterScope,
+	}
+}
+
+// Server serves the kube-bind provider endpoints, using rancher as the identity provider.
+type Server struct {
+	options *Options
+	Router  *mux.Router
+
+	handler *handler
+	// secrets loads the cookie keys from the CookieSecret on start.
+	secrets client.Client
+}
+
+// NewServer sets up the kube-bind provider endpoints on top of the backend informers,
+// offering the resources of the export catalogs read through the catalog reader.
+// The informers are registered on the shared factories, which have to be started afterwards.
+func NewServer(options *Options, config *controller.Config, catalogReader client.Reader) (*Server, error) {
+	if (options.TLSCertFile == "") != (options.TLSKeyFile == "") {
+		return nil, errors.New("both the TLS certificate and key have to be set")
+	}
+	if options.TLSCertFile == "" && !options.AllowInsecureHTTP {
+		return nil, errors.New("the login form posts rancher credentials, set the TLS certificate and key, or allow insecure HTTP when TLS is terminated in front of the backend")
+	}
+
+	signingKey, err := decodeKey(options.CookieSigningKey)
+	if err != nil {
+		return nil, fmt.Errorf("invalid cookie signing key: %w", err)
+	}
+	encryptionKey, err := decodeKey(options.CookieEncryptionKey)
+	if err != nil {
+		return nil, fmt.Errorf("invalid cookie encryption key: %w", err)
+	}
+	if err := validateEncryptionKey(encryptionKey); err != nil {
+		return nil, err
+	}
+
+	var secrets client.Client
+	if len(signingKey) == 0 && options.CookieSecret != "" {
+		if _, _, ok := strings.Cut(options.CookieSecret, "/"); !ok {
+			return nil, fmt.Errorf("cookie secret %q is not in the namespace/name form", options.CookieSecret)
+		}
+		// The keys are read once on start, without caching all the Secrets.
+		if secrets, err = client.New(config.ClientConfig, client.Options{}); err != nil {
+			return nil, fmt.Errorf("unable to create cookie secret client: %w", err)
+		}
+	} else if len(signingKey) == 0 {
+		if signingKey, err = randomKey(cookieSigningKeyLength); err != nil {
+			return nil, fmt.Errorf("unable to generate cookie signing key: %w", err)
+		}
+	}
+
+	var externalCA []byte
+	if options.ExternalCAFile != "" {
+		if externalCA, err = os.ReadFile(options.ExternalCAFile); err != nil {
+			return nil, fmt.Errorf("unable to read external CA: %w", err)
+		}
+	}
+
+	kubeManager, err := kubernetes.NewKubernetesManager(
+		options.NamespacePrefix,
+		options.PrettyName,
+		config.ClientConfig,
+		options.ExternalAddress,
+		externalCA,
+		options.ExternalServerName,
+		config.Informers.Namespaces,
+		config.Informers.APIServiceExports,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("error setting up kubernetes manager: %w", err)
+	}
+
+	h := &handler{
+		scope:               options.Scope,
+		providerPrettyName:  options.PrettyName,
+		rancherServerURL:    options.RancherServerURL,
+		cookieSigningKey:    signingKey,
+		cookieEncryptionKey: encryptionKey,
+		apiextensionsLister: config.Informers.CustomResourceDefinitions.Lister(),
+		catalogReader:       catalogReader,
+		settingLister:       config.Informers.Settings.Lister(),
+		kubeManager:         kubeManager,
+	}
+
+	server := &Server{
+		options: options,
+		Router:  mux.NewRouter(),
+		handler: h,
+		secrets: secrets,
+	}
+	h.AddRoutes(server.Router)
+
+	return server, nil
+}
+
+// Start serves the endpoints until the context is closed.
+func (s *Server) Start(ctx context.Context) error {
+	logger := log.FromContext(ctx).WithName("backend")
+
+	if s.secrets != nil {
+		signingKey, encryptionKey, err := loadCookieKeys(ctx, s.secrets, s.options.CookieSecret)
+		if err != nil {
+			return err
+		}
+		s.handler.cookieSigningKey = signingKey
+		s.handler.cookieEncryptionKey = encryptionKey
+	}
+
+	listener, err := net.Listen("tcp", s.options.ListenAddress)
+	if err != nil {
+		return fmt.Errorf("unable to listen on %s: %w", s.options.ListenAddress, err)
+	}
+
+	server := &http.Server{
+		Handler:           s.Router,
+		ReadHeaderTimeout: 30 * time.Second,
+		BaseContext: func(net.Listener) context.Context {
+			return log.IntoContext(context.Background(), logger)
+		},
+	}
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		server.Shutdown(shutdownCtx) // nolint:errcheck
+	}()
+
+	logger.Info("serving kube-bind provider endpoints", "address", listener.Addr().String())
+	if s.options.TLSCertFile != "" {
+		err = server.ServeTLS(listener, s.options.TLSCertFile, s.options.TLSKeyFile)
+	} else {
+		err = server.Serve(listener)
+	}
+	if errors.Is(err, http.ErrServerClosed) {
+		return nil
+	}
+
+	return err
+}
+
+// NeedLeaderElection allows every replica to serve the endpoints.
+func (s *Server) NeedLeaderElection() bool {
+	return false
+}
+
+var _ manager.LeaderElectionRunnable = &Server{}
+
+func decodeKey(key string) ([]byte, error) {
+	if key == "" {
+		return nil, nil
+	}
+
+	return base64.StdEncoding.DecodeString(key)
+}
+
+func validateEncryptionKey(key []byte) error {
+	switch len(key) {
+	case 0, 16, 24, 32:
+		return nil
+	default:
+		return fmt.Errorf("cookie encryption key must be 16, 24 or 32 bytes long, got %d", len(key))
+	}
+}
+
+func randomKey(length int) ([]byte, error) {
+	key := make([]byte, length)
+	if _, err := rand.Read(key); err != nil {
+		return nil, err
+	}
+
+	return key, nil
+}
+
+// loadCookieKeys reads the session cookie keys from the namespace/name Secret, creating it
+// with random keys when missing. Replicas racing on the creation read the winning keys.
+func loadCookieKeys(ctx context.Context, c client.Client, ref string) ([]byte, []byte, error) {
+	namespace, name, _ := strings.Cut(ref, "/")
+	key := client.ObjectKey{Namespace: namespace, Name: name}
+
+	secret := &corev1.Secret{}
+	err := c.Get(ctx, key, secret)
+	if apierrors.IsNotFound(err) {
+		var signingKey, encryptionKey []byte
+		if signingKey, err = randomKey(cookieSigningKeyLength); err != nil {
+			return nil, nil, fmt.Errorf("unable to generate cookie signing key: %w", err)
+		}
+		if encryptionKey, err = randomKey(cookieEncryptionKeyLength); err != nil {
+			return nil, nil, fmt.Errorf("unable to generate cookie encryption key: %w", err)
+		}
+
+		secret = &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Namespace: namespace, Name: name},
+			Data: map[string][]byte{
+				CookieSigningKeyName:    signingKey,
+				CookieEncryptionKeyName: encryptionKey,
+			},
+		}
+		err = c.Create(ctx, secret)
+		if apierrors.IsAlreadyExists(err) {
+			err = c.Get(ctx, key, secret)
+		}
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("unable to load cookie keys from secret %s: %w", ref, err)
+	}
+
+	signingKey := secret.Data[CookieSigningKeyName]
+	if len(signingKey) == 0 {
+		return nil, nil, fmt.Errorf("secret %s has no %s", ref, CookieSigningKeyName)
+	}
+	encryptionKey := secret.Data[CookieEncryptionKeyName]
+	if err := validateEncryptionKey(encryptionKey); err != nil {
+		return nil, nil, fmt.Errorf("secret %s: %w", ref, err)
+	}
+
+	return signingKey, encryptionKey, nil
+}
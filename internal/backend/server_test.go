@@ -0,0 +1,72 @@
+/*
+Copyright 2023 SUSE.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package backend
+
+import (
+	"context"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/Danil-Grigorev/rancher-bind/internal/controller"
+)
+
+func TestNewServerRequiresTLS(t *testing.T) {
+	g := NewWithT(t)
+
+	options := NewOptions()
+	_, err := NewServer(options, &controller.Config{}, nil)
+	g.Expect(err).To(MatchError(ContainSubstring("set the TLS certificate and key")))
+
+	options.TLSCertFile = "tls.crt"
+	_, err = NewServer(options, &controller.Config{}, nil)
+	g.Expect(err).To(MatchError("both the TLS certificate and key have to be set"))
+}
+
+func TestLoadCookieKeys(t *testing.T) {
+	g := NewWithT(t)
+	ctx := context.Background()
+
+	c := fake.NewClientBuilder().Build()
+
+	signingKey, encryptionKey, err := loadCookieKeys(ctx, c, "rancher-bind-system/cookie")
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(signingKey).To(HaveLen(cookieSigningKeyLength))
+	g.Expect(encryptionKey).To(HaveLen(cookieEncryptionKeyLength))
+
+	// Other replicas read the same keys.
+	signingKeyReplica, encryptionKeyReplica, err := loadCookieKeys(ctx, c, "rancher-bind-system/cookie")
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(signingKeyReplica).To(Equal(signingKey))
+	g.Expect(encryptionKeyReplica).To(Equal(encryptionKey))
+
+	g.Expect(c.Create(ctx, &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "rancher-bind-system", Name: "invalid"},
+		Data: map[string][]byte{
+			CookieSigningKeyName:    []byte("signing"),
+			CookieEncryptionKeyName: []byte("short"),
+		},
+	})).To(Succeed())
+	_, _, err = loadCookieKeys(ctx, c, "rancher-bind-system/invalid")
+	g.Expect(err).To(MatchError(ContainSubstring("cookie encryption key must be 16, 24 or 32 bytes long")))
+
+	g.Expect(c.Get(ctx, client.ObjectKey{Namespace: "rancher-bind-system", Name: "cookie"}, &corev1.Secret{})).To(Succeed())
+}
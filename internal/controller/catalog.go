@@ -0,0 +1,245 @@
+/*
+Copyright 2023 SUSE.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	apiextensionsinformers "k8s.io/apiextensions-apiserver/pkg/client/informers/externalversions/apiextensions/v1"
+	apiextensionslisters "k8s.io/apiextensions-apiserver/pkg/client/listers/apiextensions/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	kubebindv1alpha1 "github.com/kube-bind/kube-bind/pkg/apis/kubebind/v1alpha1"
+	"github.com/kube-bind/kube-bind/pkg/apis/third_party/conditions/util/conditions"
+	bindinformers "github.com/kube-bind/kube-bind/pkg/client/informers/externalversions/kubebind/v1alpha1"
+	bindlisters "github.com/kube-bind/kube-bind/pkg/client/listers/kubebind/v1alpha1"
+
+	rancherv1alpha1 "github.com/Danil-Grigorev/rancher-bind/pkg/apis/rancherbind/v1alpha1"
+)
+
+//+kubebuilder:rbac:groups=rancher.kube-bind.io,resources=rancherexportcatalogs,verbs=get;list;watch;create
+
+// LoadCatalog returns the union of all catalogs.
+func LoadCatalog(ctx context.Context, cl client.Reader) (*rancherv1alpha1.RancherExportCatalog, error) {
+	catalogs := &rancherv1alpha1.RancherExportCatalogList{}
+	if err := cl.List(ctx, catalogs); err != nil {
+		return nil, fmt.Errorf("unable to list export catalogs: %w", err)
+	}
+
+	union := &rancherv1alpha1.RancherExportCatalog{}
+	for i := range catalogs.Items {
+		for _, resource := range catalogs.Items[i].Spec.Resources {
+			if union.Find(resource.Group, resource.Resource) == nil {
+				union.Spec.Resources = append(union.Spec.Resources, resource)
+			}
+		}
+		for _, bundle := range catalogs.Items[i].Spec.Bundles {
+			if union.FindBundle(bundle.Name) == nil {
+				union.Spec.Bundles = append(union.Spec.Bundles, bundle)
+			}
+		}
+	}
+
+	return union, nil
+}
+
+// DefaultCatalog creates the default catalog on a fresh installation. Once any catalog
+// but the legacy-exported one exists it is left to the admin.
+type DefaultCatalog struct {
+	Client client.Client
+}
+
+func (d *DefaultCatalog) Start(ctx context.Context) error {
+	catalogs := &rancherv1alpha1.RancherExportCatalogList{}
+	if err := d.Client.List(ctx, catalogs); err != nil {
+		return fmt.Errorf("unable to list export catalogs: %w", err)
+	}
+	for _, catalog := range catalogs.Items {
+		if catalog.Name != rancherv1alpha1.LegacyCatalogName {
+			return nil
+		}
+	}
+
+	catalog := &rancherv1alpha1.RancherExportCatalog{
+		ObjectMeta: metav1.ObjectMeta{Name: rancherv1alpha1.DefaultCatalogName},
+		Spec: rancherv1alpha1.RancherExportCatalogSpec{
+			Resources: rancherv1alpha1.DefaultCatalogResources,
+			Bundles:   rancherv1alpha1.DefaultCatalogBundles,
+		},
+	}
+	if err := d.Client.Create(ctx, catalog); err != nil && !apierrors.IsAlreadyExists(err) {
+		return fmt.Errorf("unable to create default export catalog: %w", err)
+	}
+
+	log.FromContext(ctx).Info("Created default export catalog", "resources", len(catalog.Spec.Resources), "bundles", len(catalog.Spec.Bundles))
+	return nil
+}
+
+// catalogCRDInformer hides the CRDs outside the catalog and its bundles from the listers
+// handed out, so the kube-bind controllers never export them, and delete the exports of
+// resources removed from the catalog.
+type catalogCRDInformer struct {
+	apiextensionsinformers.CustomResourceDefinitionInformer
+	reader client.Reader
+}
+
+func newCatalogCRDInformer(informer apiextensionsinformers.CustomResourceDefinitionInformer, reader client.Reader) *catalogCRDInformer {
+	return &catalogCRDInformer{
+		CustomResourceDefinitionInformer: informer,
+		reader:                           reader,
+	}
+}
+
+func (i *catalogCRDInformer) Lister() apiextensionslisters.CustomResourceDefinitionLister {
+	return &catalogCRDLister{
+		CustomResourceDefinitionLister: i.CustomResourceDefinitionInformer.Lister(),
+		reader:                         i.reader,
+	}
+}
+
+type catalogCRDLister struct {
+	apiextensionslisters.CustomResourceDefinitionLister
+	reader client.Reader
+}
+
+func (l *catalogCRDLister) List(selector labels.Selector) ([]*apiextensionsv1.CustomResourceDefinition, error) {
+	crds, err := l.CustomResourceDefinitionLister.List(selector)
+	if err != nil {
+		return nil, err
+	}
+
+	catalog, err := LoadCatalog(context.TODO(), l.reader)
+	if err != nil {
+		return nil, err
+	}
+
+	offered := []*apiextensionsv1.CustomResourceDefinition{}
+	for _, crd := range crds {
+		if catalog.Offers(crd.Spec.Group, crd.Spec.Names.Plural) {
+			offered = append(offered, crd)
+		}
+	}
+
+	return offered, nil
+}
+
+func (l *catalogCRDLister) Get(name string) (*apiextensionsv1.CustomResourceDefinition, error) {
+	crd, err := l.CustomResourceDefinitionLister.Get(name)
+	if err != nil {
+		return nil, err
+	}
+
+	catalog, err := LoadCatalog(context.TODO(), l.reader)
+	if err != nil {
+		return nil, err
+	}
+	if !catalog.Offers(crd.Spec.Group, crd.Spec.Names.Plural) {
+		return nil, apierrors.NewNotFound(apiextensionsv1.Resource("customresourcedefinitions"), name)
+	}
+
+	return crd, nil
+}
+
+// catalogExportRequestInformer hides the pending export requests not yet accepted by the
+// ExportRequestReconciler from the listers handed out, so the kube-bind controller never
+// creates the exports of requests outside the catalog, like bundle resources requested on
+// their own. Consumers can not update the request status, so they can not accept requests.
+type catalogExportRequestInformer struct {
+	bindinformers.APIServiceExportRequestInformer
+}
+
+func newCatalogExportRequestInformer(informer bindinformers.APIServiceExportRequestInformer) *catalogExportRequestInformer {
+	return &catalogExportRequestInformer{APIServiceExportRequestInformer: informer}
+}
+
+func (i *catalogExportRequestInformer) Lister() bindlisters.APIServiceExportRequestLister {
+	return &catalogExportRequestLister{
+		APIServiceExportRequestLister: i.APIServiceExportRequestInformer.Lister(),
+	}
+}
+
+type catalogExportRequestLister struct {
+	bindlisters.APIServiceExportRequestLister
+}
+
+func (l *catalogExportRequestLister) List(selector labels.Selector) ([]*kubebindv1alpha1.APIServiceExportRequest, error) {
+	requests, err := l.APIServiceExportRequestLister.List(selector)
+	if err != nil {
+		return nil, err
+	}
+
+	return acceptedRequests(requests), nil
+}
+
+func (l *catalogExportRequestLister) APIServiceExportRequests(namespace string) bindlisters.APIServiceExportRequestNamespaceLister {
+	return &catalogExportRequestNamespaceLister{
+		APIServiceExportRequestNamespaceLister: l.APIServiceExportRequestLister.APIServiceExportRequests(namespace),
+	}
+}
+
+type catalogExportRequestNamespaceLister struct {
+	bindlisters.APIServiceExportRequestNamespaceLister
+}
+
+func (l *catalogExportRequestNamespaceLister) List(selector labels.Selector) ([]*kubebindv1alpha1.APIServiceExportRequest, error) {
+	requests, err := l.APIServiceExportRequestNamespaceLister.List(selector)
+	if err != nil {
+		return nil, err
+	}
+
+	return acceptedRequests(requests), nil
+}
+
+func (l *catalogExportRequestNamespaceLister) Get(name string) (*kubebindv1alpha1.APIServiceExportRequest, error) {
+	request, err := l.APIServiceExportRequestNamespaceLister.Get(name)
+	if err != nil {
+		return nil, err
+	}
+	if !accepted(request) {
+		return nil, apierrors.NewNotFound(kubebindv1alpha1.Resource("apiserviceexportrequests"), name)
+	}
+
+	return request, nil
+}
+
+// accepted returns true if the export request is no longer pending, or was accepted by the
+// ExportRequestReconciler.
+func accepted(request *kubebindv1alpha1.APIServiceExportRequest) bool {
+	switch request.Status.Phase {
+	case "", kubebindv1alpha1.APIServiceExportRequestPhasePending:
+		return conditions.IsTrue(request, InCatalogCondition)
+	default:
+		return true
+	}
+}
+
+func acceptedRequests(requests []*kubebindv1alpha1.APIServiceExportRequest) []*kubebindv1alpha1.APIServiceExportRequest {
+	result := []*kubebindv1alpha1.APIServiceExportRequest{}
+	for _, request := range requests {
+		if accepted(request) {
+			result = append(result, request)
+		}
+	}
+
+	return result
+}
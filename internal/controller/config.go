@@ -17,62 +17,148 @@ limitations under the License.
 package controller
 
 import (
-	"time"
+	"context"
+	"fmt"
+	"reflect"
 
-	// Import all Kubernetes client auth plugins (e.g. Azure, GCP, OIDC, etc.)
-	// to ensure that exec-entrypoint and run can make use of them.
-	_ "k8s.io/client-go/plugin/pkg/client/auth"
+	corev1 "k8s.io/api/core/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	apiextensionsinformers "k8s.io/apiextensions-apiserver/pkg/client/informers/externalversions/apiextensions/v1"
+	apiextensionslisters "k8s.io/apiextensions-apiserver/pkg/client/listers/apiextensions/v1"
+	coreinformers "k8s.io/client-go/informers/core/v1"
+	rbacinformers "k8s.io/client-go/informers/rbac/v1"
+	corelisters "k8s.io/client-go/listers/core/v1"
+	rbaclisters "k8s.io/client-go/listers/rbac/v1"
 	"k8s.io/client-go/rest"
-	"k8s.io/client-go/tools/clientcmd"
+	toolscache "k8s.io/client-go/tools/cache"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
 
-	apiextensionsclient "k8s.io/apiextensions-apiserver/pkg/client/clientset/clientset"
-	apiextensionsinformers "k8s.io/apiextensions-apiserver/pkg/client/informers/externalversions"
-	kubeinformers "k8s.io/client-go/informers"
-	kubernetesclient "k8s.io/client-go/kubernetes"
+	kubebindv1alpha1 "github.com/kube-bind/kube-bind/pkg/apis/kubebind/v1alpha1"
+	bindinformers "github.com/kube-bind/kube-bind/pkg/client/informers/externalversions/kubebind/v1alpha1"
+	bindlisters "github.com/kube-bind/kube-bind/pkg/client/listers/kubebind/v1alpha1"
 
-	bindclient "github.com/kube-bind/kube-bind/pkg/client/clientset/versioned"
-	bindinformers "github.com/kube-bind/kube-bind/pkg/client/informers/externalversions"
+	managementv3 "github.com/Danil-Grigorev/rancher-bind/pkg/apis/rancher/management/v3"
+	managementinformers "github.com/Danil-Grigorev/rancher-bind/pkg/client/informers/externalversions/management/v3"
+	managementlisters "github.com/Danil-Grigorev/rancher-bind/pkg/client/listers/management/v3"
 	//+kubebuilder:scaffold:imports
 )
 
+// Config holds the rest config and the informers shared by the kube-bind controllers and
+// the provider endpoints.
 type Config struct {
-	ClientConfig        *rest.Config
-	BindClient          *bindclient.Clientset
-	KubeClient          *kubernetesclient.Clientset
-	ApiextensionsClient *apiextensionsclient.Clientset
-
-	KubeInformers          kubeinformers.SharedInformerFactory
-	BindInformers          bindinformers.SharedInformerFactory
-	ApiextensionsInformers apiextensionsinformers.SharedInformerFactory
+	// ClientConfig is the manager rest config, with its QPS, burst and user agent.
+	ClientConfig *rest.Config
+
+	Informers *Informers
 }
 
-func NewConfig() (*Config, error) {
-	config := &Config{}
+// Informers are the typed informers the kube-bind controllers and the provider endpoints
+// consume. They are served from the manager cache, so every type is watched once.
+type Informers struct {
+	Namespaces   coreinformers.NamespaceInformer
+	Roles        rbacinformers.RoleInformer
+	RoleBindings rbacinformers.RoleBindingInformer
+
+	APIServiceNamespaces     bindinformers.APIServiceNamespaceInformer
+	ClusterBindings          bindinformers.ClusterBindingInformer
+	APIServiceExports        bindinformers.APIServiceExportInformer
+	APIServiceExportRequests bindinformers.APIServiceExportRequestInformer
+
+	CustomResourceDefinitions apiextensionsinformers.CustomResourceDefinitionInformer
+
+	Settings managementinformers.SettingInformer
+}
+
+// NewConfig returns the config sharing the rest config and the cache of the manager. The
+// manager scheme must include the types of all the informers.
+func NewConfig(mgr manager.Manager) (*Config, error) {
+	c := mgr.GetCache()
+	informers := &Informers{}
 
-	// create clients
-	rules := clientcmd.NewDefaultClientConfigLoadingRules()
 	var err error
-	config.ClientConfig, err = clientcmd.NewNonInteractiveDeferredLoadingClientConfig(rules, nil).ClientConfig()
-	if err != nil {
+	if informers.Namespaces, err = sharedInformerFor(c, &corev1.Namespace{}, corelisters.NewNamespaceLister); err != nil {
 		return nil, err
 	}
-	config.ClientConfig = rest.CopyConfig(config.ClientConfig)
-	config.ClientConfig = rest.AddUserAgent(config.ClientConfig, "kube-bind-example-backend")
-
-	if config.BindClient, err = bindclient.NewForConfig(config.ClientConfig); err != nil {
+	if informers.Roles, err = sharedInformerFor(c, &rbacv1.Role{}, rbaclisters.NewRoleLister); err != nil {
+		return nil, err
+	}
+	if informers.RoleBindings, err = sharedInformerFor(c, &rbacv1.RoleBinding{}, rbaclisters.NewRoleBindingLister); err != nil {
+		return nil, err
+	}
+	if informers.APIServiceNamespaces, err = sharedInformerFor(c, &kubebindv1alpha1.APIServiceNamespace{}, bindlisters.NewAPIServiceNamespaceLister); err != nil {
+		return nil, err
+	}
+	if informers.ClusterBindings, err = sharedInformerFor(c, &kubebindv1alpha1.ClusterBinding{}, bindlisters.NewClusterBindingLister); err != nil {
+		return nil, err
+	}
+	if informers.APIServiceExports, err = sharedInformerFor(c, &kubebindv1alpha1.APIServiceExport{}, bindlisters.NewAPIServiceExportLister); err != nil {
+		return nil, err
+	}
+	if informers.APIServiceExportRequests, err = sharedInformerFor(c, &kubebindv1alpha1.APIServiceExportRequest{}, bindlisters.NewAPIServiceExportRequestLister); err != nil {
 		return nil, err
 	}
-	if config.KubeClient, err = kubernetesclient.NewForConfig(config.ClientConfig); err != nil {
+	if informers.CustomResourceDefinitions, err = sharedInformerFor(c, &apiextensionsv1.CustomResourceDefinition{}, apiextensionslisters.NewCustomResourceDefinitionLister); err != nil {
 		return nil, err
 	}
-	if config.ApiextensionsClient, err = apiextensionsclient.NewForConfig(config.ClientConfig); err != nil {
+	if informers.Settings, err = sharedInformerFor(c, &managementv3.Setting{}, managementlisters.NewSettingLister); err != nil {
 		return nil, err
 	}
 
-	// construct informer factories
-	config.KubeInformers = kubeinformers.NewSharedInformerFactory(config.KubeClient, time.Minute*30)
-	config.BindInformers = bindinformers.NewSharedInformerFactory(config.BindClient, time.Minute*30)
-	config.ApiextensionsInformers = apiextensionsinformers.NewSharedInformerFactory(config.ApiextensionsClient, time.Minute*30)
+	return &Config{
+		ClientConfig: mgr.GetConfig(),
+		Informers:    informers,
+	}, nil
+}
+
+// Start implements InformerRunnable. The informers are started with the manager cache.
+func (i *Informers) Start(_ <-chan struct{}) {}
+
+// WaitForCacheSync implements InformerRunnable.
+func (i *Informers) WaitForCacheSync(stopCh <-chan struct{}) map[reflect.Type]bool {
+	synced := map[reflect.Type]bool{}
+	for _, informer := range []interface {
+		Informer() toolscache.SharedIndexInformer
+	}{
+		i.Namespaces, i.Roles, i.RoleBindings,
+		i.APIServiceNamespaces, i.ClusterBindings, i.APIServiceExports, i.APIServiceExportRequests,
+		i.CustomResourceDefinitions, i.Settings,
+	} {
+		synced[reflect.TypeOf(informer)] = toolscache.WaitForCacheSync(stopCh, informer.Informer().HasSynced)
+	}
+
+	return synced
+}
+
+// sharedInformer serves a typed client-go informer from an informer of the manager cache.
+type sharedInformer[L any] struct {
+	informer toolscache.SharedIndexInformer
+	lister   L
+}
+
+func (i *sharedInformer[L]) Informer() toolscache.SharedIndexInformer {
+	return i.informer
+}
+
+func (i *sharedInformer[L]) Lister() L {
+	return i.lister
+}
+
+func sharedInformerFor[L any](c cache.Cache, obj client.Object, newLister func(toolscache.Indexer) L) (*sharedInformer[L], error) {
+	informer, err := c.GetInformer(context.Background(), obj)
+	if err != nil {
+		return nil, fmt.Errorf("unable to get informer for %T: %w", obj, err)
+	}
+
+	shared, ok := informer.(toolscache.SharedIndexInformer)
+	if !ok {
+		return nil, fmt.Errorf("informer for %T is not a shared index informer", obj)
+	}
 
-	return config, nil
+	return &sharedInformer[L]{
+		informer: shared,
+		lister:   newLister(shared.GetIndexer()),
+	}, nil
 }
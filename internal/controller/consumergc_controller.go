@@ -0,0 +1,256 @@
+/*
+Copyright 2023 SUSE.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	kubebindv1alpha1 "github.com/kube-bind/kube-bind/pkg/apis/kubebind/v1alpha1"
+
+	managementv3 "github.com/Danil-Grigorev/rancher-bind/pkg/apis/rancher/management/v3"
+	rancherv1alpha1 "github.com/Danil-Grigorev/rancher-bind/pkg/apis/rancherbind/v1alpha1"
+)
+
+const (
+	// userConsumerIndex indexes the rancher users by the namespace of their consumer.
+	userConsumerIndex = "metadata.annotations.consumer-namespace"
+
+	// clusterBindingName is the name of the singleton ClusterBinding of a consumer.
+	clusterBindingName = "cluster"
+)
+
+// ConsumerGCReconciler removes the rancher users, bindings and tokens of consumers whose
+// ClusterBinding or APIServiceNamespace was deleted.
+type ConsumerGCReconciler struct {
+	client.Client
+
+	// GracePeriod is how long the rancher objects are kept after the consumer is gone, so a
+	// consumer rebinding right away keeps its kubeconfigs.
+	GracePeriod time.Duration
+
+	// APIReader lists the rancher Tokens without caching them, the manager API reader is used when unset.
+	APIReader client.Reader
+}
+
+//+kubebuilder:rbac:groups=rancher.kube-bind.io,resources=kubeconfigrequests,verbs=delete
+//+kubebuilder:rbac:groups=management.cattle.io,resources=users,verbs=get;list;watch;patch;delete
+
+// Reconcile garbage collects the rancher objects of a user created for a consumer.
+//
+// Flow:
+// - Check the ClusterBinding and the APIServiceNamespace of the consumer the user was created for.
+// - Annotate the user with the time the consumer was found gone, or drop the annotation once it is back.
+// - After the grace period delete the KubeconfigRequest or RancherBind declaring the user, which
+// removes the rancher objects. Without a RancherBind they are removed directly.
+func (r *ConsumerGCReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	user := &managementv3.User{}
+	if err := r.Get(ctx, req.NamespacedName, user); apierrors.IsNotFound(err) {
+		return ctrl.Result{}, nil
+	} else if err != nil {
+		return ctrl.Result{}, err
+	}
+
+	if user.Annotations[rancherv1alpha1.ConsumerNamespaceAnnotation] == "" || !user.DeletionTimestamp.IsZero() {
+		return ctrl.Result{}, nil
+	}
+
+	gone, err := r.consumerGone(ctx, user)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+
+	goneSince, err := markConsumerGone(ctx, r.Client, user, gone)
+	if err != nil || goneSince.IsZero() {
+		return ctrl.Result{}, err
+	}
+
+	if remaining := r.GracePeriod - time.Since(goneSince); remaining > 0 {
+		return ctrl.Result{RequeueAfter: remaining}, nil
+	}
+
+	return ctrl.Result{}, r.collect(ctx, user)
+}
+
+// markConsumerGone annotates the object with the time its consumer was found gone, or drops
+// the annotation once the consumer is back. It returns the time the consumer is gone since,
+// zero while the consumer is there.
+func markConsumerGone(ctx context.Context, c client.Client, obj client.Object, gone bool) (time.Time, error) {
+	logger := log.FromContext(ctx)
+	consumer := obj.GetAnnotations()[rancherv1alpha1.ConsumerNamespaceAnnotation]
+
+	original := obj.DeepCopyObject().(client.Object)
+	goneSince, parseErr := time.Parse(time.RFC3339, obj.GetAnnotations()[rancherv1alpha1.ConsumerGoneAnnotation])
+	switch {
+	case !gone && parseErr == nil:
+		annotations := obj.GetAnnotations()
+		delete(annotations, rancherv1alpha1.ConsumerGoneAnnotation)
+		obj.SetAnnotations(annotations)
+		if err := c.Patch(ctx, obj, client.MergeFrom(original)); err != nil {
+			return time.Time{}, fmt.Errorf("unable to unmark %T %s: %w", obj, obj.GetName(), err)
+		}
+
+		logger.Info("Consumer is back, keeping its rancher objects", "consumer", consumer)
+		return time.Time{}, nil
+	case !gone:
+		return time.Time{}, nil
+	case parseErr != nil:
+		goneSince = time.Now()
+		setAnnotations(obj, map[string]string{rancherv1alpha1.ConsumerGoneAnnotation: goneSince.UTC().Format(time.RFC3339)})
+		if err := c.Patch(ctx, obj, client.MergeFrom(original)); err != nil {
+			return time.Time{}, fmt.Errorf("unable to mark %T %s: %w", obj, obj.GetName(), err)
+		}
+
+		logger.Info("Consumer is gone, removing its rancher objects after the grace period", "consumer", consumer)
+	}
+
+	return goneSince, nil
+}
+
+// consumerGone returns true once the ClusterBinding or the APIServiceNamespace the user was
+// created through is deleted.
+func (r *ConsumerGCReconciler) consumerGone(ctx context.Context, user *managementv3.User) (bool, error) {
+	key := client.ObjectKey{
+		Namespace: user.Annotations[rancherv1alpha1.ConsumerNamespaceAnnotation],
+		Name:      clusterBindingName,
+	}
+	if gone, err := deleted(ctx, r.Client, key, &kubebindv1alpha1.ClusterBinding{}); gone || err != nil {
+		return gone, err
+	}
+
+	namespace, name, ok := strings.Cut(user.Annotations[rancherv1alpha1.APIServiceNamespaceAnnotation], "/")
+	if !ok {
+		return false, nil
+	}
+
+	return deleted(ctx, r.Client, client.ObjectKey{Namespace: namespace, Name: name}, &kubebindv1alpha1.APIServiceNamespace{})
+}
+
+// deleted returns true if the object does not exist or is being deleted.
+func deleted(ctx context.Context, c client.Reader, key client.ObjectKey, obj client.Object) (bool, error) {
+	if err := c.Get(ctx, key, obj); apierrors.IsNotFound(err) {
+		return true, nil
+	} else if err != nil {
+		return false, fmt.Errorf("unable to get %T %s: %w", obj, key, err)
+	}
+
+	return !obj.GetDeletionTimestamp().IsZero(), nil
+}
+
+// collect removes the rancher objects of the user. A remaining RancherBind would recreate
+// them, so it is deleted instead, through the KubeconfigRequest declaring it if any, and its
+// finalizer removes them.
+func (r *ConsumerGCReconciler) collect(ctx context.Context, user *managementv3.User) error {
+	logger := log.FromContext(ctx)
+
+	bind := &rancherv1alpha1.RancherBind{}
+	key := client.ObjectKey{
+		Namespace: user.Labels[rancherv1alpha1.RancherBindNamespaceLabel],
+		Name:      user.Labels[rancherv1alpha1.RancherBindNameLabel],
+	}
+	err := r.Get(ctx, key, bind)
+	if err == nil {
+		if !bind.DeletionTimestamp.IsZero() {
+			return nil
+		}
+
+		var declaration client.Object = bind
+		if ref := metav1.GetControllerOf(bind); ref != nil && ref.APIVersion == rancherv1alpha1.GroupVersion.String() && ref.Kind == "KubeconfigRequest" {
+			declaration = &rancherv1alpha1.KubeconfigRequest{ObjectMeta: metav1.ObjectMeta{
+				Namespace: bind.Namespace,
+				Name:      ref.Name,
+			}}
+		}
+		if err := r.Delete(ctx, declaration); client.IgnoreNotFound(err) != nil {
+			return fmt.Errorf("unable to delete the declaration of %s: %w", key, err)
+		}
+
+		logger.Info("Deleted the declaration of a gone consumer", "namespace", key.Namespace, "name", declaration.GetName())
+		return nil
+	} else if !apierrors.IsNotFound(err) {
+		return fmt.Errorf("unable to get RancherBind %s: %w", key, err)
+	}
+
+	// The RancherBind is gone without removing the rancher objects, e.g. its namespace was
+	// removed while the controller was down.
+	bind.Namespace = key.Namespace
+	bind.Name = key.Name
+	if err := cleanup(ctx, r.Client, r.APIReader, bind); err != nil {
+		return err
+	}
+
+	logger.Info("Removed rancher objects of a gone consumer", "user", user.Name)
+	return nil
+}
+
+// usersForConsumer enqueues the users created for the consumer of a ClusterBinding or an
+// APIServiceNamespace.
+func (r *ConsumerGCReconciler) usersForConsumer(ctx context.Context, obj client.Object) []reconcile.Request {
+	users := &managementv3.UserList{}
+	if err := r.List(ctx, users, client.MatchingFields{userConsumerIndex: obj.GetNamespace()}); err != nil {
+		log.FromContext(ctx).Error(err, "Unable to list users", "consumer", obj.GetNamespace())
+		return nil
+	}
+
+	result := []reconcile.Request{}
+	for _, user := range users.Items {
+		result = append(result, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(&user)})
+	}
+
+	return result
+}
+
+// userConsumer returns the consumer namespace of a rancher user for the userConsumerIndex.
+func userConsumer(obj client.Object) []string {
+	if consumer, ok := obj.GetAnnotations()[rancherv1alpha1.ConsumerNamespaceAnnotation]; ok {
+		return []string{consumer}
+	}
+	return nil
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *ConsumerGCReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	if r.APIReader == nil {
+		r.APIReader = mgr.GetAPIReader()
+	}
+
+	if err := mgr.GetFieldIndexer().IndexField(context.Background(), &managementv3.User{}, userConsumerIndex, userConsumer); err != nil {
+		return err
+	}
+
+	return ctrl.NewControllerManagedBy(mgr).
+		Named("consumergc").
+		For(&managementv3.User{}, builder.WithPredicates(predicate.NewPredicateFuncs(func(obj client.Object) bool {
+			_, ok := obj.GetAnnotations()[rancherv1alpha1.ConsumerNamespaceAnnotation]
+			return ok
+		}))).
+		Watches(&kubebindv1alpha1.ClusterBinding{}, handler.EnqueueRequestsFromMapFunc(r.usersForConsumer)).
+		Watches(&kubebindv1alpha1.APIServiceNamespace{}, handler.EnqueueRequestsFromMapFunc(r.usersForConsumer)).
+		Complete(r)
+}
@@ -0,0 +1,103 @@
+/*
+Copyright 2023 SUSE.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	kubebindv1alpha1 "github.com/kube-bind/kube-bind/pkg/apis/kubebind/v1alpha1"
+
+	managementv3 "github.com/Danil-Grigorev/rancher-bind/pkg/apis/rancher/management/v3"
+	rancherv1alpha1 "github.com/Danil-Grigorev/rancher-bind/pkg/apis/rancherbind/v1alpha1"
+)
+
+func TestConsumerGC(t *testing.T) {
+	g := NewWithT(t)
+	ctx := context.Background()
+
+	scheme := runtime.NewScheme()
+	g.Expect(managementv3.AddToScheme(scheme)).To(Succeed())
+	g.Expect(rancherv1alpha1.AddToScheme(scheme)).To(Succeed())
+	g.Expect(kubebindv1alpha1.AddToScheme(scheme)).To(Succeed())
+
+	request := &rancherv1alpha1.KubeconfigRequest{ObjectMeta: metav1.ObjectMeta{Namespace: "kube-bind-a-default", Name: "admin", UID: "request-uid"}}
+	bind := &rancherv1alpha1.RancherBind{ObjectMeta: metav1.ObjectMeta{Namespace: "kube-bind-a-default", Name: "admin"}}
+	g.Expect(ctrl.SetControllerReference(request, bind, scheme)).To(Succeed())
+
+	user := &managementv3.User{ObjectMeta: metav1.ObjectMeta{
+		Name:   userName(bind),
+		Labels: ownerLabels(bind),
+		Annotations: map[string]string{
+			rancherv1alpha1.ConsumerNamespaceAnnotation:   "kube-bind-a",
+			rancherv1alpha1.APIServiceNamespaceAnnotation: "kube-bind-a/default",
+		},
+	}}
+	binding := &managementv3.GlobalRoleBinding{ObjectMeta: metav1.ObjectMeta{Name: user.Name + "-admin", Labels: ownerLabels(bind)}}
+	token := &managementv3.Token{ObjectMeta: metav1.ObjectMeta{
+		Name:   "token-abcde",
+		Labels: map[string]string{managementv3.TokenUserIDLabel: user.Name},
+	}}
+	clusterBinding := &kubebindv1alpha1.ClusterBinding{ObjectMeta: metav1.ObjectMeta{Namespace: "kube-bind-a", Name: "cluster"}}
+	serviceNamespace := &kubebindv1alpha1.APIServiceNamespace{ObjectMeta: metav1.ObjectMeta{Namespace: "kube-bind-a", Name: "default"}}
+
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(request, bind, user, binding, token, clusterBinding).Build()
+	r := &ConsumerGCReconciler{Client: c, GracePeriod: time.Hour, APIReader: c}
+	req := ctrl.Request{NamespacedName: client.ObjectKeyFromObject(user)}
+
+	// The APIServiceNamespace is gone, the user is kept for the grace period.
+	result, err := r.Reconcile(ctx, req)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(result.RequeueAfter).To(BeNumerically("~", time.Hour, time.Minute))
+	g.Expect(c.Get(ctx, req.NamespacedName, user)).To(Succeed())
+	g.Expect(user.Annotations).To(HaveKey(rancherv1alpha1.ConsumerGoneAnnotation))
+
+	// The consumer is back.
+	g.Expect(c.Create(ctx, serviceNamespace)).To(Succeed())
+	result, err = r.Reconcile(ctx, req)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(result.RequeueAfter).To(BeZero())
+	g.Expect(c.Get(ctx, req.NamespacedName, user)).To(Succeed())
+	g.Expect(user.Annotations).ToNot(HaveKey(rancherv1alpha1.ConsumerGoneAnnotation))
+
+	// The ClusterBinding is gone past the grace period, the KubeconfigRequest declaring the
+	// RancherBind is deleted.
+	g.Expect(c.Delete(ctx, clusterBinding)).To(Succeed())
+	user.Annotations[rancherv1alpha1.ConsumerGoneAnnotation] = time.Now().Add(-2 * time.Hour).UTC().Format(time.RFC3339)
+	g.Expect(c.Update(ctx, user)).To(Succeed())
+	_, err = r.Reconcile(ctx, req)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(apierrors.IsNotFound(c.Get(ctx, client.ObjectKeyFromObject(request), request))).To(BeTrue())
+	g.Expect(c.Get(ctx, req.NamespacedName, user)).To(Succeed())
+
+	// Without the RancherBind the rancher objects are removed directly.
+	g.Expect(c.Delete(ctx, bind)).To(Succeed())
+	_, err = r.Reconcile(ctx, req)
+	g.Expect(err).ToNot(HaveOccurred())
+	for _, obj := range []client.Object{user, binding, token} {
+		g.Expect(apierrors.IsNotFound(c.Get(ctx, client.ObjectKeyFromObject(obj), obj))).To(BeTrue(), "%T should be removed", obj)
+	}
+}
@@ -0,0 +1,83 @@
+/*
+Copyright 2023 SUSE.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	kubebindv1alpha1 "github.com/kube-bind/kube-bind/pkg/apis/kubebind/v1alpha1"
+
+	rancherv1alpha1 "github.com/Danil-Grigorev/rancher-bind/pkg/apis/rancherbind/v1alpha1"
+)
+
+// ConsumerLabelReconciler labels the service namespaces with the consumer namespace. The
+// policy webhook selects the namespaces by the label, keeping away from the rancher ones.
+// The namespace webhook labels them on creation, this labels the ones it missed, e.g. created
+// while the webhook was unavailable.
+type ConsumerLabelReconciler struct {
+	client.Client
+}
+
+// Reconcile labels the service namespace of an APIServiceNamespace.
+func (r *ConsumerLabelReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	serviceNamespace := &kubebindv1alpha1.APIServiceNamespace{}
+	if err := r.Get(ctx, req.NamespacedName, serviceNamespace); apierrors.IsNotFound(err) {
+		return ctrl.Result{}, nil
+	} else if err != nil {
+		return ctrl.Result{}, err
+	}
+	if !serviceNamespace.DeletionTimestamp.IsZero() || serviceNamespace.Status.Namespace == "" {
+		return ctrl.Result{}, nil
+	}
+
+	namespace := &corev1.Namespace{}
+	if err := r.Get(ctx, client.ObjectKey{Name: serviceNamespace.Status.Namespace}, namespace); apierrors.IsNotFound(err) {
+		return ctrl.Result{}, nil
+	} else if err != nil {
+		return ctrl.Result{}, err
+	}
+	if namespace.Labels[rancherv1alpha1.ConsumerNamespaceLabel] == serviceNamespace.Namespace {
+		return ctrl.Result{}, nil
+	}
+
+	original := namespace.DeepCopy()
+	if namespace.Labels == nil {
+		namespace.Labels = map[string]string{}
+	}
+	namespace.Labels[rancherv1alpha1.ConsumerNamespaceLabel] = serviceNamespace.Namespace
+	if err := r.Patch(ctx, namespace, client.MergeFrom(original)); err != nil {
+		return ctrl.Result{}, fmt.Errorf("unable to label namespace %s: %w", namespace.Name, err)
+	}
+
+	log.FromContext(ctx).Info("Labeled service namespace", "namespace", namespace.Name)
+	return ctrl.Result{}, nil
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *ConsumerLabelReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		Named("consumerlabel").
+		For(&kubebindv1alpha1.APIServiceNamespace{}).
+		Complete(r)
+}
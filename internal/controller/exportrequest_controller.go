@@ -0,0 +1,261 @@
+/*
+Copyright 2023 SUSE.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	kubebindv1alpha1 "github.com/kube-bind/kube-bind/pkg/apis/kubebind/v1alpha1"
+	conditionsapi "github.com/kube-bind/kube-bind/pkg/apis/third_party/conditions/apis/conditions/v1alpha1"
+	"github.com/kube-bind/kube-bind/pkg/apis/third_party/conditions/util/conditions"
+
+	rancherv1alpha1 "github.com/Danil-Grigorev/rancher-bind/pkg/apis/rancherbind/v1alpha1"
+)
+
+const (
+	// InCatalogCondition is true once the export request was checked against the catalog. The
+	// kube-bind controller only sees the pending requests with the condition.
+	InCatalogCondition conditionsapi.ConditionType = "InCatalog"
+
+	// NotInCatalogReason is the ExportsReady condition reason of rejected export requests.
+	NotInCatalogReason = "NotInCatalog"
+
+	// BundleMismatchReason is the ExportsReady condition reason of rejected bundle requests
+	// not matching the bundle offered by the catalog.
+	BundleMismatchReason = "BundleMismatch"
+)
+
+// ExportRequestReconciler rejects the APIServiceExportRequests for resources outside the catalog,
+// and groups the APIServiceExports of bundle requests.
+type ExportRequestReconciler struct {
+	client.Client
+	Scheme *runtime.Scheme
+}
+
+// Reconcile fails the pending export requests naming resources no catalog offers, deleting the
+// APIServiceExports created for them, and accepts the others by setting the InCatalog condition.
+// The kube-bind controller only sees the accepted requests, and never sees the CRDs outside
+// the catalog.
+//
+// Bundle requests carry the bundle name and version in their parameters, and must name exactly
+// the bundle resources. Once they succeed, their APIServiceExports are labeled with the bundle.
+func (r *ExportRequestReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	request := &kubebindv1alpha1.APIServiceExportRequest{}
+	if err := r.Get(ctx, req.NamespacedName, request); apierrors.IsNotFound(err) {
+		return ctrl.Result{}, nil
+	} else if err != nil {
+		return ctrl.Result{}, err
+	}
+
+	parameters := &rancherv1alpha1.BundleParameters{}
+	var parametersErr error
+	if request.Spec.Parameters != nil && len(request.Spec.Parameters.Raw) > 0 {
+		parametersErr = json.Unmarshal(request.Spec.Parameters.Raw, parameters)
+	}
+
+	switch request.Status.Phase {
+	case "", kubebindv1alpha1.APIServiceExportRequestPhasePending:
+	case kubebindv1alpha1.APIServiceExportRequestPhaseSucceeded:
+		if parametersErr != nil || parameters.Bundle == "" {
+			return ctrl.Result{}, nil
+		}
+		return ctrl.Result{}, r.labelBundleExports(ctx, request, parameters)
+	default:
+		return ctrl.Result{}, nil
+	}
+
+	if parametersErr != nil {
+		return ctrl.Result{}, r.reject(ctx, request, BundleMismatchReason, "invalid bundle parameters: %v", parametersErr)
+	}
+
+	catalog, err := LoadCatalog(ctx, r.Client)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+
+	if parameters.Bundle != "" {
+		return ctrl.Result{}, r.checkBundle(ctx, request, catalog, parameters)
+	}
+
+	rejected := []string{}
+	for _, resource := range request.Spec.Resources {
+		if resource.Group == rancherv1alpha1.BundleGroup {
+			return ctrl.Result{}, r.reject(ctx, request, NotInCatalogReason,
+				"bundle %s must be bound through the provider backend, which expands it into the bundle resources", resource.Resource)
+		}
+		if catalog.Find(resource.Group, resource.Resource) == nil {
+			rejected = append(rejected, resource.Resource+"."+resource.Group)
+		}
+	}
+	if len(rejected) == 0 {
+		return ctrl.Result{}, r.accept(ctx, request)
+	}
+
+	return ctrl.Result{}, r.reject(ctx, request, NotInCatalogReason,
+		"%s not offered by the provider, see the RancherExportCatalog for the available resources", strings.Join(rejected, ", "))
+}
+
+// checkBundle rejects the bundle requests for bundles no catalog offers, or not naming
+// exactly the resources of the current bundle version.
+func (r *ExportRequestReconciler) checkBundle(ctx context.Context, request *kubebindv1alpha1.APIServiceExportRequest, catalog *rancherv1alpha1.RancherExportCatalog, parameters *rancherv1alpha1.BundleParameters) error {
+	bundle := catalog.FindBundle(parameters.Bundle)
+	if bundle == nil {
+		return r.reject(ctx, request, NotInCatalogReason,
+			"bundle %s not offered by the provider, see the RancherExportCatalog for the available bundles", parameters.Bundle)
+	}
+	if bundle.Version != parameters.Version {
+		return r.reject(ctx, request, BundleMismatchReason,
+			"bundle %s version %s was requested, the provider offers version %s", bundle.Name, parameters.Version, bundle.Version)
+	}
+
+	matches := len(request.Spec.Resources) == len(bundle.Resources)
+	for _, resource := range request.Spec.Resources {
+		matches = matches && bundle.Contains(resource.Group, resource.Resource)
+	}
+	if !matches {
+		return r.reject(ctx, request, BundleMismatchReason,
+			"requested resources do not match bundle %s version %s", bundle.Name, bundle.Version)
+	}
+
+	return r.accept(ctx, request)
+}
+
+// labelBundleExports labels the APIServiceExports created for a bundle request with the
+// bundle name and version.
+func (r *ExportRequestReconciler) labelBundleExports(ctx context.Context, request *kubebindv1alpha1.APIServiceExportRequest, parameters *rancherv1alpha1.BundleParameters) error {
+	logger := log.FromContext(ctx)
+
+	for _, resource := range request.Spec.Resources {
+		export := &kubebindv1alpha1.APIServiceExport{}
+		key := client.ObjectKey{Namespace: request.Namespace, Name: resource.Resource + "." + resource.Group}
+		if err := r.Get(ctx, key, export); apierrors.IsNotFound(err) {
+			continue
+		} else if err != nil {
+			return err
+		}
+
+		labels := export.GetLabels()
+		if labels[rancherv1alpha1.BundleLabel] == parameters.Bundle && labels[rancherv1alpha1.BundleVersionLabel] == parameters.Version {
+			continue
+		}
+
+		original := export.DeepCopy()
+		if export.Labels == nil {
+			export.Labels = map[string]string{}
+		}
+		export.Labels[rancherv1alpha1.BundleLabel] = parameters.Bundle
+		export.Labels[rancherv1alpha1.BundleVersionLabel] = parameters.Version
+		if err := r.Patch(ctx, export, client.MergeFrom(original)); err != nil {
+			return fmt.Errorf("unable to label export %s: %w", key, err)
+		}
+
+		logger.Info("Labeled bundle export", "export", key.Name, "bundle", parameters.Bundle, "version", parameters.Version)
+	}
+
+	return nil
+}
+
+// accept hands the export request to the kube-bind controller.
+func (r *ExportRequestReconciler) accept(ctx context.Context, request *kubebindv1alpha1.APIServiceExportRequest) error {
+	if conditions.IsTrue(request, InCatalogCondition) {
+		return nil
+	}
+
+	original := request.DeepCopy()
+	conditions.MarkTrue(request, InCatalogCondition)
+	conditions.SetSummary(request)
+
+	if err := r.Status().Patch(ctx, request, client.MergeFrom(original)); err != nil {
+		return fmt.Errorf("unable to accept export request: %w", err)
+	}
+
+	log.FromContext(ctx).V(1).Info("Accepted export request")
+	return nil
+}
+
+// reject fails the export request with the message, and deletes the APIServiceExports created for it.
+func (r *ExportRequestReconciler) reject(ctx context.Context, request *kubebindv1alpha1.APIServiceExportRequest, reason, messageFormat string, args ...interface{}) error {
+	if err := r.deleteExports(ctx, request); err != nil {
+		return err
+	}
+
+	original := request.DeepCopy()
+	for _, condition := range []conditionsapi.ConditionType{InCatalogCondition, kubebindv1alpha1.APIServiceExportRequestConditionExportsReady} {
+		conditions.MarkFalse(
+			request,
+			condition,
+			reason,
+			conditionsapi.ConditionSeverityError,
+			messageFormat,
+			args...,
+		)
+	}
+	conditions.SetSummary(request)
+	request.Status.Phase = kubebindv1alpha1.APIServiceExportRequestPhaseFailed
+	request.Status.TerminalMessage = conditions.GetMessage(request, kubebindv1alpha1.APIServiceExportRequestConditionExportsReady)
+
+	if err := r.Status().Patch(ctx, request, client.MergeFrom(original)); err != nil {
+		return fmt.Errorf("unable to reject export request: %w", err)
+	}
+
+	log.FromContext(ctx).Info("Rejected export request", "reason", reason, "message", request.Status.TerminalMessage)
+	return nil
+}
+
+// deleteExports deletes the APIServiceExports of the request resources created since the request,
+// in case the kube-bind controller handled it before the catalog changed. Older exports belong
+// to earlier requests and are kept.
+func (r *ExportRequestReconciler) deleteExports(ctx context.Context, request *kubebindv1alpha1.APIServiceExportRequest) error {
+	logger := log.FromContext(ctx)
+
+	for _, resource := range request.Spec.Resources {
+		export := &kubebindv1alpha1.APIServiceExport{}
+		key := client.ObjectKey{Namespace: request.Namespace, Name: resource.Resource + "." + resource.Group}
+		if err := r.Get(ctx, key, export); apierrors.IsNotFound(err) {
+			continue
+		} else if err != nil {
+			return err
+		}
+		if export.CreationTimestamp.Before(&request.CreationTimestamp) {
+			continue
+		}
+
+		if err := r.Delete(ctx, export); client.IgnoreNotFound(err) != nil {
+			return fmt.Errorf("unable to delete export %s: %w", key, err)
+		}
+
+		logger.Info("Deleted export of rejected request", "export", key.Name)
+	}
+
+	return nil
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *ExportRequestReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&kubebindv1alpha1.APIServiceExportRequest{}).
+		Complete(r)
+}
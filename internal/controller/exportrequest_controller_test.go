@@ -0,0 +1,290 @@
+/*
+Copyright 2023 SUSE.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/cache"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	kubebindv1alpha1 "github.com/kube-bind/kube-bind/pkg/apis/kubebind/v1alpha1"
+	"github.com/kube-bind/kube-bind/pkg/apis/third_party/conditions/util/conditions"
+	bindlisters "github.com/kube-bind/kube-bind/pkg/client/listers/kubebind/v1alpha1"
+
+	rancherv1alpha1 "github.com/Danil-Grigorev/rancher-bind/pkg/apis/rancherbind/v1alpha1"
+)
+
+func TestExportRequest(t *testing.T) {
+	bundle := rancherv1alpha1.CatalogBundle{
+		Name:    "clusters",
+		Version: "v2",
+		Resources: []rancherv1alpha1.BundleResource{
+			{Group: "provisioning.cattle.io", Resource: "clusters"},
+			{Group: "rke-machine-config.cattle.io", Resource: "amazonec2configs"},
+		},
+	}
+	catalog := &rancherv1alpha1.RancherExportCatalog{
+		ObjectMeta: metav1.ObjectMeta{Name: rancherv1alpha1.DefaultCatalogName},
+		Spec: rancherv1alpha1.RancherExportCatalogSpec{
+			Resources: []rancherv1alpha1.CatalogResource{{Group: "fleet.cattle.io", Resource: "gitrepos"}},
+			Bundles:   []rancherv1alpha1.CatalogBundle{bundle},
+		},
+	}
+	resource := func(group, resource string) kubebindv1alpha1.APIServiceExportRequestResource {
+		return kubebindv1alpha1.APIServiceExportRequestResource{
+			GroupResource: kubebindv1alpha1.GroupResource{Group: group, Resource: resource},
+		}
+	}
+	parameters := func(bundle, version string) *runtime.RawExtension {
+		raw, _ := json.Marshal(rancherv1alpha1.BundleParameters{Bundle: bundle, Version: version})
+		return &runtime.RawExtension{Raw: raw}
+	}
+	bundleResources := []kubebindv1alpha1.APIServiceExportRequestResource{
+		resource("provisioning.cattle.io", "clusters"),
+		resource("rke-machine-config.cattle.io", "amazonec2configs"),
+	}
+
+	tests := []struct {
+		name       string
+		spec       kubebindv1alpha1.APIServiceExportRequestSpec
+		wantPhase  kubebindv1alpha1.APIServiceExportRequestPhase
+		wantReason string
+	}{
+		{
+			name:      "catalog resource",
+			spec:      kubebindv1alpha1.APIServiceExportRequestSpec{Resources: []kubebindv1alpha1.APIServiceExportRequestResource{resource("fleet.cattle.io", "gitrepos")}},
+			wantPhase: kubebindv1alpha1.APIServiceExportRequestPhasePending,
+		},
+		{
+			name:       "resource outside the catalog",
+			spec:       kubebindv1alpha1.APIServiceExportRequestSpec{Resources: []kubebindv1alpha1.APIServiceExportRequestResource{resource("management.cattle.io", "users")}},
+			wantPhase:  kubebindv1alpha1.APIServiceExportRequestPhaseFailed,
+			wantReason: NotInCatalogReason,
+		},
+		{
+			name:       "bundle resource on its own",
+			spec:       kubebindv1alpha1.APIServiceExportRequestSpec{Resources: []kubebindv1alpha1.APIServiceExportRequestResource{resource("provisioning.cattle.io", "clusters")}},
+			wantPhase:  kubebindv1alpha1.APIServiceExportRequestPhaseFailed,
+			wantReason: NotInCatalogReason,
+		},
+		{
+			name:       "bundle pseudo resource",
+			spec:       kubebindv1alpha1.APIServiceExportRequestSpec{Resources: []kubebindv1alpha1.APIServiceExportRequestResource{resource(rancherv1alpha1.BundleGroup, "clusters")}},
+			wantPhase:  kubebindv1alpha1.APIServiceExportRequestPhaseFailed,
+			wantReason: NotInCatalogReason,
+		},
+		{
+			name:      "bundle",
+			spec:      kubebindv1alpha1.APIServiceExportRequestSpec{Parameters: parameters("clusters", "v2"), Resources: bundleResources},
+			wantPhase: kubebindv1alpha1.APIServiceExportRequestPhasePending,
+		},
+		{
+			name:       "unknown bundle",
+			spec:       kubebindv1alpha1.APIServiceExportRequestSpec{Parameters: parameters("other", "v2"), Resources: bundleResources},
+			wantPhase:  kubebindv1alpha1.APIServiceExportRequestPhaseFailed,
+			wantReason: NotInCatalogReason,
+		},
+		{
+			name:       "previous bundle version",
+			spec:       kubebindv1alpha1.APIServiceExportRequestSpec{Parameters: parameters("clusters", "v1"), Resources: bundleResources},
+			wantPhase:  kubebindv1alpha1.APIServiceExportRequestPhaseFailed,
+			wantReason: BundleMismatchReason,
+		},
+		{
+			name:       "partial bundle",
+			spec:       kubebindv1alpha1.APIServiceExportRequestSpec{Parameters: parameters("clusters", "v2"), Resources: bundleResources[:1]},
+			wantPhase:  kubebindv1alpha1.APIServiceExportRequestPhaseFailed,
+			wantReason: BundleMismatchReason,
+		},
+		{
+			name:       "invalid parameters",
+			spec:       kubebindv1alpha1.APIServiceExportRequestSpec{Parameters: &runtime.RawExtension{Raw: []byte(`"clusters"`)}, Resources: bundleResources},
+			wantPhase:  kubebindv1alpha1.APIServiceExportRequestPhaseFailed,
+			wantReason: BundleMismatchReason,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+			ctx := context.Background()
+
+			scheme := runtime.NewScheme()
+			g.Expect(rancherv1alpha1.AddToScheme(scheme)).To(Succeed())
+			g.Expect(kubebindv1alpha1.AddToScheme(scheme)).To(Succeed())
+
+			request := &kubebindv1alpha1.APIServiceExportRequest{
+				ObjectMeta: metav1.ObjectMeta{Namespace: "kube-bind-a-default", Name: "request"},
+				Spec:       tt.spec,
+				Status:     kubebindv1alpha1.APIServiceExportRequestStatus{Phase: kubebindv1alpha1.APIServiceExportRequestPhasePending},
+			}
+			c := fake.NewClientBuilder().WithScheme(scheme).
+				WithObjects(catalog.DeepCopy(), request).
+				WithStatusSubresource(&kubebindv1alpha1.APIServiceExportRequest{}).
+				Build()
+			r := &ExportRequestReconciler{Client: c, Scheme: scheme}
+
+			_, err := r.Reconcile(ctx, ctrl.Request{NamespacedName: client.ObjectKeyFromObject(request)})
+			g.Expect(err).ToNot(HaveOccurred())
+
+			g.Expect(c.Get(ctx, client.ObjectKeyFromObject(request), request)).To(Succeed())
+			g.Expect(request.Status.Phase).To(Equal(tt.wantPhase))
+			if tt.wantReason != "" {
+				g.Expect(conditions.GetReason(request, kubebindv1alpha1.APIServiceExportRequestConditionExportsReady)).To(Equal(tt.wantReason))
+				g.Expect(request.Status.TerminalMessage).ToNot(BeEmpty())
+				g.Expect(accepted(request)).To(BeTrue(), "failed requests are handed to kube-bind for the cleanup")
+			} else {
+				g.Expect(conditions.IsTrue(request, InCatalogCondition)).To(BeTrue())
+			}
+		})
+	}
+}
+
+func TestExportRequestLabelsBundleExports(t *testing.T) {
+	g := NewWithT(t)
+	ctx := context.Background()
+
+	scheme := runtime.NewScheme()
+	g.Expect(rancherv1alpha1.AddToScheme(scheme)).To(Succeed())
+	g.Expect(kubebindv1alpha1.AddToScheme(scheme)).To(Succeed())
+
+	raw, err := json.Marshal(rancherv1alpha1.BundleParameters{Bundle: "clusters", Version: "v2"})
+	g.Expect(err).ToNot(HaveOccurred())
+	request := &kubebindv1alpha1.APIServiceExportRequest{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "kube-bind-a-default", Name: "request"},
+		Spec: kubebindv1alpha1.APIServiceExportRequestSpec{
+			Parameters: &runtime.RawExtension{Raw: raw},
+			Resources: []kubebindv1alpha1.APIServiceExportRequestResource{
+				{GroupResource: kubebindv1alpha1.GroupResource{Group: "provisioning.cattle.io", Resource: "clusters"}},
+				{GroupResource: kubebindv1alpha1.GroupResource{Group: "rke-machine-config.cattle.io", Resource: "amazonec2configs"}},
+			},
+		},
+		Status: kubebindv1alpha1.APIServiceExportRequestStatus{Phase: kubebindv1alpha1.APIServiceExportRequestPhaseSucceeded},
+	}
+	// The second export is not created yet.
+	export := &kubebindv1alpha1.APIServiceExport{
+		ObjectMeta: metav1.ObjectMeta{Namespace: request.Namespace, Name: "clusters.provisioning.cattle.io"},
+	}
+	c := fake.NewClientBuilder().WithScheme(scheme).
+		WithObjects(request, export).
+		WithStatusSubresource(&kubebindv1alpha1.APIServiceExportRequest{}).
+		Build()
+	r := &ExportRequestReconciler{Client: c, Scheme: scheme}
+
+	_, err = r.Reconcile(ctx, ctrl.Request{NamespacedName: client.ObjectKeyFromObject(request)})
+	g.Expect(err).ToNot(HaveOccurred())
+
+	g.Expect(c.Get(ctx, client.ObjectKeyFromObject(export), export)).To(Succeed())
+	g.Expect(export.Labels).To(HaveKeyWithValue(rancherv1alpha1.BundleLabel, "clusters"))
+	g.Expect(export.Labels).To(HaveKeyWithValue(rancherv1alpha1.BundleVersionLabel, "v2"))
+}
+
+func TestExportRequestRejectionDeletesExports(t *testing.T) {
+	g := NewWithT(t)
+	ctx := context.Background()
+
+	scheme := runtime.NewScheme()
+	g.Expect(rancherv1alpha1.AddToScheme(scheme)).To(Succeed())
+	g.Expect(kubebindv1alpha1.AddToScheme(scheme)).To(Succeed())
+
+	created := metav1.NewTime(time.Now().Truncate(time.Second))
+	request := &kubebindv1alpha1.APIServiceExportRequest{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "kube-bind-a-default", Name: "request", CreationTimestamp: created},
+		Spec: kubebindv1alpha1.APIServiceExportRequestSpec{
+			Resources: []kubebindv1alpha1.APIServiceExportRequestResource{
+				{GroupResource: kubebindv1alpha1.GroupResource{Group: "fleet.cattle.io", Resource: "gitrepos"}},
+				{GroupResource: kubebindv1alpha1.GroupResource{Group: "provisioning.cattle.io", Resource: "clusters"}},
+			},
+		},
+		Status: kubebindv1alpha1.APIServiceExportRequestStatus{Phase: kubebindv1alpha1.APIServiceExportRequestPhasePending},
+	}
+	// The gitrepos export was created by an earlier request, the clusters one for this request.
+	earlier := &kubebindv1alpha1.APIServiceExport{ObjectMeta: metav1.ObjectMeta{
+		Namespace:         request.Namespace,
+		Name:              "gitrepos.fleet.cattle.io",
+		CreationTimestamp: metav1.NewTime(created.Add(-time.Hour)),
+	}}
+	partial := &kubebindv1alpha1.APIServiceExport{ObjectMeta: metav1.ObjectMeta{
+		Namespace:         request.Namespace,
+		Name:              "clusters.provisioning.cattle.io",
+		CreationTimestamp: created,
+	}}
+	c := fake.NewClientBuilder().WithScheme(scheme).
+		WithObjects(request, earlier, partial).
+		WithStatusSubresource(&kubebindv1alpha1.APIServiceExportRequest{}).
+		Build()
+	r := &ExportRequestReconciler{Client: c, Scheme: scheme}
+
+	_, err := r.Reconcile(ctx, ctrl.Request{NamespacedName: client.ObjectKeyFromObject(request)})
+	g.Expect(err).ToNot(HaveOccurred())
+
+	g.Expect(c.Get(ctx, client.ObjectKeyFromObject(request), request)).To(Succeed())
+	g.Expect(request.Status.Phase).To(Equal(kubebindv1alpha1.APIServiceExportRequestPhaseFailed))
+	g.Expect(conditions.IsFalse(request, InCatalogCondition)).To(BeTrue())
+	g.Expect(c.Get(ctx, client.ObjectKeyFromObject(earlier), earlier)).To(Succeed())
+	g.Expect(apierrors.IsNotFound(c.Get(ctx, client.ObjectKeyFromObject(partial), partial))).To(BeTrue())
+}
+
+func TestCatalogExportRequestLister(t *testing.T) {
+	g := NewWithT(t)
+
+	indexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{cache.NamespaceIndex: cache.MetaNamespaceIndexFunc})
+	request := func(name string, phase kubebindv1alpha1.APIServiceExportRequestPhase, inCatalog bool) *kubebindv1alpha1.APIServiceExportRequest {
+		request := &kubebindv1alpha1.APIServiceExportRequest{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "kube-bind-a-default", Name: name},
+			Status:     kubebindv1alpha1.APIServiceExportRequestStatus{Phase: phase},
+		}
+		if inCatalog {
+			conditions.MarkTrue(request, InCatalogCondition)
+		}
+		g.Expect(indexer.Add(request)).To(Succeed())
+		return request
+	}
+	request("unchecked", kubebindv1alpha1.APIServiceExportRequestPhasePending, false)
+	request("defaulted", "", false)
+	request("accepted", kubebindv1alpha1.APIServiceExportRequestPhasePending, true)
+	request("succeeded", kubebindv1alpha1.APIServiceExportRequestPhaseSucceeded, true)
+
+	lister := (&catalogExportRequestLister{APIServiceExportRequestLister: bindlisters.NewAPIServiceExportRequestLister(indexer)}).
+		APIServiceExportRequests("kube-bind-a-default")
+
+	_, err := lister.Get("unchecked")
+	g.Expect(apierrors.IsNotFound(err)).To(BeTrue())
+	_, err = lister.Get("defaulted")
+	g.Expect(apierrors.IsNotFound(err)).To(BeTrue())
+	_, err = lister.Get("accepted")
+	g.Expect(err).ToNot(HaveOccurred())
+
+	requests, err := lister.List(labels.Everything())
+	g.Expect(err).ToNot(HaveOccurred())
+	names := []string{}
+	for _, request := range requests {
+		names = append(names, request.Name)
+	}
+	g.Expect(names).To(ConsistOf("accepted", "succeeded"))
+}
@@ -0,0 +1,102 @@
+/*
+Copyright 2023 SUSE.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	kubebindv1alpha1 "github.com/kube-bind/kube-bind/pkg/apis/kubebind/v1alpha1"
+
+	managementv3 "github.com/Danil-Grigorev/rancher-bind/pkg/apis/rancher/management/v3"
+	rancherv1alpha1 "github.com/Danil-Grigorev/rancher-bind/pkg/apis/rancherbind/v1alpha1"
+	"github.com/Danil-Grigorev/rancher-bind/pkg/kubectl/bind-kubeconfig/plugin"
+)
+
+// FleetWorkspaceReconciler makes the service namespaces of the consumers fleet workspaces.
+// Rancher provisions the clusters of fleet workspace namespaces only, like fleet-default,
+// while kube-bind syncs the clusters of a consumer into its service namespaces.
+type FleetWorkspaceReconciler struct {
+	client.Client
+	Scheme *runtime.Scheme
+}
+
+//+kubebuilder:rbac:groups=management.cattle.io,resources=fleetworkspaces,verbs=get;list;watch;create
+
+// Reconcile creates the FleetWorkspace of the service namespace of an APIServiceNamespace.
+// The workspace is named after the namespace and owned by it, so it is garbage collected
+// together with the namespace.
+func (r *FleetWorkspaceReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	serviceNamespace := &kubebindv1alpha1.APIServiceNamespace{}
+	if err := r.Get(ctx, req.NamespacedName, serviceNamespace); apierrors.IsNotFound(err) {
+		return ctrl.Result{}, nil
+	} else if err != nil {
+		return ctrl.Result{}, err
+	}
+	if !serviceNamespace.DeletionTimestamp.IsZero() || serviceNamespace.Status.Namespace == "" {
+		return ctrl.Result{}, nil
+	}
+
+	namespace := &corev1.Namespace{}
+	if err := r.Get(ctx, client.ObjectKey{Name: serviceNamespace.Status.Namespace}, namespace); apierrors.IsNotFound(err) {
+		return ctrl.Result{}, nil
+	} else if err != nil {
+		return ctrl.Result{}, err
+	}
+	if !namespace.DeletionTimestamp.IsZero() {
+		return ctrl.Result{}, nil
+	}
+
+	workspace := &managementv3.FleetWorkspace{}
+	if err := r.Get(ctx, client.ObjectKey{Name: namespace.Name}, workspace); err == nil {
+		return ctrl.Result{}, nil
+	} else if !apierrors.IsNotFound(err) {
+		return ctrl.Result{}, fmt.Errorf("unable to get fleet workspace: %w", err)
+	}
+
+	workspace = &managementv3.FleetWorkspace{ObjectMeta: metav1.ObjectMeta{
+		Name:        namespace.Name,
+		Labels:      map[string]string{plugin.ManagedByLabel: plugin.ManagedByValue},
+		Annotations: map[string]string{rancherv1alpha1.ConsumerNamespaceAnnotation: serviceNamespace.Namespace},
+	}}
+	if err := controllerutil.SetOwnerReference(namespace, workspace, r.Scheme); err != nil {
+		return ctrl.Result{}, err
+	}
+	if err := r.Create(ctx, workspace); err != nil && !apierrors.IsAlreadyExists(err) {
+		return ctrl.Result{}, fmt.Errorf("unable to create fleet workspace: %w", err)
+	}
+
+	log.FromContext(ctx).Info("Created fleet workspace", "workspace", workspace.Name)
+	return ctrl.Result{}, nil
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *FleetWorkspaceReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		Named("fleetworkspace").
+		For(&kubebindv1alpha1.APIServiceNamespace{}).
+		Complete(r)
+}
@@ -0,0 +1,69 @@
+/*
+Copyright 2023 SUSE.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	kubebindv1alpha1 "github.com/kube-bind/kube-bind/pkg/apis/kubebind/v1alpha1"
+
+	managementv3 "github.com/Danil-Grigorev/rancher-bind/pkg/apis/rancher/management/v3"
+	rancherv1alpha1 "github.com/Danil-Grigorev/rancher-bind/pkg/apis/rancherbind/v1alpha1"
+)
+
+func TestFleetWorkspace(t *testing.T) {
+	g := NewWithT(t)
+	ctx := context.Background()
+
+	scheme := runtime.NewScheme()
+	g.Expect(corev1.AddToScheme(scheme)).To(Succeed())
+	g.Expect(managementv3.AddToScheme(scheme)).To(Succeed())
+	g.Expect(kubebindv1alpha1.AddToScheme(scheme)).To(Succeed())
+
+	serviceNamespace := &kubebindv1alpha1.APIServiceNamespace{ObjectMeta: metav1.ObjectMeta{Namespace: "kube-bind-a", Name: "default"}}
+	namespace := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "kube-bind-a-default", UID: "namespace-uid"}}
+
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(serviceNamespace, namespace).Build()
+	r := &FleetWorkspaceReconciler{Client: c, Scheme: scheme}
+	req := ctrl.Request{NamespacedName: client.ObjectKeyFromObject(serviceNamespace)}
+
+	// The service namespace is not created yet.
+	_, err := r.Reconcile(ctx, req)
+	g.Expect(err).ToNot(HaveOccurred())
+	workspaces := &managementv3.FleetWorkspaceList{}
+	g.Expect(c.List(ctx, workspaces)).To(Succeed())
+	g.Expect(workspaces.Items).To(BeEmpty())
+
+	serviceNamespace.Status.Namespace = namespace.Name
+	g.Expect(c.Update(ctx, serviceNamespace)).To(Succeed())
+	_, err = r.Reconcile(ctx, req)
+	g.Expect(err).ToNot(HaveOccurred())
+
+	workspace := &managementv3.FleetWorkspace{}
+	g.Expect(c.Get(ctx, client.ObjectKey{Name: namespace.Name}, workspace)).To(Succeed())
+	g.Expect(workspace.Annotations).To(HaveKeyWithValue(rancherv1alpha1.ConsumerNamespaceAnnotation, "kube-bind-a"))
+	g.Expect(workspace.OwnerReferences).To(ConsistOf(HaveField("UID", namespace.UID)))
+}
@@ -0,0 +1,201 @@
+/*
+Copyright 2023 SUSE.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+
+	kubebindv1alpha1 "github.com/kube-bind/kube-bind/pkg/apis/kubebind/v1alpha1"
+	conditionsapi "github.com/kube-bind/kube-bind/pkg/apis/third_party/conditions/apis/conditions/v1alpha1"
+	"github.com/kube-bind/kube-bind/pkg/apis/third_party/conditions/util/conditions"
+	bindinformers "github.com/kube-bind/kube-bind/pkg/client/informers/externalversions/kubebind/v1alpha1"
+
+	managementv3 "github.com/Danil-Grigorev/rancher-bind/pkg/apis/rancher/management/v3"
+	rancherv1alpha1 "github.com/Danil-Grigorev/rancher-bind/pkg/apis/rancherbind/v1alpha1"
+)
+
+const (
+	// ConsumerHeartbeatCondition is set on the ClusterBindings, true while the konnector of the
+	// consumer sends heartbeats.
+	ConsumerHeartbeatCondition conditionsapi.ConditionType = "ConsumerHeartbeat"
+
+	// HeartbeatStaleReason is the ConsumerHeartbeat condition reason of silent consumers.
+	HeartbeatStaleReason = "HeartbeatStale"
+
+	// ConsumerSuspendedReason is the ConsumerHeartbeat condition reason of consumers silent for
+	// so long that their rancher users are disabled.
+	ConsumerSuspendedReason = "ConsumerSuspended"
+
+	// heartbeatRecoveredReason is the reason of the Event reporting heartbeats coming back.
+	heartbeatRecoveredReason = "HeartbeatRecovered"
+)
+
+// HeartbeatReconciler marks the ClusterBindings of consumers whose konnector went silent,
+// and optionally suspends their rancher users.
+type HeartbeatReconciler struct {
+	client.Client
+
+	// ClusterBindings is the shared ClusterBinding informer.
+	ClusterBindings bindinformers.ClusterBindingInformer
+
+	Recorder record.EventRecorder
+
+	// StaleAfter is how long a ClusterBinding can go without a heartbeat before it is marked stale.
+	StaleAfter time.Duration
+
+	// SuspendAfter is how long a ClusterBinding can go without a heartbeat before the rancher
+	// users of the consumer are disabled, zero never suspends.
+	SuspendAfter time.Duration
+}
+
+//+kubebuilder:rbac:groups=kube-bind.io,resources=clusterbindings/status,verbs=get;update;patch
+//+kubebuilder:rbac:groups=management.cattle.io,resources=users,verbs=get;list;watch;patch
+//+kubebuilder:rbac:groups=core,resources=events,verbs=create;patch
+
+// Reconcile reports the konnector heartbeats of a ClusterBinding.
+//
+// Flow:
+// - Compare the last heartbeat, or the creation of a ClusterBinding never reporting one, with the intervals.
+// - Set the ConsumerHeartbeat condition, recording an Event when the condition reason changes.
+// - Disable the rancher users of suspended consumers, and enable them again once the heartbeats are back.
+// - Requeue for the next interval, the heartbeats themselves requeue through the informer.
+func (r *HeartbeatReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	cached, err := r.ClusterBindings.Lister().ClusterBindings(req.Namespace).Get(req.Name)
+	if apierrors.IsNotFound(err) {
+		return ctrl.Result{}, nil
+	} else if err != nil {
+		return ctrl.Result{}, err
+	}
+	if !cached.DeletionTimestamp.IsZero() {
+		return ctrl.Result{}, nil
+	}
+
+	binding := cached.DeepCopy()
+	lastHeartbeat := binding.Status.LastHeartbeatTime
+	if lastHeartbeat.IsZero() {
+		lastHeartbeat = binding.CreationTimestamp
+	}
+	silent := time.Since(lastHeartbeat.Time)
+	suspend := r.SuspendAfter > 0 && silent > r.SuspendAfter
+	previous := conditions.Get(binding, ConsumerHeartbeatCondition)
+
+	var requeueAfter time.Duration
+	switch {
+	case silent <= r.StaleAfter:
+		conditions.MarkTrue(binding, ConsumerHeartbeatCondition)
+		requeueAfter = r.StaleAfter - silent
+	case !suspend:
+		conditions.MarkFalse(binding, ConsumerHeartbeatCondition, HeartbeatStaleReason, conditionsapi.ConditionSeverityWarning,
+			"no konnector heartbeat since %s", lastHeartbeat.UTC().Format(time.RFC3339))
+		if r.SuspendAfter > 0 {
+			requeueAfter = r.SuspendAfter - silent
+		}
+	default:
+		conditions.MarkFalse(binding, ConsumerHeartbeatCondition, ConsumerSuspendedReason, conditionsapi.ConditionSeverityError,
+			"no konnector heartbeat since %s, the rancher users of the consumer are disabled", lastHeartbeat.UTC().Format(time.RFC3339))
+	}
+	conditions.SetSummary(binding)
+
+	// Without suspensions, only the users of a consumer suspended before are enabled again.
+	if r.SuspendAfter > 0 || (previous != nil && previous.Reason == ConsumerSuspendedReason) {
+		if err := r.suspend(ctx, binding.Namespace, suspend); err != nil {
+			return ctrl.Result{}, err
+		}
+	}
+
+	current := conditions.Get(binding, ConsumerHeartbeatCondition)
+	if previous == nil || previous.Reason != current.Reason {
+		r.recordTransition(binding, previous, current)
+	}
+
+	if !equality.Semantic.DeepEqual(cached.Status, binding.Status) {
+		if err := r.Status().Update(ctx, binding); err != nil {
+			return ctrl.Result{}, fmt.Errorf("unable to update ClusterBinding status: %w", err)
+		}
+	}
+
+	return ctrl.Result{RequeueAfter: requeueAfter}, nil
+}
+
+// recordTransition records an Event for a change of the ConsumerHeartbeat condition reason.
+// A binding starting healthy is not reported.
+func (r *HeartbeatReconciler) recordTransition(binding *kubebindv1alpha1.ClusterBinding, previous, current *conditionsapi.Condition) {
+	switch {
+	case current.Status == corev1.ConditionFalse:
+		r.Recorder.Event(binding, corev1.EventTypeWarning, current.Reason, current.Message)
+	case previous != nil:
+		r.Recorder.Event(binding, corev1.EventTypeNormal, heartbeatRecoveredReason, "konnector heartbeats are back")
+	}
+}
+
+// suspend disables the rancher users of the consumer, or enables the ones it disabled. Users
+// disabled by an admin are left alone.
+func (r *HeartbeatReconciler) suspend(ctx context.Context, consumer string, suspend bool) error {
+	logger := log.FromContext(ctx)
+
+	users := &managementv3.UserList{}
+	if err := r.List(ctx, users, client.MatchingFields{userConsumerIndex: consumer}); err != nil {
+		return fmt.Errorf("unable to list users: %w", err)
+	}
+
+	for i := range users.Items {
+		user := &users.Items[i]
+		_, suspended := user.Annotations[rancherv1alpha1.SuspendedAnnotation]
+		enabled := user.Enabled == nil || *user.Enabled
+		original := user.DeepCopy()
+		switch {
+		case suspend && !suspended && enabled:
+			enabled = false
+			user.Annotations[rancherv1alpha1.SuspendedAnnotation] = time.Now().UTC().Format(time.RFC3339)
+		case !suspend && suspended:
+			enabled = true
+			delete(user.Annotations, rancherv1alpha1.SuspendedAnnotation)
+		default:
+			continue
+		}
+		user.Enabled = &enabled
+
+		if err := r.Patch(ctx, user, client.MergeFrom(original)); err != nil {
+			return fmt.Errorf("unable to patch user %s: %w", user.Name, err)
+		}
+
+		logger.Info("Changed rancher user of the consumer", "user", user.Name, "enabled", enabled)
+	}
+
+	return nil
+}
+
+// SetupWithManager sets up the controller with the Manager. The users are listed through the
+// userConsumerIndex registered by the ConsumerGCReconciler.
+func (r *HeartbeatReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		Named("heartbeat").
+		WatchesRawSource(&source.Informer{Informer: r.ClusterBindings.Informer()}, &handler.EnqueueRequestForObject{}).
+		Complete(r)
+}
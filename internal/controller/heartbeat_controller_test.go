@@ -0,0 +1,132 @@
+/*
+Copyright 2023 SUSE.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	toolscache "k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+
+	kubebindv1alpha1 "github.com/kube-bind/kube-bind/pkg/apis/kubebind/v1alpha1"
+	conditionsapi "github.com/kube-bind/kube-bind/pkg/apis/third_party/conditions/apis/conditions/v1alpha1"
+	"github.com/kube-bind/kube-bind/pkg/apis/third_party/conditions/util/conditions"
+	bindlisters "github.com/kube-bind/kube-bind/pkg/client/listers/kubebind/v1alpha1"
+
+	managementv3 "github.com/Danil-Grigorev/rancher-bind/pkg/apis/rancher/management/v3"
+	rancherv1alpha1 "github.com/Danil-Grigorev/rancher-bind/pkg/apis/rancherbind/v1alpha1"
+	"github.com/Danil-Grigorev/rancher-bind/pkg/kubectl/bind-kubeconfig/plugin"
+)
+
+func TestHeartbeat(t *testing.T) {
+	g := NewWithT(t)
+	ctx := context.Background()
+
+	scheme := runtime.NewScheme()
+	g.Expect(managementv3.AddToScheme(scheme)).To(Succeed())
+	g.Expect(kubebindv1alpha1.AddToScheme(scheme)).To(Succeed())
+
+	binding := &kubebindv1alpha1.ClusterBinding{ObjectMeta: metav1.ObjectMeta{Namespace: "kube-bind-a", Name: "cluster"}}
+	binding.Status.LastHeartbeatTime = metav1.NewTime(time.Now().Add(-2 * time.Hour))
+	user := &managementv3.User{ObjectMeta: metav1.ObjectMeta{
+		Name:        "u-rb-0123456789",
+		Labels:      map[string]string{plugin.ManagedByLabel: plugin.ManagedByValue},
+		Annotations: map[string]string{rancherv1alpha1.ConsumerNamespaceAnnotation: "kube-bind-a"},
+	}}
+	other := &managementv3.User{ObjectMeta: metav1.ObjectMeta{
+		Name:        "u-rb-9876543210",
+		Labels:      map[string]string{plugin.ManagedByLabel: plugin.ManagedByValue},
+		Annotations: map[string]string{rancherv1alpha1.ConsumerNamespaceAnnotation: "kube-bind-b"},
+	}}
+
+	userLists := 0
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(binding, user, other).WithStatusSubresource(binding).
+		WithIndex(&managementv3.User{}, userConsumerIndex, userConsumer).
+		WithInterceptorFuncs(interceptor.Funcs{List: func(ctx context.Context, c client.WithWatch, list client.ObjectList, opts ...client.ListOption) error {
+			if _, ok := list.(*managementv3.UserList); ok {
+				userLists++
+			}
+			return c.List(ctx, list, opts...)
+		}}).Build()
+	indexer := toolscache.NewIndexer(toolscache.MetaNamespaceKeyFunc, toolscache.Indexers{})
+	recorder := record.NewFakeRecorder(10)
+	r := &HeartbeatReconciler{
+		Client:          c,
+		ClusterBindings: &sharedInformer[bindlisters.ClusterBindingLister]{lister: bindlisters.NewClusterBindingLister(indexer)},
+		Recorder:        recorder,
+		StaleAfter:      15 * time.Minute,
+		SuspendAfter:    time.Hour,
+	}
+	req := ctrl.Request{NamespacedName: client.ObjectKeyFromObject(binding)}
+
+	reconcile := func() {
+		g.Expect(c.Get(ctx, req.NamespacedName, binding)).To(Succeed())
+		g.Expect(indexer.Update(binding)).To(Succeed())
+		_, err := r.Reconcile(ctx, req)
+		g.Expect(err).ToNot(HaveOccurred())
+		g.Expect(c.Get(ctx, req.NamespacedName, binding)).To(Succeed())
+		g.Expect(c.Get(ctx, client.ObjectKeyFromObject(user), user)).To(Succeed())
+	}
+
+	// Silent for longer than SuspendAfter, the consumer users are disabled.
+	reconcile()
+	g.Expect(conditions.GetReason(binding, ConsumerHeartbeatCondition)).To(Equal(ConsumerSuspendedReason))
+	g.Expect(user.Enabled).To(HaveValue(BeFalse()))
+	g.Expect(user.Annotations).To(HaveKey(rancherv1alpha1.SuspendedAnnotation))
+	g.Expect(conditions.IsFalse(binding, conditionsapi.ReadyCondition)).To(BeTrue())
+	g.Expect(recorder.Events).To(Receive(ContainSubstring("Warning " + ConsumerSuspendedReason)))
+	// The users of other consumers are left alone.
+	g.Expect(c.Get(ctx, client.ObjectKeyFromObject(other), other)).To(Succeed())
+	g.Expect(other.Enabled).To(BeNil())
+
+	// The heartbeats are back.
+	binding.Status.LastHeartbeatTime = metav1.Now()
+	g.Expect(c.Status().Update(ctx, binding)).To(Succeed())
+	reconcile()
+	g.Expect(conditions.IsTrue(binding, ConsumerHeartbeatCondition)).To(BeTrue())
+	g.Expect(conditions.IsTrue(binding, conditionsapi.ReadyCondition)).To(BeTrue())
+	g.Expect(user.Enabled).To(HaveValue(BeTrue()))
+	g.Expect(user.Annotations).ToNot(HaveKey(rancherv1alpha1.SuspendedAnnotation))
+	g.Expect(recorder.Events).To(Receive(ContainSubstring("Normal " + heartbeatRecoveredReason)))
+
+	// Silent for longer than StaleAfter only.
+	binding.Status.LastHeartbeatTime = metav1.NewTime(time.Now().Add(-30 * time.Minute))
+	g.Expect(c.Status().Update(ctx, binding)).To(Succeed())
+	reconcile()
+	g.Expect(conditions.GetReason(binding, ConsumerHeartbeatCondition)).To(Equal(HeartbeatStaleReason))
+	g.Expect(user.Enabled).To(HaveValue(BeTrue()))
+	g.Expect(recorder.Events).To(Receive(ContainSubstring("Warning " + HeartbeatStaleReason)))
+
+	// Without suspensions the users are not listed.
+	r.SuspendAfter = 0
+	binding.Status.LastHeartbeatTime = metav1.NewTime(time.Now().Add(-2 * time.Hour))
+	g.Expect(c.Status().Update(ctx, binding)).To(Succeed())
+	userLists = 0
+	reconcile()
+	g.Expect(conditions.GetReason(binding, ConsumerHeartbeatCondition)).To(Equal(HeartbeatStaleReason))
+	g.Expect(user.Enabled).To(HaveValue(BeTrue()))
+	g.Expect(userLists).To(BeZero())
+}
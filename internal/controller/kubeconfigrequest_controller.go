@@ -0,0 +1,289 @@
+/*
+Copyright 2023 SUSE.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/api/equality"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	managementv3 "github.com/Danil-Grigorev/rancher-bind/pkg/apis/rancher/management/v3"
+	provisioningv1 "github.com/Danil-Grigorev/rancher-bind/pkg/apis/rancher/provisioning/v1"
+	rancherv1alpha1 "github.com/Danil-Grigorev/rancher-bind/pkg/apis/rancherbind/v1alpha1"
+	"github.com/Danil-Grigorev/rancher-bind/pkg/kubectl/bind-kubeconfig/plugin"
+)
+
+// kubeconfigRequestRoleIndex indexes the KubeconfigRequests by the requested role preset.
+const kubeconfigRequestRoleIndex = "spec.role"
+
+// KubeconfigRequestReconciler reconciles a KubeconfigRequest object
+type KubeconfigRequestReconciler struct {
+	client.Client
+	Scheme *runtime.Scheme
+}
+
+//+kubebuilder:rbac:groups=rancher.kube-bind.io,resources=kubeconfigrequests,verbs=get;list;watch;update;patch
+//+kubebuilder:rbac:groups=rancher.kube-bind.io,resources=kubeconfigrequests/status,verbs=get;update;patch
+//+kubebuilder:rbac:groups=rancher.kube-bind.io,resources=kubeconfigrequests/finalizers,verbs=update
+
+// Reconcile issues the kubeconfig of a KubeconfigRequest synced from a consumer cluster.
+//
+// Flow:
+// - Resolve the requested role preset to the GlobalRole offered by the provider.
+// - Check the requested cluster is granted by the preset, revoking the kubeconfig otherwise.
+// - Declare a RancherBind for the request, which issues and renews the kubeconfig Secret.
+// - Report the RancherBind outcome and the kubeconfig Secret name in the request status.
+// kube-bind syncs the status back to the consumer.
+// Deleting the request garbage collects the RancherBind, removing the rancher objects.
+func (r *KubeconfigRequestReconciler) Reconcile(ctx context.Context, req ctrl.Request) (_ ctrl.Result, reterr error) {
+	logger := log.FromContext(ctx)
+
+	request := &rancherv1alpha1.KubeconfigRequest{}
+	if err := r.Get(ctx, req.NamespacedName, request); apierrors.IsNotFound(err) {
+		return ctrl.Result{}, nil
+	} else if err != nil {
+		return ctrl.Result{}, err
+	}
+
+	if !request.DeletionTimestamp.IsZero() {
+		return ctrl.Result{}, nil
+	}
+
+	original := request.DeepCopy()
+	defer func() {
+		if equality.Semantic.DeepEqual(original.Status, request.Status) {
+			return
+		}
+
+		if err := r.Status().Patch(ctx, request, client.MergeFrom(original)); err != nil && reterr == nil {
+			reterr = err
+		}
+	}()
+
+	role, err := r.resolvePreset(ctx, request.Spec.Role)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+	if role == nil {
+		// Requeued by the GlobalRole watch once a role with the preset is offered.
+		fail(request, "PresetNotFound", fmt.Sprintf("role preset %q is not offered by the provider", request.Spec.Role))
+		return ctrl.Result{}, r.revoke(ctx, request)
+	}
+
+	granted, err := r.clusterGranted(ctx, role, request.Spec.Cluster)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+	if !granted {
+		// Requeued by the GlobalRole watch once the preset grants the cluster.
+		fail(request, "ClusterNotGranted", fmt.Sprintf("role preset %q is not offered for cluster %q", request.Spec.Role, request.Spec.Cluster))
+		return ctrl.Result{}, r.revoke(ctx, request)
+	}
+
+	bind := &rancherv1alpha1.RancherBind{ObjectMeta: metav1.ObjectMeta{
+		Namespace: request.Namespace,
+		Name:      request.Name,
+	}}
+	result, err := controllerutil.CreateOrUpdate(ctx, r.Client, bind, func() error {
+		if bind.Labels == nil {
+			bind.Labels = map[string]string{}
+		}
+		bind.Labels[rancherv1alpha1.KubeconfigRequestNameLabel] = request.Name
+		bind.Spec = rancherv1alpha1.RancherBindSpec{
+			// The provider namespace identifies the consumer.
+			Consumer:   request.Namespace,
+			Roles:      []rancherv1alpha1.RoleRef{{Name: role.Name}},
+			Clusters:   []string{request.Spec.Cluster},
+			TokenTTL:   request.Spec.TokenTTL,
+			SecretName: request.GetSecretName(),
+		}
+		return controllerutil.SetControllerReference(request, bind, r.Scheme)
+	})
+	if err != nil {
+		fail(request, "RancherBindFailed", err.Error())
+		return ctrl.Result{}, err
+	}
+	if result != controllerutil.OperationResultNone {
+		logger.Info("Declared RancherBind", "operation", result, "role", role.Name, "cluster", request.Spec.Cluster)
+	}
+
+	mirrorStatus(request, bind)
+
+	return ctrl.Result{}, nil
+}
+
+// resolvePreset returns the GlobalRole offered for the preset, or nil if there is none.
+func (r *KubeconfigRequestReconciler) resolvePreset(ctx context.Context, preset string) (*managementv3.GlobalRole, error) {
+	roles := &managementv3.GlobalRoleList{}
+	if err := r.List(ctx, roles, client.MatchingLabels{rancherv1alpha1.RolePresetLabel: preset}); err != nil {
+		return nil, fmt.Errorf("unable to list role presets: %w", err)
+	}
+
+	switch len(roles.Items) {
+	case 0:
+		return nil, nil
+	case 1:
+		return &roles.Items[0], nil
+	default:
+		return nil, fmt.Errorf("role preset %q is ambiguous, %d GlobalRoles offer it", preset, len(roles.Items))
+	}
+}
+
+// clusterGranted returns true if the preset role grants the cluster, comparing the resolved
+// cluster IDs, so either a cluster ID or a provisioning cluster name can be granted.
+func (r *KubeconfigRequestReconciler) clusterGranted(ctx context.Context, role *managementv3.GlobalRole, cluster string) (bool, error) {
+	clusterID, err := plugin.ResolveClusterID(ctx, r.Client, provisioningv1.DefaultNamespace, cluster)
+	if err != nil {
+		return false, err
+	}
+
+	for _, grant := range strings.Split(role.Annotations[rancherv1alpha1.PresetClustersAnnotation], ",") {
+		grant = strings.TrimSpace(grant)
+		if grant == "*" && clusterID != "local" {
+			return true, nil
+		}
+		if grant == "" || grant == "*" {
+			continue
+		}
+		if grant == cluster || grant == clusterID {
+			return true, nil
+		}
+
+		grantedID, err := plugin.ResolveClusterID(ctx, r.Client, provisioningv1.DefaultNamespace, grant)
+		if err != nil {
+			return false, err
+		}
+		if grantedID == clusterID {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+// revoke deletes the RancherBind of a request no longer granted, logging out its tokens,
+// and drops the kubeconfig Secret from the status.
+func (r *KubeconfigRequestReconciler) revoke(ctx context.Context, request *rancherv1alpha1.KubeconfigRequest) error {
+	request.Status.SecretName = ""
+	request.Status.TokenExpiresAt = nil
+
+	bind := &rancherv1alpha1.RancherBind{ObjectMeta: metav1.ObjectMeta{
+		Namespace: request.Namespace,
+		Name:      request.Name,
+	}}
+	if err := r.Delete(ctx, bind); client.IgnoreNotFound(err) != nil {
+		return fmt.Errorf("unable to revoke kubeconfig: %w", err)
+	}
+
+	return nil
+}
+
+func fail(request *rancherv1alpha1.KubeconfigRequest, reason, message string) {
+	request.Status.Phase = rancherv1alpha1.KubeconfigRequestFailed
+	request.Status.ObservedGeneration = request.Generation
+	meta.SetStatusCondition(&request.Status.Conditions, metav1.Condition{
+		Type:               rancherv1alpha1.ReadyCondition,
+		Status:             metav1.ConditionFalse,
+		Reason:             reason,
+		Message:            message,
+		ObservedGeneration: request.Generation,
+	})
+}
+
+// mirrorStatus reports the outcome of the RancherBind on the request.
+func mirrorStatus(request *rancherv1alpha1.KubeconfigRequest, bind *rancherv1alpha1.RancherBind) {
+	request.Status.ObservedGeneration = request.Generation
+
+	ready := meta.FindStatusCondition(bind.Status.Conditions, rancherv1alpha1.ReadyCondition)
+	if ready == nil || bind.Status.ObservedGeneration != bind.Generation {
+		request.Status.Phase = rancherv1alpha1.KubeconfigRequestPending
+		meta.SetStatusCondition(&request.Status.Conditions, metav1.Condition{
+			Type:               rancherv1alpha1.ReadyCondition,
+			Status:             metav1.ConditionUnknown,
+			Reason:             "Pending",
+			Message:            "kubeconfig is being issued",
+			ObservedGeneration: request.Generation,
+		})
+		return
+	}
+
+	if ready.Status != metav1.ConditionTrue {
+		fail(request, ready.Reason, ready.Message)
+		return
+	}
+
+	request.Status.Phase = rancherv1alpha1.KubeconfigRequestIssued
+	request.Status.SecretName = bind.Status.SecretName
+	request.Status.TokenExpiresAt = bind.Status.TokenExpiresAt
+	if len(bind.Status.Clusters) > 0 {
+		request.Status.ClusterID = bind.Status.Clusters[0]
+	}
+	meta.SetStatusCondition(&request.Status.Conditions, metav1.Condition{
+		Type:               rancherv1alpha1.ReadyCondition,
+		Status:             metav1.ConditionTrue,
+		Reason:             "KubeconfigIssued",
+		ObservedGeneration: request.Generation,
+	})
+}
+
+// requestsForPreset enqueues the KubeconfigRequests of the preset offered by a GlobalRole.
+func (r *KubeconfigRequestReconciler) requestsForPreset(ctx context.Context, obj client.Object) []reconcile.Request {
+	preset, ok := obj.GetLabels()[rancherv1alpha1.RolePresetLabel]
+	if !ok {
+		return nil
+	}
+
+	requests := &rancherv1alpha1.KubeconfigRequestList{}
+	if err := r.List(ctx, requests, client.MatchingFields{kubeconfigRequestRoleIndex: preset}); err != nil {
+		log.FromContext(ctx).Error(err, "Unable to list KubeconfigRequests", "preset", preset)
+		return nil
+	}
+
+	result := []reconcile.Request{}
+	for _, request := range requests.Items {
+		result = append(result, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(&request)})
+	}
+
+	return result
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *KubeconfigRequestReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	if err := mgr.GetFieldIndexer().IndexField(context.Background(), &rancherv1alpha1.KubeconfigRequest{}, kubeconfigRequestRoleIndex, func(obj client.Object) []string {
+		return []string{obj.(*rancherv1alpha1.KubeconfigRequest).Spec.Role}
+	}); err != nil {
+		return err
+	}
+
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&rancherv1alpha1.KubeconfigRequest{}).
+		Owns(&rancherv1alpha1.RancherBind{}).
+		Watches(&managementv3.GlobalRole{}, handler.EnqueueRequestsFromMapFunc(r.requestsForPreset)).
+		Complete(r)
+}
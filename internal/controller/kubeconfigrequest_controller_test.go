@@ -0,0 +1,127 @@
+/*
+Copyright 2023 SUSE.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	managementv3 "github.com/Danil-Grigorev/rancher-bind/pkg/apis/rancher/management/v3"
+	provisioningv1 "github.com/Danil-Grigorev/rancher-bind/pkg/apis/rancher/provisioning/v1"
+	rancherv1alpha1 "github.com/Danil-Grigorev/rancher-bind/pkg/apis/rancherbind/v1alpha1"
+)
+
+func TestKubeconfigRequest(t *testing.T) {
+	g := NewWithT(t)
+	ctx := context.Background()
+
+	scheme := runtime.NewScheme()
+	g.Expect(corev1.AddToScheme(scheme)).To(Succeed())
+	g.Expect(managementv3.AddToScheme(scheme)).To(Succeed())
+	g.Expect(provisioningv1.AddToScheme(scheme)).To(Succeed())
+	g.Expect(rancherv1alpha1.AddToScheme(scheme)).To(Succeed())
+
+	request := &rancherv1alpha1.KubeconfigRequest{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "kube-bind-a-default", Name: "sample"},
+		Spec: rancherv1alpha1.KubeconfigRequestSpec{
+			Cluster:    "c-abcde",
+			Role:       "view",
+			SecretName: "credentials",
+		},
+	}
+	cluster := &managementv3.Cluster{ObjectMeta: metav1.ObjectMeta{Name: "c-abcde"}}
+	role := &managementv3.GlobalRole{ObjectMeta: metav1.ObjectMeta{
+		Name:   "gr-view",
+		Labels: map[string]string{rancherv1alpha1.RolePresetLabel: "view"},
+	}}
+
+	c := fake.NewClientBuilder().WithScheme(scheme).
+		WithObjects(request, cluster).
+		WithStatusSubresource(&rancherv1alpha1.KubeconfigRequest{}, &rancherv1alpha1.RancherBind{}).
+		Build()
+	r := &KubeconfigRequestReconciler{Client: c, Scheme: scheme}
+
+	reconcile := func() {
+		_, err := r.Reconcile(ctx, ctrl.Request{NamespacedName: client.ObjectKeyFromObject(request)})
+		g.Expect(err).ToNot(HaveOccurred())
+		g.Expect(c.Get(ctx, client.ObjectKeyFromObject(request), request)).To(Succeed())
+	}
+	reason := func() string {
+		return meta.FindStatusCondition(request.Status.Conditions, rancherv1alpha1.ReadyCondition).Reason
+	}
+	bind := &rancherv1alpha1.RancherBind{}
+	bindKey := client.ObjectKeyFromObject(request)
+
+	reconcile()
+	g.Expect(request.Status.Phase).To(Equal(rancherv1alpha1.KubeconfigRequestFailed))
+	g.Expect(reason()).To(Equal("PresetNotFound"))
+
+	// Presets grant no cluster by default, and the local cluster only explicitly.
+	g.Expect(c.Create(ctx, role)).To(Succeed())
+	reconcile()
+	g.Expect(reason()).To(Equal("ClusterNotGranted"))
+	g.Expect(apierrors.IsNotFound(c.Get(ctx, bindKey, bind))).To(BeTrue())
+
+	role.Annotations = map[string]string{rancherv1alpha1.PresetClustersAnnotation: "*"}
+	g.Expect(c.Update(ctx, role)).To(Succeed())
+	request.Spec.Cluster = "local"
+	g.Expect(c.Update(ctx, request)).To(Succeed())
+	reconcile()
+	g.Expect(reason()).To(Equal("ClusterNotGranted"))
+
+	role.Annotations[rancherv1alpha1.PresetClustersAnnotation] = "c-other, c-abcde"
+	g.Expect(c.Update(ctx, role)).To(Succeed())
+	request.Spec.Cluster = "c-abcde"
+	g.Expect(c.Update(ctx, request)).To(Succeed())
+	reconcile()
+	g.Expect(request.Status.Phase).To(Equal(rancherv1alpha1.KubeconfigRequestPending))
+	g.Expect(c.Get(ctx, bindKey, bind)).To(Succeed())
+	g.Expect(bind.Spec.Consumer).To(Equal("kube-bind-a-default"))
+	g.Expect(bind.Spec.Roles).To(Equal([]rancherv1alpha1.RoleRef{{Name: "gr-view"}}))
+	g.Expect(bind.Spec.Clusters).To(Equal([]string{"c-abcde"}))
+	g.Expect(bind.Spec.SecretName).To(Equal("kubeconfigrequest-credentials"))
+
+	// The RancherBind issued the kubeconfig.
+	bind.Status.ObservedGeneration = bind.Generation
+	bind.Status.SecretName = bind.Spec.SecretName
+	bind.Status.Clusters = []string{"c-abcde"}
+	setCondition(bind, rancherv1alpha1.ReadyCondition, metav1.ConditionTrue, "Ready", "")
+	g.Expect(c.Status().Update(ctx, bind)).To(Succeed())
+
+	reconcile()
+	g.Expect(request.Status.Phase).To(Equal(rancherv1alpha1.KubeconfigRequestIssued))
+	g.Expect(request.Status.SecretName).To(Equal("kubeconfigrequest-credentials"))
+	g.Expect(request.Status.ClusterID).To(Equal("c-abcde"))
+
+	// Withdrawing the grant revokes the kubeconfig.
+	role.Annotations[rancherv1alpha1.PresetClustersAnnotation] = "c-other"
+	g.Expect(c.Update(ctx, role)).To(Succeed())
+	reconcile()
+	g.Expect(reason()).To(Equal("ClusterNotGranted"))
+	g.Expect(request.Status.SecretName).To(BeEmpty())
+	g.Expect(apierrors.IsNotFound(c.Get(ctx, bindKey, bind))).To(BeTrue())
+}
@@ -0,0 +1,124 @@
+/*
+Copyright 2023 SUSE.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sort"
+
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	"github.com/kube-bind/kube-bind/contrib/example-backend/kubernetes/resources"
+
+	rancherv1alpha1 "github.com/Danil-Grigorev/rancher-bind/pkg/apis/rancherbind/v1alpha1"
+	"github.com/Danil-Grigorev/rancher-bind/pkg/kubectl/bind-kubeconfig/plugin"
+)
+
+// LegacyCatalogReconciler keeps offering the CRDs exported before the export catalogs, labelled
+// kube-bind.io/exported=true or in the exported groups, through the legacy-exported catalog.
+//
+// Deprecated: list the resources in a RancherExportCatalog instead.
+type LegacyCatalogReconciler struct {
+	client.Client
+
+	// ExportedGroups are the API groups set with the deprecated --exported-groups flag.
+	ExportedGroups []string
+}
+
+//+kubebuilder:rbac:groups=rancher.kube-bind.io,resources=rancherexportcatalogs,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups=apiextensions.k8s.io,resources=customresourcedefinitions,verbs=get;list;watch
+
+// Reconcile writes the legacy-exported catalog with the legacy exported CRDs, and deletes it
+// once there are none.
+func (r *LegacyCatalogReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	logger := log.FromContext(ctx)
+
+	crds := &apiextensionsv1.CustomResourceDefinitionList{}
+	if err := r.List(ctx, crds); err != nil {
+		return ctrl.Result{}, fmt.Errorf("unable to list CRDs: %w", err)
+	}
+
+	exported := []rancherv1alpha1.CatalogResource{}
+	for _, crd := range crds.Items {
+		if crd.Labels[resources.ExportedCRDsLabel] != "true" && !slices.Contains(r.ExportedGroups, crd.Spec.Group) {
+			continue
+		}
+		exported = append(exported, rancherv1alpha1.CatalogResource{
+			Group:       crd.Spec.Group,
+			Resource:    crd.Spec.Names.Plural,
+			Description: fmt.Sprintf("%s exported with the deprecated %s label or --exported-groups flag.", crd.Spec.Names.Kind, resources.ExportedCRDsLabel),
+		})
+	}
+	sort.Slice(exported, func(i, j int) bool {
+		if exported[i].Group != exported[j].Group {
+			return exported[i].Group < exported[j].Group
+		}
+		return exported[i].Resource < exported[j].Resource
+	})
+
+	catalog := &rancherv1alpha1.RancherExportCatalog{ObjectMeta: metav1.ObjectMeta{Name: rancherv1alpha1.LegacyCatalogName}}
+	if len(exported) == 0 {
+		if err := r.Delete(ctx, catalog); client.IgnoreNotFound(err) != nil {
+			return ctrl.Result{}, fmt.Errorf("unable to delete legacy export catalog: %w", err)
+		}
+		return ctrl.Result{}, nil
+	}
+
+	result, err := controllerutil.CreateOrUpdate(ctx, r.Client, catalog, func() error {
+		if catalog.Labels == nil {
+			catalog.Labels = map[string]string{}
+		}
+		catalog.Labels[plugin.ManagedByLabel] = plugin.ManagedByValue
+		catalog.Spec = rancherv1alpha1.RancherExportCatalogSpec{Resources: exported}
+		return nil
+	})
+	if err != nil {
+		return ctrl.Result{}, fmt.Errorf("unable to write legacy export catalog: %w", err)
+	}
+	if result != controllerutil.OperationResultNone {
+		logger.Info("Wrote legacy export catalog, list the resources in a RancherExportCatalog instead",
+			"operation", result, "resources", len(exported))
+	}
+
+	return ctrl.Result{}, nil
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *LegacyCatalogReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	legacyCatalog := func(context.Context, client.Object) []reconcile.Request {
+		return []reconcile.Request{{NamespacedName: client.ObjectKey{Name: rancherv1alpha1.LegacyCatalogName}}}
+	}
+
+	return ctrl.NewControllerManagedBy(mgr).
+		Named("legacycatalog").
+		For(&rancherv1alpha1.RancherExportCatalog{}, builder.WithPredicates(predicate.NewPredicateFuncs(func(obj client.Object) bool {
+			return obj.GetName() == rancherv1alpha1.LegacyCatalogName
+		}))).
+		Watches(&apiextensionsv1.CustomResourceDefinition{}, handler.EnqueueRequestsFromMapFunc(legacyCatalog)).
+		Complete(r)
+}
@@ -0,0 +1,104 @@
+/*
+Copyright 2023 SUSE.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	rancherv1alpha1 "github.com/Danil-Grigorev/rancher-bind/pkg/apis/rancherbind/v1alpha1"
+)
+
+func TestLegacyCatalog(t *testing.T) {
+	g := NewWithT(t)
+	ctx := context.Background()
+
+	scheme := runtime.NewScheme()
+	g.Expect(apiextensionsv1.AddToScheme(scheme)).To(Succeed())
+	g.Expect(rancherv1alpha1.AddToScheme(scheme)).To(Succeed())
+
+	crd := func(group, plural string, labels map[string]string) *apiextensionsv1.CustomResourceDefinition {
+		return &apiextensionsv1.CustomResourceDefinition{
+			ObjectMeta: metav1.ObjectMeta{Name: plural + "." + group, Labels: labels},
+			Spec: apiextensionsv1.CustomResourceDefinitionSpec{
+				Group: group,
+				Names: apiextensionsv1.CustomResourceDefinitionNames{Plural: plural, Kind: plural},
+			},
+		}
+	}
+	labelled := crd("fleet.cattle.io", "gitrepos", map[string]string{"kube-bind.io/exported": "true"})
+	c := fake.NewClientBuilder().WithScheme(scheme).
+		WithObjects(
+			labelled,
+			crd("provisioning.cattle.io", "clusters", nil),
+			crd("management.cattle.io", "users", nil),
+			&rancherv1alpha1.RancherExportCatalog{ObjectMeta: metav1.ObjectMeta{Name: rancherv1alpha1.DefaultCatalogName}},
+		).
+		Build()
+	r := &LegacyCatalogReconciler{Client: c, ExportedGroups: []string{"provisioning.cattle.io"}}
+
+	reconcile := func() {
+		_, err := r.Reconcile(ctx, ctrl.Request{NamespacedName: client.ObjectKey{Name: rancherv1alpha1.LegacyCatalogName}})
+		g.Expect(err).ToNot(HaveOccurred())
+	}
+	catalog := &rancherv1alpha1.RancherExportCatalog{}
+	key := client.ObjectKey{Name: rancherv1alpha1.LegacyCatalogName}
+
+	reconcile()
+	g.Expect(c.Get(ctx, key, catalog)).To(Succeed())
+	g.Expect(catalog.Spec.Resources).To(HaveLen(2))
+	g.Expect(catalog.Find("fleet.cattle.io", "gitrepos")).ToNot(BeNil())
+	g.Expect(catalog.Find("provisioning.cattle.io", "clusters")).ToNot(BeNil())
+
+	// The union of the catalogs offers the legacy resources.
+	union, err := LoadCatalog(ctx, c)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(union.Offers("fleet.cattle.io", "gitrepos")).To(BeTrue())
+	g.Expect(union.Offers("management.cattle.io", "users")).To(BeFalse())
+
+	r.ExportedGroups = nil
+	g.Expect(c.Delete(ctx, labelled)).To(Succeed())
+	reconcile()
+	g.Expect(apierrors.IsNotFound(c.Get(ctx, key, catalog))).To(BeTrue())
+}
+
+func TestDefaultCatalogIgnoresLegacyCatalog(t *testing.T) {
+	g := NewWithT(t)
+	ctx := context.Background()
+
+	scheme := runtime.NewScheme()
+	g.Expect(rancherv1alpha1.AddToScheme(scheme)).To(Succeed())
+
+	c := fake.NewClientBuilder().WithScheme(scheme).
+		WithObjects(&rancherv1alpha1.RancherExportCatalog{ObjectMeta: metav1.ObjectMeta{Name: rancherv1alpha1.LegacyCatalogName}}).
+		Build()
+
+	g.Expect((&DefaultCatalog{Client: c}).Start(ctx)).To(Succeed())
+
+	catalog := &rancherv1alpha1.RancherExportCatalog{}
+	g.Expect(c.Get(ctx, client.ObjectKey{Name: rancherv1alpha1.DefaultCatalogName}, catalog)).To(Succeed())
+	g.Expect(catalog.Find(rancherv1alpha1.GroupVersion.Group, "kubeconfigrequests")).ToNot(BeNil())
+}
@@ -0,0 +1,199 @@
+/*
+Copyright 2023 SUSE.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"strings"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"k8s.io/apimachinery/pkg/labels"
+	toolscache "k8s.io/client-go/tools/cache"
+	"k8s.io/component-base/metrics/legacyregistry"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+
+	kubebindv1alpha1 "github.com/kube-bind/kube-bind/pkg/apis/kubebind/v1alpha1"
+	"github.com/kube-bind/kube-bind/pkg/apis/third_party/conditions/util/conditions"
+	bindlisters "github.com/kube-bind/kube-bind/pkg/client/listers/kubebind/v1alpha1"
+)
+
+// The queue depth and the reconcile durations of the kube-bind controllers are reported by
+// the workqueue metrics, e.g. workqueue_depth{name="kube-bind-example-backend-serviceexport"}
+// and workqueue_work_duration_seconds{name="kube-bind-example-backend-serviceexport"}.
+
+var (
+	kubeconfigsIssued = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "rancher_bind_kubeconfigs_issued_total",
+		Help: "Number of kubeconfigs issued with new rancher tokens.",
+	})
+
+	kubeconfigsRevoked = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "rancher_bind_kubeconfigs_revoked_total",
+		Help: "Number of kubeconfigs revoked by removing the rancher user and its tokens.",
+	})
+
+	exportRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "rancher_bind_export_requests_total",
+		Help: "Number of APIServiceExportRequests completed, by phase and ExportsReady reason.",
+	}, []string{"phase", "reason"})
+
+	clusterBindingsDesc = prometheus.NewDesc(
+		"rancher_bind_clusterbindings",
+		"Number of ClusterBindings, one for each consumer cluster.",
+		nil, nil,
+	)
+
+	staleClusterBindingsDesc = prometheus.NewDesc(
+		"rancher_bind_stale_clusterbindings",
+		"Number of ClusterBindings without recent konnector heartbeats, by ConsumerHeartbeat reason.",
+		[]string{"reason"}, nil,
+	)
+
+	apiServiceExportsDesc = prometheus.NewDesc(
+		"rancher_bind_apiserviceexports",
+		"Number of APIServiceExports, by consumer namespace.",
+		[]string{"consumer"}, nil,
+	)
+)
+
+func init() {
+	metrics.Registry.MustRegister(kubeconfigsIssued, kubeconfigsRevoked, exportRequests)
+	metrics.Registry.MustRegister(&legacyWorkqueueCollector{gatherer: legacyregistry.DefaultGatherer})
+}
+
+// legacyWorkqueueCollector copies the workqueue metrics of the component-base registry to the
+// controller-runtime one. The workqueue metrics provider is process wide and the first one
+// set wins; component-base, imported by the kube-bind dependencies, is initialized before
+// controller-runtime, so without it no workqueue metrics are served at all.
+type legacyWorkqueueCollector struct {
+	gatherer prometheus.Gatherer
+}
+
+// Describe sends no descriptors, the collector is unchecked.
+func (c *legacyWorkqueueCollector) Describe(chan<- *prometheus.Desc) {}
+
+func (c *legacyWorkqueueCollector) Collect(ch chan<- prometheus.Metric) {
+	families, err := c.gatherer.Gather()
+	if err != nil {
+		return
+	}
+
+	for _, family := range families {
+		if !strings.HasPrefix(family.GetName(), "workqueue_") {
+			continue
+		}
+		for _, m := range family.GetMetric() {
+			labelNames, labelValues := []string{}, []string{}
+			for _, label := range m.GetLabel() {
+				labelNames = append(labelNames, label.GetName())
+				labelValues = append(labelValues, label.GetValue())
+			}
+			desc := prometheus.NewDesc(family.GetName(), family.GetHelp(), labelNames, nil)
+
+			switch family.GetType() {
+			case dto.MetricType_COUNTER:
+				ch <- prometheus.MustNewConstMetric(desc, prometheus.CounterValue, m.GetCounter().GetValue(), labelValues...)
+			case dto.MetricType_GAUGE:
+				ch <- prometheus.MustNewConstMetric(desc, prometheus.GaugeValue, m.GetGauge().GetValue(), labelValues...)
+			case dto.MetricType_HISTOGRAM:
+				buckets := map[float64]uint64{}
+				for _, bucket := range m.GetHistogram().GetBucket() {
+					buckets[bucket.GetUpperBound()] = bucket.GetCumulativeCount()
+				}
+				ch <- prometheus.MustNewConstHistogram(desc, m.GetHistogram().GetSampleCount(), m.GetHistogram().GetSampleSum(), buckets, labelValues...)
+			}
+		}
+	}
+}
+
+// bindCollector reports the kube-bind objects served by the shared informers.
+type bindCollector struct {
+	clusterBindings bindlisters.ClusterBindingLister
+	exports         bindlisters.APIServiceExportLister
+}
+
+var _ prometheus.Collector = &bindCollector{}
+
+func newBindCollector(informers *Informers) *bindCollector {
+	return &bindCollector{
+		clusterBindings: informers.ClusterBindings.Lister(),
+		exports:         informers.APIServiceExports.Lister(),
+	}
+}
+
+func (c *bindCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- clusterBindingsDesc
+	ch <- staleClusterBindingsDesc
+	ch <- apiServiceExportsDesc
+}
+
+func (c *bindCollector) Collect(ch chan<- prometheus.Metric) {
+	if bindings, err := c.clusterBindings.List(labels.Everything()); err == nil {
+		ch <- prometheus.MustNewConstMetric(clusterBindingsDesc, prometheus.GaugeValue, float64(len(bindings)))
+
+		stale := map[string]int{HeartbeatStaleReason: 0, ConsumerSuspendedReason: 0}
+		for _, binding := range bindings {
+			if conditions.IsFalse(binding, ConsumerHeartbeatCondition) {
+				stale[conditions.GetReason(binding, ConsumerHeartbeatCondition)]++
+			}
+		}
+		for reason, count := range stale {
+			ch <- prometheus.MustNewConstMetric(staleClusterBindingsDesc, prometheus.GaugeValue, float64(count), reason)
+		}
+	}
+
+	exports, err := c.exports.List(labels.Everything())
+	if err != nil {
+		return
+	}
+	perConsumer := map[string]int{}
+	for _, export := range exports {
+		perConsumer[export.Namespace]++
+	}
+	for consumer, count := range perConsumer {
+		ch <- prometheus.MustNewConstMetric(apiServiceExportsDesc, prometheus.GaugeValue, float64(count), consumer)
+	}
+}
+
+// exportRequestOutcomes counts the export requests reaching a terminal phase. Only the
+// elected replica counts, the informers run on all of them.
+func exportRequestOutcomes(elected <-chan struct{}) toolscache.ResourceEventHandler {
+	return toolscache.ResourceEventHandlerFuncs{
+		UpdateFunc: func(oldObj, newObj interface{}) {
+			select {
+			case <-elected:
+			default:
+				return
+			}
+
+			oldRequest, ok := oldObj.(*kubebindv1alpha1.APIServiceExportRequest)
+			if !ok {
+				return
+			}
+			request, ok := newObj.(*kubebindv1alpha1.APIServiceExportRequest)
+			if !ok || request.Status.Phase == oldRequest.Status.Phase {
+				return
+			}
+
+			switch request.Status.Phase {
+			case kubebindv1alpha1.APIServiceExportRequestPhaseSucceeded, kubebindv1alpha1.APIServiceExportRequestPhaseFailed:
+				reason := conditions.GetReason(request, kubebindv1alpha1.APIServiceExportRequestConditionExportsReady)
+				exportRequests.WithLabelValues(string(request.Status.Phase), reason).Inc()
+			}
+		},
+	}
+}
@@ -0,0 +1,128 @@
+/*
+Copyright 2023 SUSE.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"strings"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	toolscache "k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/util/workqueue"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+
+	kubebindv1alpha1 "github.com/kube-bind/kube-bind/pkg/apis/kubebind/v1alpha1"
+	conditionsapi "github.com/kube-bind/kube-bind/pkg/apis/third_party/conditions/apis/conditions/v1alpha1"
+	"github.com/kube-bind/kube-bind/pkg/apis/third_party/conditions/util/conditions"
+	bindlisters "github.com/kube-bind/kube-bind/pkg/client/listers/kubebind/v1alpha1"
+)
+
+func TestBindCollector(t *testing.T) {
+	g := NewWithT(t)
+
+	stale := &kubebindv1alpha1.ClusterBinding{ObjectMeta: metav1.ObjectMeta{Namespace: "kube-bind-b", Name: "cluster"}}
+	conditions.MarkFalse(stale, ConsumerHeartbeatCondition, HeartbeatStaleReason, conditionsapi.ConditionSeverityWarning, "silent")
+
+	bindings := toolscache.NewIndexer(toolscache.MetaNamespaceKeyFunc, toolscache.Indexers{})
+	exports := toolscache.NewIndexer(toolscache.MetaNamespaceKeyFunc, toolscache.Indexers{})
+	for _, obj := range []interface{}{
+		&kubebindv1alpha1.ClusterBinding{ObjectMeta: metav1.ObjectMeta{Namespace: "kube-bind-a", Name: "cluster"}},
+		stale,
+	} {
+		g.Expect(bindings.Add(obj)).To(Succeed())
+	}
+	for _, obj := range []interface{}{
+		&kubebindv1alpha1.APIServiceExport{ObjectMeta: metav1.ObjectMeta{Namespace: "kube-bind-a", Name: "clusters.provisioning.cattle.io"}},
+		&kubebindv1alpha1.APIServiceExport{ObjectMeta: metav1.ObjectMeta{Namespace: "kube-bind-a", Name: "gitrepos.fleet.cattle.io"}},
+		&kubebindv1alpha1.APIServiceExport{ObjectMeta: metav1.ObjectMeta{Namespace: "kube-bind-b", Name: "gitrepos.fleet.cattle.io"}},
+	} {
+		g.Expect(exports.Add(obj)).To(Succeed())
+	}
+
+	collector := &bindCollector{
+		clusterBindings: bindlisters.NewClusterBindingLister(bindings),
+		exports:         bindlisters.NewAPIServiceExportLister(exports),
+	}
+	g.Expect(testutil.CollectAndCompare(collector, strings.NewReader(`
+# HELP rancher_bind_apiserviceexports Number of APIServiceExports, by consumer namespace.
+# TYPE rancher_bind_apiserviceexports gauge
+rancher_bind_apiserviceexports{consumer="kube-bind-a"} 2
+rancher_bind_apiserviceexports{consumer="kube-bind-b"} 1
+# HELP rancher_bind_clusterbindings Number of ClusterBindings, one for each consumer cluster.
+# TYPE rancher_bind_clusterbindings gauge
+rancher_bind_clusterbindings 2
+# HELP rancher_bind_stale_clusterbindings Number of ClusterBindings without recent konnector heartbeats, by ConsumerHeartbeat reason.
+# TYPE rancher_bind_stale_clusterbindings gauge
+rancher_bind_stale_clusterbindings{reason="ConsumerSuspended"} 0
+rancher_bind_stale_clusterbindings{reason="HeartbeatStale"} 1
+`))).To(Succeed())
+}
+
+func TestExportRequestOutcomes(t *testing.T) {
+	g := NewWithT(t)
+
+	pending := &kubebindv1alpha1.APIServiceExportRequest{}
+	pending.Status.Phase = kubebindv1alpha1.APIServiceExportRequestPhasePending
+	failed := pending.DeepCopy()
+	failed.Status.Phase = kubebindv1alpha1.APIServiceExportRequestPhaseFailed
+	conditions.MarkFalse(failed, kubebindv1alpha1.APIServiceExportRequestConditionExportsReady,
+		NotInCatalogReason, conditionsapi.ConditionSeverityError, "not offered")
+
+	counter := exportRequests.WithLabelValues(string(kubebindv1alpha1.APIServiceExportRequestPhaseFailed), NotInCatalogReason)
+	before := testutil.ToFloat64(counter)
+
+	elected := make(chan struct{})
+	handler := exportRequestOutcomes(elected)
+
+	handler.OnUpdate(pending, failed)
+	g.Expect(testutil.ToFloat64(counter)).To(Equal(before), "standby replicas do not count")
+
+	close(elected)
+	handler.OnUpdate(pending, failed)
+	handler.OnUpdate(failed, failed)
+	g.Expect(testutil.ToFloat64(counter)).To(Equal(before + 1))
+}
+
+func TestWorkqueueMetrics(t *testing.T) {
+	g := NewWithT(t)
+
+	// The kube-bind controllers name their queues after the controller.
+	queue := workqueue.NewNamedRateLimitingQueue(workqueue.DefaultControllerRateLimiter(), "kube-bind-example-backend-test")
+	defer queue.ShutDown()
+	queue.Add("key")
+
+	families, err := metrics.Registry.(prometheus.Gatherer).Gather()
+	g.Expect(err).ToNot(HaveOccurred())
+
+	depth := 0.0
+	for _, family := range families {
+		if family.GetName() != "workqueue_depth" {
+			continue
+		}
+		for _, metric := range family.GetMetric() {
+			for _, label := range metric.GetLabel() {
+				if label.GetName() == "name" && label.GetValue() == "kube-bind-example-backend-test" {
+					depth = metric.GetGauge().GetValue()
+				}
+			}
+		}
+	}
+	g.Expect(depth).To(Equal(1.0))
+}
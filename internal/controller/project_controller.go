@@ -0,0 +1,148 @@
+/*
+Copyright 2023 SUSE.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	kubebindv1alpha1 "github.com/kube-bind/kube-bind/pkg/apis/kubebind/v1alpha1"
+
+	configv1alpha1 "github.com/Danil-Grigorev/rancher-bind/pkg/apis/config/v1alpha1"
+	managementv3 "github.com/Danil-Grigorev/rancher-bind/pkg/apis/rancher/management/v3"
+	rancherv1alpha1 "github.com/Danil-Grigorev/rancher-bind/pkg/apis/rancherbind/v1alpha1"
+	"github.com/Danil-Grigorev/rancher-bind/pkg/kubectl/bind-kubeconfig/plugin"
+)
+
+// ProjectReconciler places the service namespaces of the consumers into rancher projects, so
+// the rancher RBAC, quotas and UI apply to the bound resources.
+type ProjectReconciler struct {
+	client.Client
+
+	// Mode is PerConsumer or Shared.
+	Mode configv1alpha1.ProjectMode
+
+	// ProjectID is the project of the Shared mode.
+	ProjectID string
+}
+
+//+kubebuilder:rbac:groups=management.cattle.io,resources=projects,verbs=get;list;watch;create
+
+// Reconcile places the service namespace of an APIServiceNamespace into its project.
+//
+// Flow:
+// - Wait for the servicenamespace controller to create the service namespace.
+// - With the PerConsumer mode create the project of the consumer, named after its namespace.
+// - Set the project ID annotation of the service namespace. Namespaces already in a project
+// are left alone, so admins can move them.
+func (r *ProjectReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	logger := log.FromContext(ctx)
+
+	serviceNamespace := &kubebindv1alpha1.APIServiceNamespace{}
+	if err := r.Get(ctx, req.NamespacedName, serviceNamespace); apierrors.IsNotFound(err) {
+		return ctrl.Result{}, nil
+	} else if err != nil {
+		return ctrl.Result{}, err
+	}
+	if !serviceNamespace.DeletionTimestamp.IsZero() || serviceNamespace.Status.Namespace == "" {
+		return ctrl.Result{}, nil
+	}
+
+	namespace := &corev1.Namespace{}
+	if err := r.Get(ctx, client.ObjectKey{Name: serviceNamespace.Status.Namespace}, namespace); apierrors.IsNotFound(err) {
+		return ctrl.Result{}, nil
+	} else if err != nil {
+		return ctrl.Result{}, err
+	}
+	if current := namespace.Annotations[managementv3.ProjectIDAnnotation]; current != "" {
+		return ctrl.Result{}, nil
+	}
+
+	projectID, err := r.project(ctx, serviceNamespace.Namespace)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+
+	original := namespace.DeepCopy()
+	setAnnotations(namespace, map[string]string{managementv3.ProjectIDAnnotation: projectID})
+	if err := r.Patch(ctx, namespace, client.MergeFrom(original)); err != nil {
+		return ctrl.Result{}, fmt.Errorf("unable to place namespace %s into project %s: %w", namespace.Name, projectID, err)
+	}
+
+	logger.Info("Placed service namespace into project", "namespace", namespace.Name, "project", projectID)
+	return ctrl.Result{}, nil
+}
+
+// project returns the ID of the project for the service namespaces of the consumer, creating
+// it with the PerConsumer mode.
+func (r *ProjectReconciler) project(ctx context.Context, consumer string) (string, error) {
+	if r.Mode == configv1alpha1.ProjectModeShared {
+		return r.ProjectID, nil
+	}
+
+	project := &managementv3.Project{}
+	key := client.ObjectKey{Namespace: managementv3.LocalClusterName, Name: projectName(consumer)}
+	if err := r.Get(ctx, key, project); err == nil {
+		return project.ID(), nil
+	} else if !apierrors.IsNotFound(err) {
+		return "", fmt.Errorf("unable to get project: %w", err)
+	}
+
+	project = &managementv3.Project{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace:   key.Namespace,
+			Name:        key.Name,
+			Labels:      map[string]string{plugin.ManagedByLabel: plugin.ManagedByValue},
+			Annotations: map[string]string{rancherv1alpha1.ConsumerNamespaceAnnotation: consumer},
+		},
+		Spec: managementv3.ProjectSpec{
+			ClusterName: managementv3.LocalClusterName,
+			DisplayName: consumer,
+			Description: fmt.Sprintf("Service namespaces of the kube-bind consumer %s", consumer),
+		},
+	}
+	if err := r.Create(ctx, project); err != nil && !apierrors.IsAlreadyExists(err) {
+		return "", fmt.Errorf("unable to create project: %w", err)
+	}
+
+	log.FromContext(ctx).Info("Created consumer project", "project", project.ID(), "consumer", consumer)
+	return project.ID(), nil
+}
+
+// projectName returns the name of the project of the consumer. Projects are namespaced by
+// cluster, the name only needs to be unique in the local cluster.
+func projectName(consumer string) string {
+	hash := sha256.Sum256([]byte(consumer))
+	return "p-rb-" + hex.EncodeToString(hash[:])[:10]
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *ProjectReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		Named("project").
+		For(&kubebindv1alpha1.APIServiceNamespace{}).
+		Complete(r)
+}
@@ -0,0 +1,83 @@
+/*
+Copyright 2023 SUSE.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	kubebindv1alpha1 "github.com/kube-bind/kube-bind/pkg/apis/kubebind/v1alpha1"
+
+	configv1alpha1 "github.com/Danil-Grigorev/rancher-bind/pkg/apis/config/v1alpha1"
+	managementv3 "github.com/Danil-Grigorev/rancher-bind/pkg/apis/rancher/management/v3"
+)
+
+func TestProject(t *testing.T) {
+	g := NewWithT(t)
+	ctx := context.Background()
+
+	scheme := runtime.NewScheme()
+	g.Expect(corev1.AddToScheme(scheme)).To(Succeed())
+	g.Expect(managementv3.AddToScheme(scheme)).To(Succeed())
+	g.Expect(kubebindv1alpha1.AddToScheme(scheme)).To(Succeed())
+
+	newServiceNamespace := func(name string) *kubebindv1alpha1.APIServiceNamespace {
+		serviceNamespace := &kubebindv1alpha1.APIServiceNamespace{ObjectMeta: metav1.ObjectMeta{Namespace: "kube-bind-a", Name: name}}
+		serviceNamespace.Status.Namespace = "kube-bind-a-" + name
+		return serviceNamespace
+	}
+	moved := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{
+		Name:        "kube-bind-a-moved",
+		Annotations: map[string]string{managementv3.ProjectIDAnnotation: "local:p-admin"},
+	}}
+
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(
+		newServiceNamespace("default"), newServiceNamespace("other"), newServiceNamespace("moved"),
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "kube-bind-a-default"}},
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "kube-bind-a-other"}},
+		moved,
+	).Build()
+	r := &ProjectReconciler{Client: c, Mode: configv1alpha1.ProjectModePerConsumer}
+
+	// The service namespaces of a consumer share its project.
+	for _, name := range []string{"default", "other", "moved"} {
+		_, err := r.Reconcile(ctx, ctrl.Request{NamespacedName: client.ObjectKey{Namespace: "kube-bind-a", Name: name}})
+		g.Expect(err).ToNot(HaveOccurred())
+	}
+
+	project := &managementv3.Project{}
+	g.Expect(c.Get(ctx, client.ObjectKey{Namespace: "local", Name: projectName("kube-bind-a")}, project)).To(Succeed())
+	g.Expect(project.Spec.DisplayName).To(Equal("kube-bind-a"))
+
+	for _, name := range []string{"kube-bind-a-default", "kube-bind-a-other"} {
+		namespace := &corev1.Namespace{}
+		g.Expect(c.Get(ctx, client.ObjectKey{Name: name}, namespace)).To(Succeed())
+		g.Expect(namespace.Annotations).To(HaveKeyWithValue(managementv3.ProjectIDAnnotation, project.ID()))
+	}
+
+	// Namespaces moved by an admin stay in their project.
+	g.Expect(c.Get(ctx, client.ObjectKeyFromObject(moved), moved)).To(Succeed())
+	g.Expect(moved.Annotations).To(HaveKeyWithValue(managementv3.ProjectIDAnnotation, "local:p-admin"))
+}
@@ -0,0 +1,142 @@
+/*
+Copyright 2023 SUSE.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	kubebindv1alpha1 "github.com/kube-bind/kube-bind/pkg/apis/kubebind/v1alpha1"
+
+	managementv3 "github.com/Danil-Grigorev/rancher-bind/pkg/apis/rancher/management/v3"
+	rancherv1alpha1 "github.com/Danil-Grigorev/rancher-bind/pkg/apis/rancherbind/v1alpha1"
+	"github.com/Danil-Grigorev/rancher-bind/pkg/kubectl/bind-kubeconfig/plugin"
+)
+
+// ProjectGCReconciler removes the PerConsumer projects of consumers whose ClusterBinding was
+// deleted.
+type ProjectGCReconciler struct {
+	client.Client
+
+	// GracePeriod is how long the project is kept after the consumer is gone, like the
+	// rancher users of the consumer.
+	GracePeriod time.Duration
+}
+
+//+kubebuilder:rbac:groups=management.cattle.io,resources=projects,verbs=get;list;watch;patch;delete
+
+// Reconcile garbage collects a project created for a consumer.
+//
+// Flow:
+// - Check the ClusterBinding of the consumer the project was created for.
+// - Annotate the project with the time the consumer was found gone, or drop the annotation once it is back.
+// - After the grace period delete the project once no namespace is left in it, as deleting a
+// rancher project deletes its namespaces. Namespaces moved into it by admins keep the project.
+func (r *ProjectGCReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	logger := log.FromContext(ctx)
+
+	project := &managementv3.Project{}
+	if err := r.Get(ctx, req.NamespacedName, project); apierrors.IsNotFound(err) {
+		return ctrl.Result{}, nil
+	} else if err != nil {
+		return ctrl.Result{}, err
+	}
+
+	consumer := project.Annotations[rancherv1alpha1.ConsumerNamespaceAnnotation]
+	if !consumerProject(project) || !project.DeletionTimestamp.IsZero() {
+		return ctrl.Result{}, nil
+	}
+
+	key := client.ObjectKey{Namespace: consumer, Name: clusterBindingName}
+	gone, err := deleted(ctx, r.Client, key, &kubebindv1alpha1.ClusterBinding{})
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+
+	goneSince, err := markConsumerGone(ctx, r.Client, project, gone)
+	if err != nil || goneSince.IsZero() {
+		return ctrl.Result{}, err
+	}
+
+	if remaining := r.GracePeriod - time.Since(goneSince); remaining > 0 {
+		return ctrl.Result{RequeueAfter: remaining}, nil
+	}
+
+	namespaces := &corev1.NamespaceList{}
+	if err := r.List(ctx, namespaces); err != nil {
+		return ctrl.Result{}, fmt.Errorf("unable to list namespaces: %w", err)
+	}
+	for _, namespace := range namespaces.Items {
+		if namespace.Annotations[managementv3.ProjectIDAnnotation] == project.ID() {
+			// Requeued by the namespace watch once it leaves the project.
+			return ctrl.Result{}, nil
+		}
+	}
+
+	if err := r.Delete(ctx, project); client.IgnoreNotFound(err) != nil {
+		return ctrl.Result{}, fmt.Errorf("unable to delete project %s: %w", project.ID(), err)
+	}
+
+	logger.Info("Removed project of a gone consumer", "project", project.ID(), "consumer", consumer)
+	return ctrl.Result{}, nil
+}
+
+// consumerProject returns true if the project was created by the backend for a consumer.
+func consumerProject(obj client.Object) bool {
+	_, ok := obj.GetAnnotations()[rancherv1alpha1.ConsumerNamespaceAnnotation]
+	return ok && obj.GetLabels()[plugin.ManagedByLabel] == plugin.ManagedByValue
+}
+
+// projectForConsumer enqueues the project of the consumer of a ClusterBinding.
+func (r *ProjectGCReconciler) projectForConsumer(_ context.Context, obj client.Object) []reconcile.Request {
+	return []reconcile.Request{{NamespacedName: client.ObjectKey{
+		Namespace: managementv3.LocalClusterName,
+		Name:      projectName(obj.GetNamespace()),
+	}}}
+}
+
+// projectForNamespace enqueues the local project a namespace is placed into.
+func (r *ProjectGCReconciler) projectForNamespace(_ context.Context, obj client.Object) []reconcile.Request {
+	cluster, name, ok := strings.Cut(obj.GetAnnotations()[managementv3.ProjectIDAnnotation], ":")
+	if !ok || cluster != managementv3.LocalClusterName {
+		return nil
+	}
+
+	return []reconcile.Request{{NamespacedName: client.ObjectKey{Namespace: cluster, Name: name}}}
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *ProjectGCReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		Named("projectgc").
+		For(&managementv3.Project{}, builder.WithPredicates(predicate.NewPredicateFuncs(consumerProject))).
+		Watches(&kubebindv1alpha1.ClusterBinding{}, handler.EnqueueRequestsFromMapFunc(r.projectForConsumer)).
+		Watches(&corev1.Namespace{}, handler.EnqueueRequestsFromMapFunc(r.projectForNamespace)).
+		Complete(r)
+}
@@ -0,0 +1,120 @@
+/*
+Copyright 2023 SUSE.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	kubebindv1alpha1 "github.com/kube-bind/kube-bind/pkg/apis/kubebind/v1alpha1"
+
+	managementv3 "github.com/Danil-Grigorev/rancher-bind/pkg/apis/rancher/management/v3"
+	rancherv1alpha1 "github.com/Danil-Grigorev/rancher-bind/pkg/apis/rancherbind/v1alpha1"
+	"github.com/Danil-Grigorev/rancher-bind/pkg/kubectl/bind-kubeconfig/plugin"
+)
+
+func TestProjectGC(t *testing.T) {
+	g := NewWithT(t)
+	ctx := context.Background()
+
+	scheme := runtime.NewScheme()
+	g.Expect(corev1.AddToScheme(scheme)).To(Succeed())
+	g.Expect(managementv3.AddToScheme(scheme)).To(Succeed())
+	g.Expect(kubebindv1alpha1.AddToScheme(scheme)).To(Succeed())
+
+	project := &managementv3.Project{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace:   managementv3.LocalClusterName,
+			Name:        projectName("kube-bind-a"),
+			Labels:      map[string]string{plugin.ManagedByLabel: plugin.ManagedByValue},
+			Annotations: map[string]string{rancherv1alpha1.ConsumerNamespaceAnnotation: "kube-bind-a"},
+		},
+		Spec: managementv3.ProjectSpec{ClusterName: managementv3.LocalClusterName},
+	}
+	namespace := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{
+		Name:        "kube-bind-a-default",
+		Annotations: map[string]string{managementv3.ProjectIDAnnotation: project.ID()},
+	}}
+	clusterBinding := &kubebindv1alpha1.ClusterBinding{ObjectMeta: metav1.ObjectMeta{Namespace: "kube-bind-a", Name: "cluster"}}
+
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(project, namespace, clusterBinding).Build()
+	r := &ProjectGCReconciler{Client: c, GracePeriod: time.Hour}
+	req := ctrl.Request{NamespacedName: client.ObjectKeyFromObject(project)}
+
+	g.Expect(r.projectForConsumer(ctx, clusterBinding)).To(ConsistOf(req))
+	g.Expect(r.projectForNamespace(ctx, namespace)).To(ConsistOf(req))
+
+	// The consumer is there.
+	result, err := r.Reconcile(ctx, req)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(result.RequeueAfter).To(BeZero())
+	g.Expect(c.Get(ctx, req.NamespacedName, project)).To(Succeed())
+	g.Expect(project.Annotations).ToNot(HaveKey(rancherv1alpha1.ConsumerGoneAnnotation))
+
+	// The ClusterBinding is gone, the project is kept for the grace period.
+	g.Expect(c.Delete(ctx, clusterBinding)).To(Succeed())
+	result, err = r.Reconcile(ctx, req)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(result.RequeueAfter).To(BeNumerically("~", time.Hour, time.Minute))
+	g.Expect(c.Get(ctx, req.NamespacedName, project)).To(Succeed())
+	g.Expect(project.Annotations).To(HaveKey(rancherv1alpha1.ConsumerGoneAnnotation))
+
+	// Past the grace period a namespace left in the project keeps it.
+	project.Annotations[rancherv1alpha1.ConsumerGoneAnnotation] = time.Now().Add(-2 * time.Hour).UTC().Format(time.RFC3339)
+	g.Expect(c.Update(ctx, project)).To(Succeed())
+	_, err = r.Reconcile(ctx, req)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(c.Get(ctx, req.NamespacedName, project)).To(Succeed())
+
+	// The empty project is removed.
+	g.Expect(c.Delete(ctx, namespace)).To(Succeed())
+	_, err = r.Reconcile(ctx, req)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(apierrors.IsNotFound(c.Get(ctx, req.NamespacedName, project))).To(BeTrue())
+}
+
+func TestProjectGCIgnoresSharedProjects(t *testing.T) {
+	g := NewWithT(t)
+	ctx := context.Background()
+
+	scheme := runtime.NewScheme()
+	g.Expect(corev1.AddToScheme(scheme)).To(Succeed())
+	g.Expect(managementv3.AddToScheme(scheme)).To(Succeed())
+	g.Expect(kubebindv1alpha1.AddToScheme(scheme)).To(Succeed())
+
+	project := &managementv3.Project{
+		ObjectMeta: metav1.ObjectMeta{Namespace: managementv3.LocalClusterName, Name: "p-shared"},
+		Spec:       managementv3.ProjectSpec{ClusterName: managementv3.LocalClusterName},
+	}
+
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(project).Build()
+	r := &ProjectGCReconciler{Client: c}
+
+	_, err := r.Reconcile(ctx, ctrl.Request{NamespacedName: client.ObjectKeyFromObject(project)})
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(c.Get(ctx, client.ObjectKeyFromObject(project), project)).To(Succeed())
+}
@@ -0,0 +1,524 @@
+/*
+Copyright 2023 SUSE.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	clientcmdapiv1 "k8s.io/client-go/tools/clientcmd/api/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/yaml"
+
+	kubebindv1alpha1 "github.com/kube-bind/kube-bind/pkg/apis/kubebind/v1alpha1"
+
+	apis "github.com/Danil-Grigorev/rancher-bind/pkg/apis"
+	managementv3 "github.com/Danil-Grigorev/rancher-bind/pkg/apis/rancher/management/v3"
+	provisioningv1 "github.com/Danil-Grigorev/rancher-bind/pkg/apis/rancher/provisioning/v1"
+	rancherv1alpha1 "github.com/Danil-Grigorev/rancher-bind/pkg/apis/rancherbind/v1alpha1"
+	"github.com/Danil-Grigorev/rancher-bind/pkg/kubectl/bind-kubeconfig/plugin"
+)
+
+const (
+	// tokenRenewalRatio is the part of the token lifetime after which the tokens are renewed.
+	tokenRenewalRatio = 0.8
+	// tokenRenewalMargin is how long before the expiration tokens of unknown lifetime are renewed.
+	tokenRenewalMargin = 10 * time.Minute
+)
+
+// userName returns the rancher user name for the RancherBind. Rancher objects are cluster
+// scoped, so the name is derived from the namespaced name of the RancherBind.
+func userName(bind *rancherv1alpha1.RancherBind) string {
+	hash := sha256.Sum256([]byte(bind.Namespace + "/" + bind.Name))
+	return "u-rb-" + hex.EncodeToString(hash[:])[:10]
+}
+
+// ownerLabels link the rancher objects to the RancherBind, as owner references can not
+// point from cluster scoped objects to namespaced ones.
+func ownerLabels(bind *rancherv1alpha1.RancherBind) map[string]string {
+	return map[string]string{
+		plugin.ManagedByLabel:                     plugin.ManagedByValue,
+		rancherv1alpha1.RancherBindNameLabel:      bind.Name,
+		rancherv1alpha1.RancherBindNamespaceLabel: bind.Namespace,
+	}
+}
+
+func setCondition(bind *rancherv1alpha1.RancherBind, conditionType string, status metav1.ConditionStatus, reason, message string) {
+	meta.SetStatusCondition(&bind.Status.Conditions, metav1.Condition{
+		Type:               conditionType,
+		Status:             status,
+		Reason:             reason,
+		Message:            message,
+		ObservedGeneration: bind.Generation,
+	})
+}
+
+// summarize sets the Ready condition from the other conditions.
+func summarize(bind *rancherv1alpha1.RancherBind) {
+	for _, conditionType := range []string{
+		rancherv1alpha1.UserReadyCondition,
+		rancherv1alpha1.RolesBoundCondition,
+		rancherv1alpha1.KubeconfigReadyCondition,
+	} {
+		condition := meta.FindStatusCondition(bind.Status.Conditions, conditionType)
+		if condition == nil {
+			setCondition(bind, rancherv1alpha1.ReadyCondition, metav1.ConditionUnknown, "Pending", conditionType+" is not reported yet")
+			return
+		}
+
+		if condition.Status != metav1.ConditionTrue {
+			setCondition(bind, rancherv1alpha1.ReadyCondition, metav1.ConditionFalse, condition.Reason, condition.Message)
+			return
+		}
+	}
+
+	setCondition(bind, rancherv1alpha1.ReadyCondition, metav1.ConditionTrue, "Ready", "")
+}
+
+// consumerAnnotations link the rancher objects of a RancherBind synced from a consumer to the
+// consumer, so they are garbage collected once the consumer is gone. RancherBinds outside of
+// the kube-bind service namespaces get none.
+func consumerAnnotations(ctx context.Context, c client.Reader, bind *rancherv1alpha1.RancherBind) (map[string]string, error) {
+	namespace := &corev1.Namespace{}
+	if err := c.Get(ctx, client.ObjectKey{Name: bind.Namespace}, namespace); err != nil {
+		return nil, fmt.Errorf("unable to get namespace: %w", err)
+	}
+
+	serviceNamespace := namespace.Annotations[kubebindv1alpha1.APIServiceNamespaceAnnotationKey]
+	consumer, _, ok := strings.Cut(serviceNamespace, "/")
+	if !ok {
+		return nil, nil
+	}
+
+	return map[string]string{
+		rancherv1alpha1.ConsumerNamespaceAnnotation:   consumer,
+		rancherv1alpha1.APIServiceNamespaceAnnotation: serviceNamespace,
+	}, nil
+}
+
+// setAnnotations adds the annotations to the object, keeping the others.
+func setAnnotations(obj metav1.Object, annotations map[string]string) {
+	if len(annotations) == 0 {
+		return
+	}
+
+	merged := obj.GetAnnotations()
+	if merged == nil {
+		merged = map[string]string{}
+	}
+	for key, value := range annotations {
+		merged[key] = value
+	}
+	obj.SetAnnotations(merged)
+}
+
+func (r *RancherBindReconciler) ensureUser(ctx context.Context, bind *rancherv1alpha1.RancherBind, annotations map[string]string) (*managementv3.User, error) {
+	user := &managementv3.User{}
+	err := r.Get(ctx, client.ObjectKey{Name: userName(bind)}, user)
+	if err == nil {
+		// Users created before the consumer annotations were introduced.
+		original := user.DeepCopy()
+		setAnnotations(user, annotations)
+		if equality.Semantic.DeepEqual(original.Annotations, user.Annotations) {
+			return user, nil
+		}
+		if err := r.Patch(ctx, user, client.MergeFrom(original)); err != nil {
+			return nil, fmt.Errorf("unable to annotate user: %w", err)
+		}
+		return user, nil
+	} else if !apierrors.IsNotFound(err) {
+		return nil, fmt.Errorf("unable to get user: %w", err)
+	}
+
+	// The password is reset for every token issue, the initial one is never used.
+	_, hash, err := plugin.GenerateRandomPassword()
+	if err != nil {
+		return nil, err
+	}
+
+	user = plugin.NewUser(hash, bind.Spec.Consumer, fmt.Sprintf("Consumer %s of %s/%s", bind.Spec.Consumer, bind.Namespace, bind.Name))
+	user.Name = userName(bind)
+	user.Username = user.Name
+	user.Labels = ownerLabels(bind)
+	setAnnotations(user, annotations)
+
+	if err := r.Create(ctx, user); err != nil {
+		return nil, fmt.Errorf("unable to create user: %w", err)
+	}
+
+	return user, nil
+}
+
+// ensureBindings binds the roles to the user, and removes the roles and bindings no longer
+// present in the spec.
+func (r *RancherBindReconciler) ensureBindings(ctx context.Context, bind *rancherv1alpha1.RancherBind, user *managementv3.User, annotations map[string]string) error {
+	roles := []string{}
+	bindings := []string{}
+
+	for i, ref := range bind.Spec.Roles {
+		roleName := ref.Name
+		if len(ref.Rules) > 0 {
+			role := &managementv3.GlobalRole{ObjectMeta: metav1.ObjectMeta{
+				Name: fmt.Sprintf("%s-%d", user.Name, i),
+			}}
+			if _, err := controllerutil.CreateOrUpdate(ctx, r.Client, role, func() error {
+				role.Labels = ownerLabels(bind)
+				setAnnotations(role, annotations)
+				role.DisplayName = fmt.Sprintf("%s role %d", bind.Spec.Consumer, i)
+				role.Rules = ref.Rules
+				return nil
+			}); err != nil {
+				return fmt.Errorf("unable to apply role %s: %w", role.Name, err)
+			}
+
+			roleName = role.Name
+			roles = append(roles, role.Name)
+		}
+
+		if roleName == "" {
+			return fmt.Errorf("role %d has neither a name nor rules", i)
+		}
+
+		binding := &managementv3.GlobalRoleBinding{ObjectMeta: metav1.ObjectMeta{
+			Name: fmt.Sprintf("%s-%s", user.Name, roleName),
+		}}
+		if _, err := controllerutil.CreateOrUpdate(ctx, r.Client, binding, func() error {
+			binding.Labels = ownerLabels(bind)
+			setAnnotations(binding, annotations)
+			binding.GlobalRoleName = roleName
+			binding.UserName = user.Name
+			return nil
+		}); err != nil {
+			return fmt.Errorf("unable to apply role binding %s: %w", binding.Name, err)
+		}
+
+		bindings = append(bindings, binding.Name)
+	}
+
+	return prune(ctx, r.Client, bind, roles, bindings)
+}
+
+// prune removes the roles and bindings of the RancherBind which are not listed.
+func prune(ctx context.Context, c client.Client, bind *rancherv1alpha1.RancherBind, roles, bindings []string) error {
+	owned := client.MatchingLabels{
+		rancherv1alpha1.RancherBindNameLabel:      bind.Name,
+		rancherv1alpha1.RancherBindNamespaceLabel: bind.Namespace,
+	}
+
+	bindingList := &managementv3.GlobalRoleBindingList{}
+	if err := c.List(ctx, bindingList, owned); err != nil {
+		return fmt.Errorf("unable to list role bindings: %w", err)
+	}
+
+	for i := range bindingList.Items {
+		if slices.Contains(bindings, bindingList.Items[i].Name) {
+			continue
+		}
+
+		if err := c.Delete(ctx, &bindingList.Items[i]); client.IgnoreNotFound(err) != nil {
+			return fmt.Errorf("unable to remove role binding %s: %w", bindingList.Items[i].Name, err)
+		}
+	}
+
+	roleList := &managementv3.GlobalRoleList{}
+	if err := c.List(ctx, roleList, owned); err != nil {
+		return fmt.Errorf("unable to list roles: %w", err)
+	}
+
+	for i := range roleList.Items {
+		if slices.Contains(roles, roleList.Items[i].Name) {
+			continue
+		}
+
+		if err := c.Delete(ctx, &roleList.Items[i]); client.IgnoreNotFound(err) != nil {
+			return fmt.Errorf("unable to remove role %s: %w", roleList.Items[i].Name, err)
+		}
+	}
+
+	return nil
+}
+
+// ensureKubeconfig writes the kubeconfig Secret, issuing new tokens when the Secret is
+// missing, the spec changed or the tokens are about to expire. It returns the duration
+// after which the tokens should be renewed, zero for tokens without expiration.
+func (r *RancherBindReconciler) ensureKubeconfig(ctx context.Context, bind *rancherv1alpha1.RancherBind, user *managementv3.User) (time.Duration, error) {
+	secret := &corev1.Secret{}
+	err := r.Get(ctx, client.ObjectKey{Namespace: bind.Namespace, Name: bind.GetSecretName()}, secret)
+	if err != nil && !apierrors.IsNotFound(err) {
+		return 0, fmt.Errorf("unable to get kubeconfig secret: %w", err)
+	}
+	if err == nil && !metav1.IsControlledBy(secret, bind) {
+		return 0, fmt.Errorf("secret %s exists and is not owned by the RancherBind", secret.Name)
+	}
+
+	renewAfter := tokenRenewal(bind)
+	if err == nil && bind.Status.ObservedGeneration == bind.Generation && bind.Status.SecretName == secret.Name && renewAfter >= 0 {
+		return renewAfter, nil
+	}
+
+	clusters := []string{}
+	for _, name := range bind.GetClusters() {
+		clusterID, err := plugin.ResolveClusterID(ctx, r.Client, provisioningv1.DefaultNamespace, name)
+		if err != nil {
+			return 0, err
+		}
+		clusters = append(clusters, clusterID)
+	}
+
+	config, expiresAt, tokens, err := r.issueKubeconfig(ctx, bind, user, clusters)
+	if err != nil {
+		return 0, err
+	}
+
+	data, err := yaml.Marshal(config)
+	if err != nil {
+		return 0, fmt.Errorf("unable to encode kubeconfig: %w", err)
+	}
+
+	secret = &corev1.Secret{ObjectMeta: metav1.ObjectMeta{
+		Namespace: bind.Namespace,
+		Name:      bind.GetSecretName(),
+	}}
+	if _, err := controllerutil.CreateOrUpdate(ctx, r.Client, secret, func() error {
+		secret.Labels = ownerLabels(bind)
+		if request, ok := bind.Labels[rancherv1alpha1.KubeconfigRequestNameLabel]; ok {
+			// Selects the Secret for the consumer of the KubeconfigRequest.
+			secret.Labels[rancherv1alpha1.KubeconfigRequestNameLabel] = request
+		}
+		secret.Data = map[string][]byte{
+			rancherv1alpha1.KubeconfigSecretKey: data,
+		}
+		return controllerutil.SetControllerReference(bind, secret, r.Scheme)
+	}); err != nil {
+		// The new tokens never reached the Secret.
+		if err := revoke(ctx, r.Client, tokens...); err != nil {
+			log.FromContext(ctx).Error(err, "Unable to revoke unused tokens")
+		}
+		return 0, fmt.Errorf("unable to write kubeconfig secret: %w", err)
+	}
+	kubeconfigsIssued.Inc()
+
+	replaced := bind.Status.TokenNames
+	bind.Status.SecretName = secret.Name
+	bind.Status.Clusters = clusters
+	bind.Status.TokenExpiresAt = expiresAt
+	bind.Status.TokenNames = tokens
+
+	// The Secret holds the new tokens, the previous ones are logged out.
+	for _, name := range replaced {
+		if slices.Contains(tokens, name) {
+			continue
+		}
+		if err := revoke(ctx, r.Client, name); err != nil {
+			log.FromContext(ctx).Error(err, "Unable to revoke replaced token, retrying with the next renewal", "token", name)
+			bind.Status.TokenNames = append(bind.Status.TokenNames, name)
+		}
+	}
+
+	return tokenRenewal(bind), nil
+}
+
+// tokenRenewal returns the duration until the tokens should be renewed, negative once due.
+func tokenRenewal(bind *rancherv1alpha1.RancherBind) time.Duration {
+	if bind.Status.TokenExpiresAt == nil {
+		return 0
+	}
+
+	// Without a TTL in the spec the token lifetime is unknown, renew shortly before the expiration.
+	margin := tokenRenewalMargin
+	if bind.Spec.TokenTTL != nil {
+		margin = time.Duration(float64(bind.Spec.TokenTTL.Duration) * (1 - tokenRenewalRatio))
+	}
+
+	if until := time.Until(bind.Status.TokenExpiresAt.Add(-margin)); until > 0 {
+		return until
+	}
+
+	return -1
+}
+
+// issueKubeconfig logs in as the user with a fresh password, and collects a kubeconfig
+// with cluster scoped tokens for each of the clusters, returning the names of the tokens.
+// The login token is logged out once the cluster tokens exist, and the cluster tokens
+// are revoked if the kubeconfig can not be completed.
+func (r *RancherBindReconciler) issueKubeconfig(ctx context.Context, bind *rancherv1alpha1.RancherBind, user *managementv3.User, clusters []string) (_ *clientcmdapiv1.Config, _ *metav1.Time, tokens []string, reterr error) {
+	serverUrl, err := plugin.GetServer(ctx, r.Client)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("unable to get rancher server url: %w", err)
+	}
+
+	password, hash, err := plugin.GenerateRandomPassword()
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	user.Password = hash
+	if err := r.Update(ctx, user); err != nil {
+		return nil, nil, nil, fmt.Errorf("unable to reset user password: %w", err)
+	}
+
+	// The user needs to see the clusters to generate their kubeconfig.
+	role, err := plugin.CreateClusterRole(ctx, r.Client, user)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	defer r.Delete(ctx, role) // nolint: errcheck
+
+	binding, err := plugin.CreateRoleBinding(ctx, r.Client, user)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	defer r.Delete(ctx, binding) // nolint: errcheck
+
+	login, err := plugin.AuthenticateUser(serverUrl, &apis.Login{
+		Username: user.Username,
+		Password: password,
+	})
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	defer func() {
+		if err := revoke(ctx, r.Client, tokenName(login.Token)); err != nil {
+			log.FromContext(ctx).Error(err, "Unable to log out the login token")
+		}
+		if reterr == nil {
+			return
+		}
+		if err := revoke(ctx, r.Client, tokens...); err != nil {
+			log.FromContext(ctx).Error(err, "Unable to revoke unused tokens")
+		}
+	}()
+
+	var ttl time.Duration
+	if bind.Spec.TokenTTL != nil {
+		ttl = bind.Spec.TokenTTL.Duration
+	}
+
+	config := &clientcmdapiv1.Config{}
+	var expiresAt *metav1.Time
+	for _, clusterID := range clusters {
+		response, err := plugin.CollectClusterKubeconfig(serverUrl, clusterID, login.Token)
+		if err != nil {
+			return nil, nil, tokens, err
+		}
+
+		cfg := &clientcmdapiv1.Config{}
+		if err := yaml.Unmarshal([]byte(response.Config), cfg); err != nil {
+			return nil, nil, tokens, fmt.Errorf("unable to decode kubeconfig for cluster %s: %w", clusterID, err)
+		}
+
+		token, err := plugin.CreateTokenWithTTL(serverUrl, login.Token, clusterID, fmt.Sprintf("rancher-bind %s/%s", bind.Namespace, bind.Name), ttl)
+		if err != nil {
+			return nil, nil, tokens, err
+		}
+		tokens = append(tokens, tokenName(token.Token))
+
+		if t, err := time.Parse(time.RFC3339, token.ExpiresAt); err == nil && (expiresAt == nil || t.Before(expiresAt.Time)) {
+			expiresAt = &metav1.Time{Time: t}
+		}
+
+		for i := range cfg.AuthInfos {
+			cfg.AuthInfos[i].AuthInfo = clientcmdapiv1.AuthInfo{
+				Token: token.Token,
+			}
+		}
+
+		if config.CurrentContext == "" {
+			config.APIVersion = cfg.APIVersion
+			config.Kind = cfg.Kind
+			config.CurrentContext = cfg.CurrentContext
+		}
+		config.Clusters = append(config.Clusters, cfg.Clusters...)
+		config.AuthInfos = append(config.AuthInfos, cfg.AuthInfos...)
+		config.Contexts = append(config.Contexts, cfg.Contexts...)
+	}
+
+	return config, expiresAt, tokens, nil
+}
+
+// tokenName returns the name of the rancher Token of an API token, which has the
+// <name>:<secret> form.
+func tokenName(token string) string {
+	name, _, _ := strings.Cut(token, ":")
+	return name
+}
+
+// revoke deletes the rancher Tokens, logging them out.
+func revoke(ctx context.Context, c client.Client, names ...string) error {
+	for _, name := range names {
+		token := &managementv3.Token{ObjectMeta: metav1.ObjectMeta{Name: name}}
+		if err := c.Delete(ctx, token); client.IgnoreNotFound(err) != nil {
+			return fmt.Errorf("unable to revoke token %s: %w", name, err)
+		}
+	}
+
+	return nil
+}
+
+// logout deletes the tokens of the user, returning how many were deleted. The tokens are listed
+// with the uncached reader, so no cluster wide Token informer is started.
+func logout(ctx context.Context, c client.Client, reader client.Reader, user string) (int, error) {
+	tokens := &managementv3.TokenList{}
+	if err := reader.List(ctx, tokens, client.MatchingLabels{managementv3.TokenUserIDLabel: user}); err != nil {
+		return 0, fmt.Errorf("unable to list tokens: %w", err)
+	}
+
+	for i := range tokens.Items {
+		if err := c.Delete(ctx, &tokens.Items[i]); client.IgnoreNotFound(err) != nil {
+			return 0, fmt.Errorf("unable to remove token %s: %w", tokens.Items[i].Name, err)
+		}
+	}
+
+	return len(tokens.Items), nil
+}
+
+// cleanup removes the rancher objects created for the RancherBind. The tokens are logged out
+// first, so the kubeconfig stops working before rancher removes the user.
+func cleanup(ctx context.Context, c client.Client, reader client.Reader, bind *rancherv1alpha1.RancherBind) error {
+	if err := prune(ctx, c, bind, nil, nil); err != nil {
+		return err
+	}
+
+	tokens, err := logout(ctx, c, reader, userName(bind))
+	if err != nil {
+		return err
+	}
+
+	user := &managementv3.User{ObjectMeta: metav1.ObjectMeta{Name: userName(bind)}}
+	if err := c.Delete(ctx, user); apierrors.IsNotFound(err) {
+		return nil
+	} else if err != nil {
+		return fmt.Errorf("unable to remove user: %w", err)
+	}
+	if bind.Status.SecretName != "" || tokens > 0 {
+		kubeconfigsRevoked.Inc()
+	}
+
+	return nil
+}
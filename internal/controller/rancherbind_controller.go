@@ -20,6 +20,12 @@ import (
 	"context"
 	"fmt"
 
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+
 	"github.com/kube-bind/kube-bind/contrib/example-backend/controllers/serviceexport"
 	"github.com/kube-bind/kube-bind/contrib/example-backend/controllers/serviceexportrequest"
 	"github.com/kube-bind/kube-bind/contrib/example-backend/controllers/servicenamespace"
@@ -27,57 +33,25 @@ import (
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
 
-	kubebindv1alpha1 "github.com/kube-bind/kube-bind/pkg/apis/kubebind/v1alpha1"
+	configv1alpha1 "github.com/Danil-Grigorev/rancher-bind/pkg/apis/config/v1alpha1"
+	rancherv1alpha1 "github.com/Danil-Grigorev/rancher-bind/pkg/apis/rancherbind/v1alpha1"
 )
 
-type ThreadedRunable interface {
-	// Start starts running the component.  The component will stop running
-	// when the context is closed. Start blocks until the context is closed or
-	// an error occurs.
-	Start(context.Context, int)
-}
-
-type Threaded struct {
-	threads  int
-	runnable ThreadedRunable
-}
-
-func NewThreaded(runnable ThreadedRunable, threads int) *Threaded {
-	return &Threaded{
-		threads:  threads,
-		runnable: runnable,
-	}
-}
-
-func (t *Threaded) Start(ctx context.Context) error {
-	t.runnable.Start(ctx, t.threads)
-	return nil
-}
-
-type InformerRunnable interface {
-	Start(stopCh <-chan struct{})
-}
-
-type Informer struct {
-	informer InformerRunnable
-}
-
-func NewInformer(informer InformerRunnable) *Informer {
-	return &Informer{
-		informer: informer,
-	}
-}
-
-func (i *Informer) Start(ctx context.Context) error {
-	i.informer.Start(ctx.Done())
-	return nil
-}
-
 // RancherBindReconciler reconciles a RancherBind object
 type RancherBindReconciler struct {
 	client.Client
 	Scheme *runtime.Scheme
+
+	// APIReader lists the rancher Tokens without caching them, the manager API reader is used when unset.
+	APIReader client.Reader
+
+	// Config holds the shared rest config and informers, it is created on setup when unset.
+	Config *Config
+
+	// Backend configures the kube-bind controllers, the defaults are used when unset.
+	Backend *configv1alpha1.BackendConfiguration
 }
 
 //+kubebuilder:rbac:groups=kube-bind.io,resources=apiservicebindings,verbs=get;list;watch;create;update;patch;delete
@@ -98,6 +72,7 @@ type RancherBindReconciler struct {
 //+kubebuilder:rbac:groups=kube-bind.io,resources=clusterbindings,verbs=get;list;watch;create;update;patch;delete
 //+kubebuilder:rbac:groups=kube-bind.io,resources=clusterbindings/status,verbs=get;update;patch
 //+kubebuilder:rbac:groups=kube-bind.io,resources=clusterbindings/finalizers,verbs=update
+//+kubebuilder:rbac:groups=management.cattle.io,resources=clusters;globalrolebindings;globalroles;settings;users,verbs=get;list;watch
 //+kubebuilder:rbac:groups=apiextensions.k8s.io,resources=customresourcedefinitions,verbs=get;list;watch
 //+kubebuilder:rbac:groups=core,resources=namespaces,verbs=get;list;watch;create;update;patch;delete
 //+kubebuilder:rbac:groups=rbac.authorization.k8s.io,resources=rolebindings,verbs=get;list;watch;create;update;patch;delete
@@ -105,47 +80,134 @@ type RancherBindReconciler struct {
 //+kubebuilder:rbac:groups=rbac.authorization.k8s.io,resources=clusterroles,verbs=get;list;watch;create;update;patch;delete
 //+kubebuilder:rbac:groups=rbac.authorization.k8s.io,resources=roles,verbs=get;list;watch;create;update;patch;delete
 
+//+kubebuilder:rbac:groups=rancher.kube-bind.io,resources=rancherbinds,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups=rancher.kube-bind.io,resources=rancherbinds/status,verbs=get;update;patch
+//+kubebuilder:rbac:groups=rancher.kube-bind.io,resources=rancherbinds/finalizers,verbs=update
+//+kubebuilder:rbac:groups=core,resources=secrets,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups=management.cattle.io,resources=users;globalroles;globalrolebindings,verbs=create;update;patch;delete
+//+kubebuilder:rbac:groups=management.cattle.io,resources=tokens,verbs=get;list;watch;delete
+//+kubebuilder:rbac:groups=provisioning.cattle.io,resources=clusters,verbs=get;list;watch
+
 // Reconcile is part of the main kubernetes reconciliation loop which aims to
 // move the current state of the cluster closer to the desired state.
-// TODO(user): Modify the Reconcile function to compare the state specified by
-// the RancherBind object against the actual cluster state, and then
-// perform operations to make the cluster state reflect the state specified by
-// the user.
 //
-// For more details, check Reconcile and its Result here:
-// - https://pkg.go.dev/sigs.k8s.io/controller-runtime@v0.15.0/pkg/reconcile
-func (r *RancherBindReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
-	_ = log.FromContext(ctx)
+// Flow:
+// - Create the rancher user for the consumer.
+// - Bind the referenced GlobalRoles, creating the inline ones.
+// - Issue cluster scoped tokens and write the kubeconfig Secret, renewing them before they expire.
+// - On deletion log out the tokens and remove the user, roles and bindings, the Secret is garbage collected.
+func (r *RancherBindReconciler) Reconcile(ctx context.Context, req ctrl.Request) (_ ctrl.Result, reterr error) {
+	logger := log.FromContext(ctx)
+
+	bind := &rancherv1alpha1.RancherBind{}
+	if err := r.Get(ctx, req.NamespacedName, bind); apierrors.IsNotFound(err) {
+		return ctrl.Result{}, nil
+	} else if err != nil {
+		return ctrl.Result{}, err
+	}
+
+	if !bind.DeletionTimestamp.IsZero() {
+		if !controllerutil.ContainsFinalizer(bind, rancherv1alpha1.RancherBindFinalizer) {
+			return ctrl.Result{}, nil
+		}
 
-	// TODO(user): your logic here
+		if err := cleanup(ctx, r.Client, r.APIReader, bind); err != nil {
+			return ctrl.Result{}, err
+		}
+
+		logger.Info("Removed rancher objects", "user", userName(bind))
+
+		controllerutil.RemoveFinalizer(bind, rancherv1alpha1.RancherBindFinalizer)
+		return ctrl.Result{}, r.Update(ctx, bind)
+	}
 
-	return ctrl.Result{}, nil
+	if controllerutil.AddFinalizer(bind, rancherv1alpha1.RancherBindFinalizer) {
+		if err := r.Update(ctx, bind); err != nil {
+			return ctrl.Result{}, err
+		}
+	}
+
+	original := bind.DeepCopy()
+	defer func() {
+		summarize(bind)
+
+		if equality.Semantic.DeepEqual(original.Status, bind.Status) {
+			return
+		}
+
+		if err := r.Status().Patch(ctx, bind, client.MergeFrom(original)); err != nil && reterr == nil {
+			reterr = err
+		}
+	}()
+
+	annotations, err := consumerAnnotations(ctx, r.Client, bind)
+	if err != nil {
+		setCondition(bind, rancherv1alpha1.UserReadyCondition, metav1.ConditionFalse, "UserFailed", err.Error())
+		return ctrl.Result{}, err
+	}
+
+	user, err := r.ensureUser(ctx, bind, annotations)
+	if err != nil {
+		setCondition(bind, rancherv1alpha1.UserReadyCondition, metav1.ConditionFalse, "UserFailed", err.Error())
+		return ctrl.Result{}, err
+	}
+	bind.Status.UserName = user.Name
+	setCondition(bind, rancherv1alpha1.UserReadyCondition, metav1.ConditionTrue, "UserCreated", "")
+
+	if err := r.ensureBindings(ctx, bind, user, annotations); err != nil {
+		setCondition(bind, rancherv1alpha1.RolesBoundCondition, metav1.ConditionFalse, "BindingFailed", err.Error())
+		return ctrl.Result{}, err
+	}
+	setCondition(bind, rancherv1alpha1.RolesBoundCondition, metav1.ConditionTrue, "RolesBound", "")
+
+	renewAfter, err := r.ensureKubeconfig(ctx, bind, user)
+	if err != nil {
+		setCondition(bind, rancherv1alpha1.KubeconfigReadyCondition, metav1.ConditionFalse, "KubeconfigFailed", err.Error())
+		return ctrl.Result{}, err
+	}
+	setCondition(bind, rancherv1alpha1.KubeconfigReadyCondition, metav1.ConditionTrue, "KubeconfigIssued", "")
+
+	bind.Status.ObservedGeneration = bind.Generation
+
+	return ctrl.Result{RequeueAfter: renewAfter}, nil
 }
 
 // SetupWithManager sets up the controller with the Manager.
 func (r *RancherBindReconciler) SetupWithManager(mgr ctrl.Manager) error {
-	config, err := NewConfig()
-	if err != nil {
-		return fmt.Errorf("unable to get config: %w", err)
+	if r.APIReader == nil {
+		r.APIReader = mgr.GetAPIReader()
+	}
+
+	config := r.Config
+	if config == nil {
+		var err error
+		if config, err = NewConfig(mgr); err != nil {
+			return fmt.Errorf("unable to get config: %w", err)
+		}
+	}
+
+	backendConfig := r.Backend
+	if backendConfig == nil {
+		backendConfig = configv1alpha1.NewDefaultBackendConfiguration()
 	}
 
 	serviceNamespace, err := servicenamespace.NewController(
 		config.ClientConfig,
-		kubebindv1alpha1.ClusterScope,
-		config.BindInformers.KubeBind().V1alpha1().APIServiceNamespaces(),
-		config.BindInformers.KubeBind().V1alpha1().ClusterBindings(),
-		config.BindInformers.KubeBind().V1alpha1().APIServiceExports(),
-		config.KubeInformers.Core().V1().Namespaces(),
-		config.KubeInformers.Rbac().V1().Roles(),
-		config.KubeInformers.Rbac().V1().RoleBindings(),
+		backendConfig.ConsumerScope,
+		config.Informers.APIServiceNamespaces,
+		config.Informers.ClusterBindings,
+		config.Informers.APIServiceExports,
+		config.Informers.Namespaces,
+		config.Informers.Roles,
+		config.Informers.RoleBindings,
 	)
 	if err != nil {
 		return fmt.Errorf("error setting up APIServiceNamespace Controller: %w", err)
 	}
 	serviceExport, err := serviceexport.NewController(
 		config.ClientConfig,
-		config.BindInformers.KubeBind().V1alpha1().APIServiceExports(),
-		config.ApiextensionsInformers.Apiextensions().V1().CustomResourceDefinitions(),
+		config.Informers.APIServiceExports,
+		newCatalogCRDInformer(config.Informers.CustomResourceDefinitions, mgr.GetClient()),
 	)
 	if err != nil {
 		return fmt.Errorf("error setting up APIServiceExport Controller: %w", err)
@@ -153,23 +215,47 @@ func (r *RancherBindReconciler) SetupWithManager(mgr ctrl.Manager) error {
 
 	serviceExportRequest, err := serviceexportrequest.NewController(
 		config.ClientConfig,
-		kubebindv1alpha1.NamespacedScope,
-		config.BindInformers.KubeBind().V1alpha1().APIServiceExportRequests(),
-		config.BindInformers.KubeBind().V1alpha1().APIServiceExports(),
-		config.ApiextensionsInformers.Apiextensions().V1().CustomResourceDefinitions(),
+		backendConfig.ExportInformerScope,
+		newCatalogExportRequestInformer(config.Informers.APIServiceExportRequests),
+		config.Informers.APIServiceExports,
+		newCatalogCRDInformer(config.Informers.CustomResourceDefinitions, mgr.GetClient()),
 	)
 	if err != nil {
 		return fmt.Errorf("error setting up ServiceExportRequest Controller: %w", err)
 	}
 
-	// start informer factories
-	mgr.Add(NewInformer(config.KubeInformers))
-	mgr.Add(NewInformer(config.BindInformers))
-	mgr.Add(NewInformer(config.ApiextensionsInformers))
+	// report the shared informers cache sync on /readyz
+	informers := NewInformer("informers", config.Informers)
+	if err := mgr.Add(informers); err != nil {
+		return err
+	}
+	if err := mgr.AddReadyzCheck(informers.Name(), informers.Readyz); err != nil {
+		return err
+	}
 
-	mgr.Add(NewThreaded(serviceNamespace, 1))
-	mgr.Add(NewThreaded(serviceExport, 1))
-	mgr.Add(NewThreaded(serviceExportRequest, 1))
+	if err := metrics.Registry.Register(newBindCollector(config.Informers)); err != nil {
+		return fmt.Errorf("unable to register metrics: %w", err)
+	}
+	if _, err := config.Informers.APIServiceExportRequests.Informer().AddEventHandler(exportRequestOutcomes(mgr.Elected())); err != nil {
+		return fmt.Errorf("unable to count export request outcomes: %w", err)
+	}
+
+	// start kube-bind controllers, reporting their liveness on /healthz
+	for _, controller := range []*Threaded{
+		NewThreaded("apiservicenamespace", serviceNamespace, *backendConfig.Workers.APIServiceNamespace),
+		NewThreaded("apiserviceexport", serviceExport, *backendConfig.Workers.APIServiceExport),
+		NewThreaded("apiserviceexportrequest", serviceExportRequest, *backendConfig.Workers.APIServiceExportRequest),
+	} {
+		if err := mgr.Add(controller); err != nil {
+			return err
+		}
+		if err := mgr.AddHealthzCheck(controller.Name(), controller.Healthz); err != nil {
+			return err
+		}
+	}
 
-	return nil
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&rancherv1alpha1.RancherBind{}).
+		Owns(&corev1.Secret{}).
+		Complete(r)
 }
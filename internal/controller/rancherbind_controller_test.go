@@ -0,0 +1,261 @@
+/*
+Copyright 2023 SUSE.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/scheme"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	apis "github.com/Danil-Grigorev/rancher-bind/pkg/apis"
+	managementv3 "github.com/Danil-Grigorev/rancher-bind/pkg/apis/rancher/management/v3"
+	rancherv1alpha1 "github.com/Danil-Grigorev/rancher-bind/pkg/apis/rancherbind/v1alpha1"
+	"github.com/Danil-Grigorev/rancher-bind/pkg/kubectl/bind-kubeconfig/plugin"
+)
+
+const fakeKubeconfig = `apiVersion: v1
+kind: Config
+clusters:
+- name: local
+  cluster:
+    server: https://rancher.example.com/k8s/clusters/local
+users:
+- name: local
+  user:
+    token: login
+contexts:
+- name: local
+  context:
+    cluster: local
+    user: local
+current-context: local
+`
+
+// fakeRancher serves the rancher login, kubeconfig and token endpoints, storing the issued
+// tokens as Token objects like rancher does.
+type fakeRancher struct {
+	client client.Client
+
+	lock   sync.Mutex
+	issued int
+	users  map[string]string
+}
+
+func (f *fakeRancher) issue(ctx context.Context, userID string, ttl time.Duration) (*apis.LoginResponse, error) {
+	f.lock.Lock()
+	f.issued++
+	name := fmt.Sprintf("token-%d", f.issued)
+	f.users[name] = userID
+	f.lock.Unlock()
+
+	token := &managementv3.Token{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:   name,
+			Labels: map[string]string{managementv3.TokenUserIDLabel: userID},
+		},
+		UserID: userID,
+	}
+	if err := f.client.Create(ctx, token); err != nil {
+		return nil, err
+	}
+
+	response := &apis.LoginResponse{Token: name + ":secret", UserID: userID}
+	if ttl > 0 {
+		response.ExpiresAt = time.Now().Add(ttl).UTC().Format(time.RFC3339)
+	}
+	return response, nil
+}
+
+func (f *fakeRancher) user(r *http.Request) (string, bool) {
+	auth, _ := base64.StdEncoding.DecodeString(strings.TrimPrefix(r.Header.Get("Authorization"), "Basic "))
+
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	user, ok := f.users[tokenName(string(auth))]
+	return user, ok
+}
+
+func (f *fakeRancher) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var response any
+	switch r.URL.Path {
+	case "/v3-public/localProviders/local":
+		login := &apis.Login{}
+		if err := json.NewDecoder(r.Body).Decode(login); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		token, err := f.issue(r.Context(), login.Username, 0)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		response = token
+	case "/v3/clusters/local":
+		if _, ok := f.user(r); !ok {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		response = &apis.ConfigResponse{Config: fakeKubeconfig}
+	case "/v3/tokens":
+		user, ok := f.user(r)
+		if !ok {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		request := &apis.TokenRequest{}
+		if err := json.NewDecoder(r.Body).Decode(request); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		token, err := f.issue(r.Context(), user, time.Duration(request.TTL)*time.Millisecond)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		response = token
+	default:
+		http.NotFound(w, r)
+		return
+	}
+
+	_ = json.NewEncoder(w).Encode(response)
+}
+
+var _ = Describe("RancherBind controller", func() {
+	var (
+		ctx        context.Context
+		server     *httptest.Server
+		reconciler *RancherBindReconciler
+		bind       *rancherv1alpha1.RancherBind
+	)
+
+	userTokens := func() []string {
+		tokens := &managementv3.TokenList{}
+		Expect(k8sClient.List(ctx, tokens, client.MatchingLabels{managementv3.TokenUserIDLabel: userName(bind)})).To(Succeed())
+
+		names := []string{}
+		for _, token := range tokens.Items {
+			names = append(names, token.Name)
+		}
+		return names
+	}
+
+	reconcile := func() {
+		_, err := reconciler.Reconcile(ctx, ctrl.Request{NamespacedName: client.ObjectKeyFromObject(bind)})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(bind), bind)).To(Succeed())
+	}
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		server = httptest.NewServer(&fakeRancher{client: k8sClient, users: map[string]string{}})
+		reconciler = &RancherBindReconciler{Client: k8sClient, Scheme: scheme.Scheme, APIReader: k8sClient}
+
+		setting := &managementv3.Setting{
+			ObjectMeta: metav1.ObjectMeta{Name: plugin.ServerURLSetting},
+			Value:      server.URL,
+		}
+		if err := k8sClient.Create(ctx, setting); apierrors.IsAlreadyExists(err) {
+			Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(setting), setting)).To(Succeed())
+			setting.Value = server.URL
+			Expect(k8sClient.Update(ctx, setting)).To(Succeed())
+		} else {
+			Expect(err).NotTo(HaveOccurred())
+		}
+
+		namespace := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{GenerateName: "rancherbind-"}}
+		Expect(k8sClient.Create(ctx, namespace)).To(Succeed())
+
+		bind = &rancherv1alpha1.RancherBind{
+			ObjectMeta: metav1.ObjectMeta{Namespace: namespace.Name, Name: "consumer"},
+			Spec: rancherv1alpha1.RancherBindSpec{
+				Consumer: "consumer",
+				Roles:    []rancherv1alpha1.RoleRef{{Name: "user-base"}},
+				TokenTTL: &metav1.Duration{Duration: time.Hour},
+			},
+		}
+		Expect(k8sClient.Create(ctx, bind)).To(Succeed())
+	})
+
+	AfterEach(func() {
+		server.Close()
+	})
+
+	It("revokes the login token and the replaced tokens", func() {
+		reconcile()
+
+		secret := &corev1.Secret{}
+		Expect(k8sClient.Get(ctx, client.ObjectKey{Namespace: bind.Namespace, Name: bind.GetSecretName()}, secret)).To(Succeed())
+		Expect(string(secret.Data[rancherv1alpha1.KubeconfigSecretKey])).To(ContainSubstring(bind.Status.TokenNames[0] + ":secret"))
+		Expect(bind.Status.TokenNames).To(HaveLen(1))
+		Expect(bind.Status.TokenExpiresAt).NotTo(BeNil())
+
+		By("logging out the login token")
+		Expect(userTokens()).To(ConsistOf(bind.Status.TokenNames))
+
+		By("keeping the tokens while the spec is unchanged")
+		issued := bind.Status.TokenNames
+		reconcile()
+		Expect(bind.Status.TokenNames).To(Equal(issued))
+
+		By("revoking the previous tokens on a spec change")
+		bind.Spec.TokenTTL = &metav1.Duration{Duration: 2 * time.Hour}
+		Expect(k8sClient.Update(ctx, bind)).To(Succeed())
+		reconcile()
+		Expect(bind.Status.TokenNames).To(HaveLen(1))
+		Expect(bind.Status.TokenNames).NotTo(Equal(issued))
+		Expect(userTokens()).To(ConsistOf(bind.Status.TokenNames))
+
+		By("logging out the user on deletion")
+		Expect(k8sClient.Delete(ctx, bind)).To(Succeed())
+		_, err := reconciler.Reconcile(ctx, ctrl.Request{NamespacedName: client.ObjectKeyFromObject(bind)})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(userTokens()).To(BeEmpty())
+		Expect(apierrors.IsNotFound(k8sClient.Get(ctx, client.ObjectKey{Name: userName(bind)}, &managementv3.User{}))).To(BeTrue())
+	})
+
+	It("refuses to overwrite secrets it does not own", func() {
+		secret := &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Namespace: bind.Namespace, Name: bind.GetSecretName()},
+			Data:       map[string][]byte{"password": []byte("secret")},
+		}
+		Expect(k8sClient.Create(ctx, secret)).To(Succeed())
+
+		_, err := reconciler.Reconcile(ctx, ctrl.Request{NamespacedName: client.ObjectKeyFromObject(bind)})
+		Expect(err).To(MatchError(ContainSubstring("is not owned by the RancherBind")))
+
+		Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(secret), secret)).To(Succeed())
+		Expect(secret.Data).To(Equal(map[string][]byte{"password": []byte("secret")}))
+		Expect(userTokens()).To(BeEmpty())
+	})
+})
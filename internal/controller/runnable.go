@@ -0,0 +1,182 @@
+/*
+Copyright 2023 SUSE.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+	"sync"
+
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+)
+
+type ThreadedRunable interface {
+	// Start starts running the component.  The component will stop running
+	// when the context is closed. Start blocks until the context is closed or
+	// an error occurs.
+	Start(context.Context, int)
+}
+
+// Threaded runs a kube-bind controller on the elected leader only, so replicas do not
+// reconcile the same objects concurrently.
+type Threaded struct {
+	name     string
+	threads  int
+	runnable ThreadedRunable
+
+	lock sync.RWMutex
+	err  error
+}
+
+var _ manager.LeaderElectionRunnable = &Threaded{}
+
+func NewThreaded(name string, runnable ThreadedRunable, threads int) *Threaded {
+	return &Threaded{
+		name:     name,
+		threads:  threads,
+		runnable: runnable,
+	}
+}
+
+// Name returns the name of the controller.
+func (t *Threaded) Name() string {
+	return t.name
+}
+
+// Start runs the controller workers until the context is closed. Each worker runs the
+// controller with a single thread, and a worker exiting early stops the other workers and
+// fails the manager. The kube-bind controllers reconcile in goroutines of their own, whose
+// panics crash the process through runtime.HandleCrash.
+func (t *Threaded) Start(ctx context.Context) error {
+	ctx, cancel := context.WithCancelCause(ctx)
+	defer cancel(nil)
+
+	var wg sync.WaitGroup
+	for i := 0; i < t.threads; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			cancel(t.worker(ctx))
+		}()
+	}
+	wg.Wait()
+
+	err := context.Cause(ctx)
+	if errors.Is(err, context.Canceled) {
+		// Stopped with the manager.
+		return nil
+	}
+	t.setErr(err)
+	return err
+}
+
+// worker runs the controller with a single thread, returning an error if it exited before
+// the context was closed.
+func (t *Threaded) worker(ctx context.Context) error {
+	t.runnable.Start(ctx, 1)
+	if ctx.Err() == nil {
+		return fmt.Errorf("controller %s exited unexpectedly", t.name)
+	}
+
+	return nil
+}
+
+// NeedLeaderElection implements manager.LeaderElectionRunnable.
+func (t *Threaded) NeedLeaderElection() bool {
+	return true
+}
+
+// Healthz fails once the controller stopped with an error. Controllers waiting for the
+// leadership are healthy.
+func (t *Threaded) Healthz(_ *http.Request) error {
+	t.lock.RLock()
+	defer t.lock.RUnlock()
+
+	return t.err
+}
+
+func (t *Threaded) setErr(err error) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	t.err = err
+}
+
+type InformerRunnable interface {
+	Start(stopCh <-chan struct{})
+	WaitForCacheSync(stopCh <-chan struct{}) map[reflect.Type]bool
+}
+
+// Informer runs an informer factory on every replica, keeping the caches warm for a
+// leadership change.
+type Informer struct {
+	name     string
+	informer InformerRunnable
+
+	lock   sync.RWMutex
+	synced bool
+}
+
+var _ manager.LeaderElectionRunnable = &Informer{}
+
+func NewInformer(name string, informer InformerRunnable) *Informer {
+	return &Informer{
+		name:     name,
+		informer: informer,
+	}
+}
+
+// Name returns the name of the informer factory.
+func (i *Informer) Name() string {
+	return i.name
+}
+
+// Start runs the informer factory until the context is closed.
+func (i *Informer) Start(ctx context.Context) error {
+	i.informer.Start(ctx.Done())
+	// Blocks until all caches are synced or the context is closed.
+	i.informer.WaitForCacheSync(ctx.Done())
+	if ctx.Err() != nil {
+		return nil
+	}
+
+	i.lock.Lock()
+	i.synced = true
+	i.lock.Unlock()
+
+	<-ctx.Done()
+	return nil
+}
+
+// NeedLeaderElection implements manager.LeaderElectionRunnable.
+func (i *Informer) NeedLeaderElection() bool {
+	return false
+}
+
+// Readyz fails until the informer caches are synced.
+func (i *Informer) Readyz(_ *http.Request) error {
+	i.lock.RLock()
+	defer i.lock.RUnlock()
+
+	if !i.synced {
+		return fmt.Errorf("informers %s are not synced", i.name)
+	}
+	return nil
+}
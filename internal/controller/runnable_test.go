@@ -0,0 +1,176 @@
+/*
+Copyright 2023 SUSE.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"reflect"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"k8s.io/client-go/kubernetes/scheme"
+	ctrl "sigs.k8s.io/controller-runtime"
+	metricsserver "sigs.k8s.io/controller-runtime/pkg/metrics/server"
+)
+
+// countingRunnable counts the replicas running a controller or informer factory.
+type countingRunnable struct {
+	started atomic.Int32
+	running atomic.Int32
+}
+
+func (c *countingRunnable) run(done <-chan struct{}) {
+	c.started.Add(1)
+	c.running.Add(1)
+	go func() {
+		<-done
+		c.running.Add(-1)
+	}()
+}
+
+type countingController struct{ countingRunnable }
+
+func (c *countingController) Start(ctx context.Context, _ int) {
+	c.run(ctx.Done())
+	<-ctx.Done()
+}
+
+type countingInformers struct{ countingRunnable }
+
+func (c *countingInformers) Start(stopCh <-chan struct{}) {
+	c.run(stopCh)
+}
+
+func (c *countingInformers) WaitForCacheSync(_ <-chan struct{}) map[reflect.Type]bool {
+	return nil
+}
+
+var _ = Describe("Runnables", func() {
+	It("runs the controllers on the leader and the informers on all replicas", func() {
+		controllers := &countingController{}
+		informers := &countingInformers{}
+
+		startReplica := func() context.CancelFunc {
+			mgr, err := ctrl.NewManager(cfg, ctrl.Options{
+				Scheme:                        scheme.Scheme,
+				Metrics:                       metricsserver.Options{BindAddress: "0"},
+				LeaderElection:                true,
+				LeaderElectionID:              "runnables.rancher.kube-bind.io",
+				LeaderElectionNamespace:       "default",
+				LeaderElectionReleaseOnCancel: true,
+				LeaseDuration:                 ptrTo(2 * time.Second),
+				RenewDeadline:                 ptrTo(time.Second),
+				RetryPeriod:                   ptrTo(200 * time.Millisecond),
+			})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(mgr.Add(NewInformer("informers", informers))).To(Succeed())
+			Expect(mgr.Add(NewThreaded("controller", controllers, 1))).To(Succeed())
+
+			ctx, cancel := context.WithCancel(context.Background())
+			go func() {
+				defer GinkgoRecover()
+				Expect(mgr.Start(ctx)).To(Succeed())
+			}()
+			return cancel
+		}
+
+		stopLeader := startReplica()
+		Eventually(controllers.running.Load).Should(BeEquivalentTo(1))
+		stopStandby := startReplica()
+		defer stopStandby()
+
+		Eventually(informers.running.Load).Should(BeEquivalentTo(2))
+		Consistently(controllers.started.Load, 3*time.Second).Should(BeEquivalentTo(1))
+
+		By("handing the leadership over")
+		stopLeader()
+		Eventually(informers.running.Load).Should(BeEquivalentTo(1))
+		Eventually(controllers.started.Load, 10*time.Second).Should(BeEquivalentTo(2))
+		Eventually(controllers.running.Load).Should(BeEquivalentTo(1))
+	})
+})
+
+func ptrTo[T any](v T) *T {
+	return &v
+}
+
+type failingController func(ctx context.Context)
+
+func (f failingController) Start(ctx context.Context, _ int) {
+	f(ctx)
+}
+
+func TestThreadedErrors(t *testing.T) {
+	g := NewWithT(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	exited := NewThreaded("exited", failingController(func(context.Context) {}), 1)
+	g.Expect(exited.Healthz(nil)).To(Succeed())
+	g.Expect(exited.Start(ctx)).To(MatchError("controller exited exited unexpectedly"))
+	g.Expect(exited.Healthz(nil)).ToNot(Succeed())
+
+	// A worker exiting early stops the other workers.
+	var workers, stoppedWorkers atomic.Int32
+	workerExited := NewThreaded("worker", failingController(func(ctx context.Context) {
+		if workers.Add(1) == 3 {
+			return
+		}
+		<-ctx.Done()
+		stoppedWorkers.Add(1)
+	}), 3)
+	g.Expect(workerExited.Start(ctx)).To(MatchError("controller worker exited unexpectedly"))
+	g.Expect(workerExited.Healthz(nil)).ToNot(Succeed())
+	g.Expect(stoppedWorkers.Load()).To(BeEquivalentTo(2))
+
+	stopped := NewThreaded("stopped", failingController(func(ctx context.Context) { <-ctx.Done() }), 1)
+	cancel()
+	g.Expect(stopped.Start(ctx)).To(Succeed())
+	g.Expect(stopped.Healthz(nil)).To(Succeed())
+}
+
+type syncingInformers chan struct{}
+
+func (s syncingInformers) Start(_ <-chan struct{}) {}
+
+func (s syncingInformers) WaitForCacheSync(stopCh <-chan struct{}) map[reflect.Type]bool {
+	select {
+	case <-s:
+	case <-stopCh:
+	}
+	return nil
+}
+
+func TestInformerReadyz(t *testing.T) {
+	g := NewWithT(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	synced := make(syncingInformers)
+	informer := NewInformer("informers", synced)
+	go informer.Start(ctx) // nolint:errcheck
+
+	g.Consistently(func() error { return informer.Readyz(nil) }, 100*time.Millisecond).ShouldNot(Succeed())
+	close(synced)
+	g.Eventually(func() error { return informer.Readyz(nil) }).Should(Succeed())
+}
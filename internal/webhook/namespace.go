@@ -0,0 +1,70 @@
+/*
+Copyright 2023 SUSE.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"gomodules.xyz/jsonpatch/v2"
+	corev1 "k8s.io/api/core/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	kubebindv1alpha1 "github.com/kube-bind/kube-bind/pkg/apis/kubebind/v1alpha1"
+
+	rancherv1alpha1 "github.com/Danil-Grigorev/rancher-bind/pkg/apis/rancherbind/v1alpha1"
+)
+
+// NamespacePath is the path the namespace webhook is served on.
+const NamespacePath = "/mutate-rancher-bind-namespace"
+
+//+kubebuilder:webhook:path=/mutate-rancher-bind-namespace,mutating=true,failurePolicy=ignore,sideEffects=None,groups=core,resources=namespaces,verbs=create,versions=v1,name=namespace.rancher.kube-bind.io,admissionReviewVersions=v1
+
+// NamespaceLabeler labels the service namespaces with the consumer namespace when kube-bind
+// creates them, so the policy webhook selects them before consumers can write into them.
+// The webhook fails open, the ConsumerLabel controller labels the namespaces it missed.
+type NamespaceLabeler struct{}
+
+// Handle labels the created namespaces annotated with their APIServiceNamespace.
+func (l *NamespaceLabeler) Handle(_ context.Context, req admission.Request) admission.Response {
+	namespace := &corev1.Namespace{}
+	if err := json.Unmarshal(req.Object.Raw, namespace); err != nil {
+		return admission.Errored(http.StatusBadRequest, err)
+	}
+	consumer, _, ok := strings.Cut(namespace.Annotations[kubebindv1alpha1.APIServiceNamespaceAnnotationKey], "/")
+	if !ok || namespace.Labels[rancherv1alpha1.ConsumerNamespaceLabel] == consumer {
+		return admission.Allowed("")
+	}
+
+	if namespace.Labels == nil {
+		return admission.Patched("", jsonpatch.NewOperation("add", "/metadata/labels",
+			map[string]string{rancherv1alpha1.ConsumerNamespaceLabel: consumer}))
+	}
+	// The / of the label key is escaped in the JSON pointer.
+	return admission.Patched("", jsonpatch.NewOperation("add",
+		"/metadata/labels/"+strings.ReplaceAll(rancherv1alpha1.ConsumerNamespaceLabel, "/", "~1"), consumer))
+}
+
+// SetupWithManager registers the webhook with the webhook server of the Manager.
+func (l *NamespaceLabeler) SetupWithManager(mgr ctrl.Manager) error {
+	mgr.GetWebhookServer().Register(NamespacePath, &webhook.Admission{Handler: l})
+	return nil
+}
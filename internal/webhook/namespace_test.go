@@ -0,0 +1,57 @@
+/*
+Copyright 2023 SUSE.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhook
+
+import (
+	"context"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	"gomodules.xyz/jsonpatch/v2"
+	admissionv1 "k8s.io/api/admission/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	rancherv1alpha1 "github.com/Danil-Grigorev/rancher-bind/pkg/apis/rancherbind/v1alpha1"
+)
+
+func TestNamespaceLabeler(t *testing.T) {
+	g := NewWithT(t)
+	l := &NamespaceLabeler{}
+
+	create := func(namespace string) admission.Response {
+		return l.Handle(context.Background(), admission.Request{AdmissionRequest: admissionv1.AdmissionRequest{
+			Operation: admissionv1.Create,
+			Object:    runtime.RawExtension{Raw: []byte(namespace)},
+		}})
+	}
+
+	resp := create(`{"metadata":{"name":"kube-bind-a-default","annotations":{"kube-bind.io/api-service-namespace":"kube-bind-a/default"}}}`)
+	g.Expect(resp.Allowed).To(BeTrue())
+	g.Expect(resp.Patches).To(Equal([]jsonpatch.Operation{jsonpatch.NewOperation("add", "/metadata/labels",
+		map[string]string{rancherv1alpha1.ConsumerNamespaceLabel: "kube-bind-a"})}))
+
+	resp = create(`{"metadata":{"name":"kube-bind-a-default","labels":{"team":"a"},"annotations":{"kube-bind.io/api-service-namespace":"kube-bind-a/default"}}}`)
+	g.Expect(resp.Allowed).To(BeTrue())
+	g.Expect(resp.Patches).To(Equal([]jsonpatch.Operation{jsonpatch.NewOperation("add",
+		"/metadata/labels/rancher.kube-bind.io~1consumer-namespace", "kube-bind-a")}))
+
+	// Other namespaces are left alone.
+	resp = create(`{"metadata":{"name":"fleet-default"}}`)
+	g.Expect(resp.Allowed).To(BeTrue())
+	g.Expect(resp.Patches).To(BeEmpty())
+}
@@ -0,0 +1,234 @@
+/*
+Copyright 2023 SUSE.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"slices"
+	"strings"
+
+	admissionv1 "k8s.io/api/admission/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	kubebindv1alpha1 "github.com/kube-bind/kube-bind/pkg/apis/kubebind/v1alpha1"
+
+	rancherv1alpha1 "github.com/Danil-Grigorev/rancher-bind/pkg/apis/rancherbind/v1alpha1"
+)
+
+// PolicyPath is the path the policy webhook is served on.
+const PolicyPath = "/validate-rancher-bind-policy"
+
+// The rules of the marker are the initial ones, the PolicyRules controller replaces them with
+// the resources of the policies.
+//+kubebuilder:webhook:path=/validate-rancher-bind-policy,mutating=false,failurePolicy=fail,sideEffects=NoneOnDryRun,groups=provisioning.cattle.io;rke-machine-config.cattle.io;fleet.cattle.io,resources=*,verbs=create;update,versions=*,name=policy.rancher.kube-bind.io,admissionReviewVersions=v1
+//+kubebuilder:rbac:groups=rancher.kube-bind.io,resources=rancherbindpolicies,verbs=get;list;watch
+//+kubebuilder:rbac:groups=kube-bind.io,resources=apiserviceexports,verbs=get
+//+kubebuilder:rbac:groups=core,resources=events,verbs=create;patch
+
+// PolicyViolationReason is the reason of the Events reporting denied objects on the
+// APIServiceExports of the consumers.
+const PolicyViolationReason = "PolicyViolation"
+
+// PolicyValidator rejects the objects consumers write into their service namespaces
+// violating a RancherBindPolicy.
+type PolicyValidator struct {
+	Client client.Reader
+
+	// APIReader reads the APIServiceExports without caching all of them.
+	APIReader client.Reader
+
+	Recorder record.EventRecorder
+}
+
+// Handle validates the created and updated objects against the policies applying to them.
+// Updates leaving the spec and the labels alone pass, so rancher can keep updating the
+// status and the finalizers of objects created before a policy.
+func (v *PolicyValidator) Handle(ctx context.Context, req admission.Request) admission.Response {
+	if req.Namespace == "" {
+		return admission.Allowed("")
+	}
+
+	namespace := &corev1.Namespace{}
+	if err := v.Client.Get(ctx, client.ObjectKey{Name: req.Namespace}, namespace); err != nil {
+		return admission.Errored(http.StatusInternalServerError, fmt.Errorf("unable to get namespace %s: %w", req.Namespace, err))
+	}
+	consumer, _, ok := strings.Cut(namespace.Annotations[kubebindv1alpha1.APIServiceNamespaceAnnotationKey], "/")
+	if !ok {
+		return admission.Allowed("not a service namespace")
+	}
+
+	policies := &rancherv1alpha1.RancherBindPolicyList{}
+	if err := v.Client.List(ctx, policies); err != nil {
+		return admission.Errored(http.StatusInternalServerError, fmt.Errorf("unable to list policies: %w", err))
+	}
+	applying := []rancherv1alpha1.RancherBindPolicy{}
+	for _, policy := range policies.Items {
+		if policy.AppliesTo(req.Resource.Group, req.Resource.Resource, consumer) {
+			applying = append(applying, policy)
+		}
+	}
+	if len(applying) == 0 {
+		return admission.Allowed("")
+	}
+
+	obj := map[string]interface{}{}
+	if err := json.Unmarshal(req.Object.Raw, &obj); err != nil {
+		return admission.Errored(http.StatusBadRequest, err)
+	}
+	if req.Operation == admissionv1.Update {
+		old := map[string]interface{}{}
+		if err := json.Unmarshal(req.OldObject.Raw, &old); err != nil {
+			return admission.Errored(http.StatusBadRequest, err)
+		}
+		if reflect.DeepEqual(consumerFields(old), consumerFields(obj)) {
+			return admission.Allowed("")
+		}
+	}
+
+	violations := []string{}
+	for i := range applying {
+		violations = append(violations, Violations(&applying[i], obj)...)
+	}
+	if len(violations) > 0 {
+		message := strings.Join(violations, "; ")
+		if req.DryRun == nil || !*req.DryRun {
+			v.recordViolation(ctx, req, consumer, message)
+		}
+		return admission.Denied(message)
+	}
+
+	return admission.Allowed("")
+}
+
+// recordViolation records the denial on the APIServiceExport of the resource in the consumer
+// namespace, as the konnector only logs the rejected writes on the consumer cluster.
+func (v *PolicyValidator) recordViolation(ctx context.Context, req admission.Request, consumer, message string) {
+	export := &kubebindv1alpha1.APIServiceExport{}
+	key := client.ObjectKey{Namespace: consumer, Name: req.Resource.Resource + "." + req.Resource.Group}
+	if err := v.APIReader.Get(ctx, key, export); err != nil {
+		log.FromContext(ctx).Error(err, "Unable to get APIServiceExport to report a policy violation", "export", key)
+		return
+	}
+
+	v.Recorder.Eventf(export, corev1.EventTypeWarning, PolicyViolationReason, "%s %s/%s denied: %s",
+		req.Kind.Kind, req.Namespace, req.Name, message)
+}
+
+// Violations returns the messages of the constraints of the policy the object violates.
+func Violations(policy *rancherv1alpha1.RancherBindPolicy, obj map[string]interface{}) []string {
+	violations := []string{}
+	violate := func(format string, args ...interface{}) {
+		violations = append(violations, fmt.Sprintf("RancherBindPolicy %s: ", policy.Name)+fmt.Sprintf(format, args...))
+	}
+
+	for _, allowed := range policy.Spec.AllowedValues {
+		for _, value := range fieldValues(obj, strings.Split(allowed.Path, ".")) {
+			if formatted := format(value); !slices.Contains(allowed.Values, formatted) {
+				violate("%s value %q is not allowed, allowed values: %s", allowed.Path, formatted, strings.Join(allowed.Values, ", "))
+			}
+		}
+	}
+
+	labels := fieldValues(obj, []string{"metadata", "labels"})
+	for _, required := range policy.Spec.RequiredLabels {
+		values := []interface{}{}
+		for _, l := range labels {
+			values = append(values, fieldValues(l, []string{required.Key})...)
+		}
+		if len(values) == 0 {
+			violate("label %s is required", required.Key)
+			continue
+		}
+		if formatted := format(values[0]); len(required.Values) > 0 && !slices.Contains(required.Values, formatted) {
+			violate("label %s value %q is not allowed, allowed values: %s", required.Key, formatted, strings.Join(required.Values, ", "))
+		}
+	}
+
+	for _, denied := range policy.Spec.DeniedFields {
+		if len(fieldValues(obj, strings.Split(denied, "."))) > 0 {
+			violate("%s must not be set", denied)
+		}
+	}
+
+	return violations
+}
+
+// fieldValues returns the values at the path, applying the rest of the path to each item
+// of the lists on the way.
+func fieldValues(value interface{}, path []string) []interface{} {
+	if items, ok := value.([]interface{}); ok {
+		values := []interface{}{}
+		for _, item := range items {
+			values = append(values, fieldValues(item, path)...)
+		}
+		return values
+	}
+	if len(path) == 0 {
+		return []interface{}{value}
+	}
+
+	fields, ok := value.(map[string]interface{})
+	if !ok {
+		return nil
+	}
+	field, ok := fields[path[0]]
+	if !ok {
+		return nil
+	}
+
+	return fieldValues(field, path[1:])
+}
+
+// format returns strings as is, and the JSON of other values.
+func format(value interface{}) string {
+	if s, ok := value.(string); ok {
+		return s
+	}
+
+	data, _ := json.Marshal(value)
+	return string(data)
+}
+
+// consumerFields returns the fields of the object consumers write: all but the status and
+// the metadata besides the labels.
+func consumerFields(obj map[string]interface{}) map[string]interface{} {
+	fields := map[string]interface{}{}
+	for key, value := range obj {
+		if key != "status" && key != "metadata" {
+			fields[key] = value
+		}
+	}
+	fields["labels"] = fieldValues(obj, []string{"metadata", "labels"})
+
+	return fields
+}
+
+// SetupWithManager registers the webhook with the webhook server of the Manager.
+func (v *PolicyValidator) SetupWithManager(mgr ctrl.Manager) error {
+	mgr.GetWebhookServer().Register(PolicyPath, &webhook.Admission{Handler: v})
+	return nil
+}
@@ -0,0 +1,124 @@
+/*
+Copyright 2023 SUSE.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhook
+
+import (
+	"context"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	admissionv1 "k8s.io/api/admission/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/utils/pointer"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	kubebindv1alpha1 "github.com/kube-bind/kube-bind/pkg/apis/kubebind/v1alpha1"
+
+	rancherv1alpha1 "github.com/Danil-Grigorev/rancher-bind/pkg/apis/rancherbind/v1alpha1"
+)
+
+func request(operation admissionv1.Operation, namespace, object, oldObject string) admission.Request {
+	return admission.Request{AdmissionRequest: admissionv1.AdmissionRequest{
+		Operation: operation,
+		Namespace: namespace,
+		Name:      "c",
+		Kind:      metav1.GroupVersionKind{Group: "provisioning.cattle.io", Version: "v1", Kind: "Cluster"},
+		Resource:  metav1.GroupVersionResource{Group: "provisioning.cattle.io", Version: "v1", Resource: "clusters"},
+		Object:    runtime.RawExtension{Raw: []byte(object)},
+		OldObject: runtime.RawExtension{Raw: []byte(oldObject)},
+	}}
+}
+
+func TestPolicyValidator(t *testing.T) {
+	g := NewWithT(t)
+	ctx := context.Background()
+
+	scheme := runtime.NewScheme()
+	g.Expect(corev1.AddToScheme(scheme)).To(Succeed())
+	g.Expect(rancherv1alpha1.AddToScheme(scheme)).To(Succeed())
+	g.Expect(kubebindv1alpha1.AddToScheme(scheme)).To(Succeed())
+
+	serviceNamespace := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{
+		Name:        "kube-bind-a-default",
+		Annotations: map[string]string{kubebindv1alpha1.APIServiceNamespaceAnnotationKey: "kube-bind-a/default"},
+	}}
+	otherNamespace := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "fleet-default"}}
+	policy := &rancherv1alpha1.RancherBindPolicy{
+		ObjectMeta: metav1.ObjectMeta{Name: "clusters"},
+		Spec: rancherv1alpha1.RancherBindPolicySpec{
+			Resources: []rancherv1alpha1.PolicyResource{{Group: "provisioning.cattle.io", Resource: "clusters"}},
+			AllowedValues: []rancherv1alpha1.AllowedValues{
+				{Path: "spec.kubernetesVersion", Values: []string{"v1.28.9+rke2r1"}},
+				{Path: "spec.rkeConfig.machinePools.quantity", Values: []string{"1", "3"}},
+			},
+			RequiredLabels: []rancherv1alpha1.RequiredLabel{{Key: "team"}, {Key: "env", Values: []string{"dev"}}},
+			DeniedFields:   []string{"spec.defaultPodSecurityAdmissionConfigurationTemplateName"},
+		},
+	}
+	otherConsumerPolicy := &rancherv1alpha1.RancherBindPolicy{
+		ObjectMeta: metav1.ObjectMeta{Name: "other"},
+		Spec: rancherv1alpha1.RancherBindPolicySpec{
+			Resources:    []rancherv1alpha1.PolicyResource{{Group: "provisioning.cattle.io", Resource: "clusters"}},
+			Consumers:    []string{"kube-bind-b"},
+			DeniedFields: []string{"spec"},
+		},
+	}
+
+	export := &kubebindv1alpha1.APIServiceExport{ObjectMeta: metav1.ObjectMeta{Namespace: "kube-bind-a", Name: "clusters.provisioning.cattle.io"}}
+
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(serviceNamespace, otherNamespace, policy, otherConsumerPolicy, export).Build()
+	recorder := record.NewFakeRecorder(10)
+	v := &PolicyValidator{Client: c, APIReader: c, Recorder: recorder}
+
+	valid := `{"metadata":{"name":"c","labels":{"team":"a","env":"dev"}},"spec":{"kubernetesVersion":"v1.28.9+rke2r1","rkeConfig":{"machinePools":[{"quantity":1},{"quantity":3}]}}}`
+	resp := v.Handle(ctx, request(admissionv1.Create, serviceNamespace.Name, valid, ""))
+	g.Expect(resp.Allowed).To(BeTrue())
+
+	invalid := `{"metadata":{"name":"c","labels":{"env":"prod"}},"spec":{"kubernetesVersion":"v1.27.1+rke2r1","defaultPodSecurityAdmissionConfigurationTemplateName":"privileged","rkeConfig":{"machinePools":[{"quantity":1},{"quantity":5}]}}}`
+	resp = v.Handle(ctx, request(admissionv1.Create, serviceNamespace.Name, invalid, ""))
+	g.Expect(resp.Allowed).To(BeFalse())
+	g.Expect(resp.Result.Message).To(Equal(`RancherBindPolicy clusters: spec.kubernetesVersion value "v1.27.1+rke2r1" is not allowed, allowed values: v1.28.9+rke2r1; ` +
+		`RancherBindPolicy clusters: spec.rkeConfig.machinePools.quantity value "5" is not allowed, allowed values: 1, 3; ` +
+		`RancherBindPolicy clusters: label team is required; ` +
+		`RancherBindPolicy clusters: label env value "prod" is not allowed, allowed values: dev; ` +
+		`RancherBindPolicy clusters: spec.defaultPodSecurityAdmissionConfigurationTemplateName must not be set`))
+	g.Expect(recorder.Events).To(Receive(HavePrefix("Warning " + PolicyViolationReason + " Cluster kube-bind-a-default/c denied: RancherBindPolicy clusters:")))
+
+	// Dry runs are denied without an Event.
+	dryRun := request(admissionv1.Create, serviceNamespace.Name, invalid, "")
+	dryRun.DryRun = pointer.Bool(true)
+	resp = v.Handle(ctx, dryRun)
+	g.Expect(resp.Allowed).To(BeFalse())
+	g.Expect(recorder.Events).ToNot(Receive())
+
+	// Objects outside the service namespaces are not validated.
+	resp = v.Handle(ctx, request(admissionv1.Create, otherNamespace.Name, invalid, ""))
+	g.Expect(resp.Allowed).To(BeTrue())
+
+	// Updates of the status of objects created before the policy pass.
+	withStatus := `{"metadata":{"name":"c","labels":{"env":"prod"},"finalizers":["x"]},"spec":{"kubernetesVersion":"v1.27.1+rke2r1","defaultPodSecurityAdmissionConfigurationTemplateName":"privileged","rkeConfig":{"machinePools":[{"quantity":1},{"quantity":5}]}},"status":{"ready":true}}`
+	resp = v.Handle(ctx, request(admissionv1.Update, serviceNamespace.Name, withStatus, invalid))
+	g.Expect(resp.Allowed).To(BeTrue())
+
+	// Spec updates are validated.
+	resp = v.Handle(ctx, request(admissionv1.Update, serviceNamespace.Name, invalid, valid))
+	g.Expect(resp.Allowed).To(BeFalse())
+}
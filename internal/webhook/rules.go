@@ -0,0 +1,141 @@
+/*
+Copyright 2023 SUSE.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhook
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	admissionregistrationv1 "k8s.io/api/admissionregistration/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	rancherv1alpha1 "github.com/Danil-Grigorev/rancher-bind/pkg/apis/rancherbind/v1alpha1"
+)
+
+const (
+	// PolicyWebhookName is the name of the policy webhook in the ValidatingWebhookConfiguration.
+	PolicyWebhookName = "policy.rancher.kube-bind.io"
+
+	// DefaultPolicyWebhookConfiguration is the name of the ValidatingWebhookConfiguration
+	// deployed by config/default.
+	DefaultPolicyWebhookConfiguration = "rancher-bind-validating-webhook-configuration"
+)
+
+// PolicyRulesReconciler keeps the rules of the policy webhook in sync with the resources of the
+// RancherBindPolicies, so every policy is enforced and no other writes go through the webhook.
+type PolicyRulesReconciler struct {
+	client.Client
+
+	// APIReader reads the ValidatingWebhookConfiguration without caching all of them.
+	APIReader client.Reader
+
+	// Configuration is the name of the ValidatingWebhookConfiguration of the policy webhook.
+	Configuration string
+}
+
+//+kubebuilder:rbac:groups=admissionregistration.k8s.io,resources=validatingwebhookconfigurations,verbs=get;update,resourceNames=rancher-bind-validating-webhook-configuration
+
+// Reconcile writes the rules of the policy webhook. The policies are resynced periodically,
+// which also reverts the rules reset by applying the manifests again.
+func (r *PolicyRulesReconciler) Reconcile(ctx context.Context, _ ctrl.Request) (ctrl.Result, error) {
+	policies := &rancherv1alpha1.RancherBindPolicyList{}
+	if err := r.List(ctx, policies); err != nil {
+		return ctrl.Result{}, fmt.Errorf("unable to list policies: %w", err)
+	}
+
+	configuration := &admissionregistrationv1.ValidatingWebhookConfiguration{}
+	if err := r.APIReader.Get(ctx, client.ObjectKey{Name: r.Configuration}, configuration); err != nil {
+		return ctrl.Result{}, fmt.Errorf("unable to get webhook configuration %s: %w", r.Configuration, err)
+	}
+
+	rules := PolicyRules(policies.Items)
+	for i := range configuration.Webhooks {
+		webhook := &configuration.Webhooks[i]
+		if webhook.Name != PolicyWebhookName {
+			continue
+		}
+		if equality.Semantic.DeepEqual(webhook.Rules, rules) {
+			return ctrl.Result{}, nil
+		}
+
+		webhook.Rules = rules
+		if err := r.Update(ctx, configuration); err != nil {
+			return ctrl.Result{}, fmt.Errorf("unable to update webhook configuration %s: %w", r.Configuration, err)
+		}
+
+		log.FromContext(ctx).Info("Updated policy webhook rules", "configuration", r.Configuration, "rules", len(rules))
+		return ctrl.Result{}, nil
+	}
+
+	return ctrl.Result{}, fmt.Errorf("webhook configuration %s has no %s webhook", r.Configuration, PolicyWebhookName)
+}
+
+// PolicyRules returns the webhook rules matching the creates and updates of the resources of
+// the policies, one rule per API group.
+func PolicyRules(policies []rancherv1alpha1.RancherBindPolicy) []admissionregistrationv1.RuleWithOperations {
+	groups := map[string]map[string]bool{}
+	for _, policy := range policies {
+		for _, resource := range policy.Spec.Resources {
+			if groups[resource.Group] == nil {
+				groups[resource.Group] = map[string]bool{}
+			}
+			groups[resource.Group][resource.Resource] = true
+		}
+	}
+
+	// No policies leave the webhook without rules, as the API server stores them.
+	var rules []admissionregistrationv1.RuleWithOperations
+	for group, resources := range groups {
+		names := []string{}
+		for resource := range resources {
+			names = append(names, resource)
+		}
+		sort.Strings(names)
+
+		scope := admissionregistrationv1.NamespacedScope
+		rules = append(rules, admissionregistrationv1.RuleWithOperations{
+			Operations: []admissionregistrationv1.OperationType{admissionregistrationv1.Create, admissionregistrationv1.Update},
+			Rule: admissionregistrationv1.Rule{
+				APIGroups:   []string{group},
+				APIVersions: []string{"*"},
+				Resources:   names,
+				Scope:       &scope,
+			},
+		})
+	}
+	sort.Slice(rules, func(i, j int) bool { return rules[i].APIGroups[0] < rules[j].APIGroups[0] })
+
+	return rules
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *PolicyRulesReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	configuration := func(context.Context, client.Object) []reconcile.Request {
+		return []reconcile.Request{{NamespacedName: client.ObjectKey{Name: r.Configuration}}}
+	}
+
+	return ctrl.NewControllerManagedBy(mgr).
+		Named("policyrules").
+		Watches(&rancherv1alpha1.RancherBindPolicy{}, handler.EnqueueRequestsFromMapFunc(configuration)).
+		Complete(r)
+}
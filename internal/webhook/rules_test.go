@@ -0,0 +1,98 @@
+/*
+Copyright 2023 SUSE.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhook
+
+import (
+	"context"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	admissionregistrationv1 "k8s.io/api/admissionregistration/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	rancherv1alpha1 "github.com/Danil-Grigorev/rancher-bind/pkg/apis/rancherbind/v1alpha1"
+)
+
+func TestPolicyRules(t *testing.T) {
+	g := NewWithT(t)
+	ctx := context.Background()
+
+	scheme := runtime.NewScheme()
+	g.Expect(admissionregistrationv1.AddToScheme(scheme)).To(Succeed())
+	g.Expect(rancherv1alpha1.AddToScheme(scheme)).To(Succeed())
+
+	configuration := &admissionregistrationv1.ValidatingWebhookConfiguration{
+		ObjectMeta: metav1.ObjectMeta{Name: DefaultPolicyWebhookConfiguration},
+		Webhooks: []admissionregistrationv1.ValidatingWebhook{{
+			Name: PolicyWebhookName,
+			Rules: []admissionregistrationv1.RuleWithOperations{{
+				Operations: []admissionregistrationv1.OperationType{admissionregistrationv1.Create},
+				Rule:       admissionregistrationv1.Rule{APIGroups: []string{"fleet.cattle.io"}, Resources: []string{"*"}},
+			}},
+		}},
+	}
+	clusters := &rancherv1alpha1.RancherBindPolicy{
+		ObjectMeta: metav1.ObjectMeta{Name: "clusters"},
+		Spec: rancherv1alpha1.RancherBindPolicySpec{Resources: []rancherv1alpha1.PolicyResource{
+			{Group: "provisioning.cattle.io", Resource: "clusters"},
+			{Group: rancherv1alpha1.GroupVersion.Group, Resource: "kubeconfigrequests"},
+		}},
+	}
+	machines := &rancherv1alpha1.RancherBindPolicy{
+		ObjectMeta: metav1.ObjectMeta{Name: "machines"},
+		Spec: rancherv1alpha1.RancherBindPolicySpec{Resources: []rancherv1alpha1.PolicyResource{
+			{Group: "rke-machine-config.cattle.io", Resource: "amazonec2configs"},
+			{Group: "provisioning.cattle.io", Resource: "clusters"},
+		}},
+	}
+
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(configuration, clusters, machines).Build()
+	r := &PolicyRulesReconciler{Client: c, APIReader: c, Configuration: DefaultPolicyWebhookConfiguration}
+	reconcile := func() {
+		_, err := r.Reconcile(ctx, ctrl.Request{NamespacedName: client.ObjectKeyFromObject(configuration)})
+		g.Expect(err).ToNot(HaveOccurred())
+		g.Expect(c.Get(ctx, client.ObjectKeyFromObject(configuration), configuration)).To(Succeed())
+	}
+
+	// The rules are replaced with the resources of the policies, including the ones outside
+	// of the default catalog.
+	reconcile()
+	rules := configuration.Webhooks[0].Rules
+	g.Expect(rules).To(HaveLen(3))
+	g.Expect(rules[0].APIGroups).To(Equal([]string{"provisioning.cattle.io"}))
+	g.Expect(rules[0].Resources).To(Equal([]string{"clusters"}))
+	g.Expect(rules[0].Operations).To(ConsistOf(admissionregistrationv1.Create, admissionregistrationv1.Update))
+	g.Expect(rules[1].APIGroups).To(Equal([]string{rancherv1alpha1.GroupVersion.Group}))
+	g.Expect(rules[1].Resources).To(Equal([]string{"kubeconfigrequests"}))
+	g.Expect(rules[2].APIGroups).To(Equal([]string{"rke-machine-config.cattle.io"}))
+
+	// Without policies the webhook has no rules.
+	g.Expect(c.Delete(ctx, clusters)).To(Succeed())
+	g.Expect(c.Delete(ctx, machines)).To(Succeed())
+	reconcile()
+	g.Expect(configuration.Webhooks[0].Rules).To(BeEmpty())
+
+	// A configuration without the policy webhook is reported.
+	configuration.Webhooks[0].Name = "other.rancher.kube-bind.io"
+	g.Expect(c.Update(ctx, configuration)).To(Succeed())
+	_, err := r.Reconcile(ctx, ctrl.Request{NamespacedName: client.ObjectKeyFromObject(configuration)})
+	g.Expect(err).To(MatchError(ContainSubstring("has no " + PolicyWebhookName)))
+}
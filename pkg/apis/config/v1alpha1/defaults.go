@@ -0,0 +1,65 @@
+package v1alpha1
+
+import (
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/pointer"
+
+	kubebindv1alpha1 "github.com/kube-bind/kube-bind/pkg/apis/kubebind/v1alpha1"
+)
+
+// SetDefaults fills the unset fields of the config. Numbers and durations are pointers, so
+// explicit zero values are kept.
+func SetDefaults(c *BackendConfiguration) {
+	if c.APIVersion == "" {
+		c.APIVersion = GroupVersion.String()
+	}
+	if c.Kind == "" {
+		c.Kind = BackendConfigurationKind
+	}
+
+	if c.Workers.APIServiceNamespace == nil {
+		c.Workers.APIServiceNamespace = pointer.Int(1)
+	}
+	if c.Workers.APIServiceExport == nil {
+		c.Workers.APIServiceExport = pointer.Int(1)
+	}
+	if c.Workers.APIServiceExportRequest == nil {
+		c.Workers.APIServiceExportRequest = pointer.Int(1)
+	}
+	if c.InformerResync == nil {
+		c.InformerResync = &metav1.Duration{Duration: 30 * time.Minute}
+	}
+	if c.ConsumerScope == "" {
+		c.ConsumerScope = kubebindv1alpha1.ClusterScope
+	}
+	if c.ExportInformerScope == "" {
+		c.ExportInformerScope = kubebindv1alpha1.NamespacedScope
+	}
+	if c.NamespacePrefix == "" {
+		c.NamespacePrefix = "kube-bind-"
+	}
+	if c.ConsumerGracePeriod == nil {
+		c.ConsumerGracePeriod = &metav1.Duration{Duration: time.Hour}
+	}
+	if c.Heartbeat.StaleAfter == nil {
+		c.Heartbeat.StaleAfter = &metav1.Duration{Duration: 15 * time.Minute}
+	}
+	if c.Projects.Mode == "" {
+		c.Projects.Mode = ProjectModeNone
+	}
+	if c.ClientConnection.QPS == nil {
+		c.ClientConnection.QPS = pointer.Float32(50)
+	}
+	if c.ClientConnection.Burst == nil {
+		c.ClientConnection.Burst = pointer.Int(100)
+	}
+}
+
+// NewDefaultBackendConfiguration returns the config used without a config file.
+func NewDefaultBackendConfiguration() *BackendConfiguration {
+	c := &BackendConfiguration{}
+	SetDefaults(c)
+	return c
+}
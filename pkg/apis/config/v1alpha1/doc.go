@@ -0,0 +1,30 @@
+/*
+Copyright 2023 SUSE.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package v1alpha1 contains the config.rancher.kube-bind.io/v1alpha1 component config of
+// the backend, read from the file passed with --config.
+// +groupName=config.rancher.kube-bind.io
+package v1alpha1
+
+import (
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// GroupVersion is the group version of the component config.
+var GroupVersion = schema.GroupVersion{Group: "config.rancher.kube-bind.io", Version: "v1alpha1"}
+
+// BackendConfigurationKind is the kind of the backend component config.
+const BackendConfigurationKind = "BackendConfiguration"
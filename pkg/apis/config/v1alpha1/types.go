@@ -0,0 +1,103 @@
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	kubebindv1alpha1 "github.com/kube-bind/kube-bind/pkg/apis/kubebind/v1alpha1"
+)
+
+// BackendConfiguration configures the kube-bind controllers and the provider endpoints.
+type BackendConfiguration struct {
+	metav1.TypeMeta `json:",inline"`
+
+	// Workers are the worker counts of the kube-bind controllers.
+	Workers WorkersConfiguration `json:"workers,omitempty"`
+
+	// InformerResync is the resync period of the shared informers.
+	InformerResync *metav1.Duration `json:"informerResync,omitempty"`
+
+	// ConsumerScope is the informer scope allowed to consumers. With Namespaced, consumers
+	// can only bind namespaced resources and their konnector watches the bound namespaces.
+	ConsumerScope kubebindv1alpha1.Scope `json:"consumerScope,omitempty"`
+
+	// ExportInformerScope is the informer scope of the APIServiceExports created for the
+	// export requests of the consumers.
+	ExportInformerScope kubebindv1alpha1.Scope `json:"exportInformerScope,omitempty"`
+
+	// NamespacePrefix is the prefix of the namespaces created for each consumer.
+	NamespacePrefix string `json:"namespacePrefix,omitempty"`
+
+	// ConsumerGracePeriod is how long the rancher users, bindings, tokens and the project of a
+	// consumer are kept after its ClusterBinding or APIServiceNamespace is deleted. Zero removes
+	// them right away.
+	ConsumerGracePeriod *metav1.Duration `json:"consumerGracePeriod,omitempty"`
+
+	// Heartbeat configures the monitoring of the konnector heartbeats of the consumers.
+	Heartbeat HeartbeatConfiguration `json:"heartbeat,omitempty"`
+
+	// Projects configures the rancher projects the consumer service namespaces are placed into.
+	Projects ProjectsConfiguration `json:"projects,omitempty"`
+
+	// FleetWorkspaces makes each consumer service namespace a fleet workspace, so rancher
+	// provisions the clusters created through kube-bind.
+	FleetWorkspaces bool `json:"fleetWorkspaces,omitempty"`
+
+	// ClientConnection configures the rate limits of all the clients talking to the kube-apiserver.
+	ClientConnection ClientConnectionConfiguration `json:"clientConnection,omitempty"`
+}
+
+// WorkersConfiguration are the worker counts of the kube-bind controllers.
+type WorkersConfiguration struct {
+	// APIServiceNamespace is the worker count of the APIServiceNamespace controller.
+	APIServiceNamespace *int `json:"apiServiceNamespace,omitempty"`
+
+	// APIServiceExport is the worker count of the APIServiceExport controller.
+	APIServiceExport *int `json:"apiServiceExport,omitempty"`
+
+	// APIServiceExportRequest is the worker count of the APIServiceExportRequest controller.
+	APIServiceExportRequest *int `json:"apiServiceExportRequest,omitempty"`
+}
+
+// HeartbeatConfiguration configures the monitoring of the konnector heartbeats.
+type HeartbeatConfiguration struct {
+	// StaleAfter is how long a ClusterBinding can go without a heartbeat before it is marked stale.
+	StaleAfter *metav1.Duration `json:"staleAfter,omitempty"`
+
+	// SuspendAfter is how long a ClusterBinding can go without a heartbeat before the rancher
+	// users of the consumer are disabled. They are enabled again with the next heartbeat.
+	// Zero never suspends consumers.
+	SuspendAfter metav1.Duration `json:"suspendAfter,omitempty"`
+}
+
+// ProjectMode selects the rancher projects of the consumer service namespaces.
+type ProjectMode string
+
+const (
+	// ProjectModeNone keeps the service namespaces outside of rancher projects.
+	ProjectModeNone ProjectMode = "None"
+
+	// ProjectModePerConsumer creates a rancher project for each consumer, holding all its
+	// service namespaces.
+	ProjectModePerConsumer ProjectMode = "PerConsumer"
+
+	// ProjectModeShared places the service namespaces of all consumers into one project.
+	ProjectModeShared ProjectMode = "Shared"
+)
+
+// ProjectsConfiguration configures the rancher projects of the consumer service namespaces.
+type ProjectsConfiguration struct {
+	// Mode is None, PerConsumer or Shared.
+	Mode ProjectMode `json:"mode,omitempty"`
+
+	// ProjectID is the local:<project> ID of the project used by the Shared mode.
+	ProjectID string `json:"projectID,omitempty"`
+}
+
+// ClientConnectionConfiguration configures the kube-apiserver client rate limits.
+type ClientConnectionConfiguration struct {
+	// QPS is the sustained queries per second allowed to the kube-apiserver.
+	QPS *float32 `json:"qps,omitempty"`
+
+	// Burst is the query burst allowed to the kube-apiserver.
+	Burst *int `json:"burst,omitempty"`
+}
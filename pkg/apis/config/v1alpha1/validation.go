@@ -0,0 +1,97 @@
+package v1alpha1
+
+import (
+	"strings"
+	"time"
+
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+
+	kubebindv1alpha1 "github.com/kube-bind/kube-bind/pkg/apis/kubebind/v1alpha1"
+)
+
+// Validate returns the errors of a defaulted config.
+func Validate(c *BackendConfiguration) field.ErrorList {
+	errs := field.ErrorList{}
+
+	if c.APIVersion != GroupVersion.String() {
+		errs = append(errs, field.NotSupported(field.NewPath("apiVersion"), c.APIVersion, []string{GroupVersion.String()}))
+	}
+	if c.Kind != BackendConfigurationKind {
+		errs = append(errs, field.NotSupported(field.NewPath("kind"), c.Kind, []string{BackendConfigurationKind}))
+	}
+
+	workersPath := field.NewPath("workers")
+	for _, workers := range []struct {
+		name  string
+		count *int
+	}{
+		{"apiServiceNamespace", c.Workers.APIServiceNamespace},
+		{"apiServiceExport", c.Workers.APIServiceExport},
+		{"apiServiceExportRequest", c.Workers.APIServiceExportRequest},
+	} {
+		if *workers.count < 1 {
+			errs = append(errs, field.Invalid(workersPath.Child(workers.name), *workers.count, "must be at least 1"))
+		}
+	}
+
+	if c.InformerResync.Duration < time.Minute {
+		errs = append(errs, field.Invalid(field.NewPath("informerResync"), c.InformerResync.Duration.String(), "must be at least 1m"))
+	}
+
+	for _, scope := range []struct {
+		name  string
+		scope kubebindv1alpha1.Scope
+	}{
+		{"consumerScope", c.ConsumerScope},
+		{"exportInformerScope", c.ExportInformerScope},
+	} {
+		if scope.scope != kubebindv1alpha1.ClusterScope && scope.scope != kubebindv1alpha1.NamespacedScope {
+			errs = append(errs, field.NotSupported(field.NewPath(scope.name), scope.scope,
+				[]string{string(kubebindv1alpha1.ClusterScope), string(kubebindv1alpha1.NamespacedScope)}))
+		}
+	}
+
+	// The consumer namespaces are generated from the prefix.
+	for _, msg := range validation.IsDNS1123Label(c.NamespacePrefix + "x") {
+		errs = append(errs, field.Invalid(field.NewPath("namespacePrefix"), c.NamespacePrefix, msg))
+	}
+
+	if c.ConsumerGracePeriod.Duration < 0 {
+		errs = append(errs, field.Invalid(field.NewPath("consumerGracePeriod"), c.ConsumerGracePeriod.Duration.String(), "must not be negative"))
+	}
+
+	heartbeatPath := field.NewPath("heartbeat")
+	if c.Heartbeat.StaleAfter.Duration < time.Minute {
+		errs = append(errs, field.Invalid(heartbeatPath.Child("staleAfter"), c.Heartbeat.StaleAfter.Duration.String(), "must be at least 1m"))
+	}
+	if c.Heartbeat.SuspendAfter.Duration != 0 && c.Heartbeat.SuspendAfter.Duration <= c.Heartbeat.StaleAfter.Duration {
+		errs = append(errs, field.Invalid(heartbeatPath.Child("suspendAfter"), c.Heartbeat.SuspendAfter.Duration.String(), "must be zero or longer than staleAfter"))
+	}
+
+	projectsPath := field.NewPath("projects")
+	switch c.Projects.Mode {
+	case ProjectModeShared:
+		// The service namespaces live on the local cluster, so does the project.
+		if cluster, name, ok := strings.Cut(c.Projects.ProjectID, ":"); !ok || cluster != "local" || name == "" {
+			errs = append(errs, field.Invalid(projectsPath.Child("projectID"), c.Projects.ProjectID, "must be local:<project>"))
+		}
+	case ProjectModeNone, ProjectModePerConsumer:
+		if c.Projects.ProjectID != "" {
+			errs = append(errs, field.Invalid(projectsPath.Child("projectID"), c.Projects.ProjectID, "must only be set with the Shared mode"))
+		}
+	default:
+		errs = append(errs, field.NotSupported(projectsPath.Child("mode"), c.Projects.Mode,
+			[]string{string(ProjectModeNone), string(ProjectModePerConsumer), string(ProjectModeShared)}))
+	}
+
+	clientPath := field.NewPath("clientConnection")
+	if *c.ClientConnection.QPS <= 0 {
+		errs = append(errs, field.Invalid(clientPath.Child("qps"), *c.ClientConnection.QPS, "must be positive"))
+	}
+	if *c.ClientConnection.Burst < 1 {
+		errs = append(errs, field.Invalid(clientPath.Child("burst"), *c.ClientConnection.Burst, "must be at least 1"))
+	}
+
+	return errs
+}
@@ -1,10 +1,38 @@
 package apis
 
 type Login struct {
-	Username string `json:"username"`
-	Password string `json:"password"`
+	Username     string `json:"username"`
+	Password     string `json:"password"`
+	ResponseType string `json:"responseType,omitempty"`
 }
 
 type LoginResponse struct {
-	Token string `json:"token"`
+	Token     string `json:"token"`
+	UserID    string `json:"userId,omitempty"`
+	ExpiresAt string `json:"expiresAt,omitempty"`
+}
+
+// AuthToken is the token handed over by rancher to the CLI after a browser login.
+type AuthToken struct {
+	ID        string `json:"id,omitempty"`
+	Token     string `json:"token,omitempty"`
+	ExpiresAt string `json:"expiresAt,omitempty"`
+}
+
+// User is the rancher user representation returned by the v3 API.
+type User struct {
+	ID       string `json:"id"`
+	Username string `json:"username,omitempty"`
+}
+
+// UserCollection is the rancher v3 API users list.
+type UserCollection struct {
+	Data []User `json:"data"`
+}
+
+// ErrorResponse is returned by rancher API on a failed request.
+type ErrorResponse struct {
+	Code    string `json:"code,omitempty"`
+	Message string `json:"message,omitempty"`
+	Status  int    `json:"status,omitempty"`
 }
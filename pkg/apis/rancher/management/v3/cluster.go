@@ -0,0 +1,45 @@
+package v3
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// Cluster is a rancher managed cluster
+// +genclient
+// +genclient:nonNamespaced
+// +kubebuilder:object:root=true
+
+type Cluster struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec ClusterSpec `json:"spec"`
+}
+
+// ClusterSpec contains the subset of the rancher cluster spec used by rancher-bind.
+type ClusterSpec struct {
+	DisplayName              string                   `json:"displayName"`
+	LocalClusterAuthEndpoint LocalClusterAuthEndpoint `json:"localClusterAuthEndpoint,omitempty"`
+}
+
+// LocalClusterAuthEndpoint is the Authorized Cluster Endpoint configuration,
+// allowing direct access to the downstream cluster API server.
+type LocalClusterAuthEndpoint struct {
+	Enabled bool   `json:"enabled"`
+	FQDN    string `json:"fqdn,omitempty"`
+	CACerts string `json:"caCerts,omitempty"`
+}
+
+// ClusterList contains a list of Clusters.
+// +kubebuilder:object:root=true
+
+type ClusterList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+
+	Items []Cluster `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&Cluster{}, &ClusterList{})
+}
@@ -0,0 +1,33 @@
+package v3
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// FleetWorkspace makes the namespace of the same name a fleet workspace. Rancher provisions
+// the clusters of fleet workspace namespaces only.
+// +kubebuilder:object:root=true
+
+type FleetWorkspace struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Status FleetWorkspaceStatus `json:"status,omitempty"`
+}
+
+// FleetWorkspaceStatus is empty upstream, kept for API compatibility.
+type FleetWorkspaceStatus struct{}
+
+// FleetWorkspaceList contains a list of FleetWorkspaces.
+// +kubebuilder:object:root=true
+
+type FleetWorkspaceList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+
+	Items []FleetWorkspace `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&FleetWorkspace{}, &FleetWorkspaceList{})
+}
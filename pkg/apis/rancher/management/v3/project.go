@@ -0,0 +1,51 @@
+package v3
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	// LocalClusterName is the name of the rancher cluster running rancher, and the namespace
+	// of its Projects.
+	LocalClusterName = "local"
+
+	// ProjectIDAnnotation places a namespace into the <cluster>:<project> rancher project.
+	// Rancher labels the namespace with the project name in turn.
+	ProjectIDAnnotation = "field.cattle.io/projectId"
+)
+
+// Project groups namespaces of a rancher cluster, sharing RBAC and resource quotas.
+// +kubebuilder:object:root=true
+
+type Project struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec ProjectSpec `json:"spec,omitempty"`
+}
+
+// ProjectSpec contains the subset of the rancher project spec used by rancher-bind.
+type ProjectSpec struct {
+	DisplayName string `json:"displayName"`
+	Description string `json:"description,omitempty"`
+	ClusterName string `json:"clusterName"`
+}
+
+// ProjectList contains a list of Projects.
+// +kubebuilder:object:root=true
+
+type ProjectList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+
+	Items []Project `json:"items"`
+}
+
+// ID returns the <cluster>:<project> ID of the project, as set on its namespaces.
+func (p *Project) ID() string {
+	return p.Spec.ClusterName + ":" + p.Name
+}
+
+func init() {
+	SchemeBuilder.Register(&Project{}, &ProjectList{})
+}
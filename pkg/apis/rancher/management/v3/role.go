@@ -6,13 +6,37 @@ import (
 )
 
 // GlobalRole is a ClusterRole approximation used within rancher ecosystem
+// +genclient
+// +genclient:nonNamespaced
+// +genclient:noStatus
 // +kubebuilder:object:root=true
 
 type GlobalRole struct {
 	metav1.TypeMeta   `json:",inline"`
 	metav1.ObjectMeta `json:"metadata,omitempty"`
 
-	Rules []rbacv1.PolicyRule `json:"rules,omitempty"`
+	DisplayName string              `json:"displayName,omitempty" norman:"required"`
+	Description string              `json:"description"`
+	Rules       []rbacv1.PolicyRule `json:"rules,omitempty"`
+
+	// NewUserDefault makes the role bound to every newly created user.
+	NewUserDefault bool `json:"newUserDefault,omitempty" norman:"required"`
+	Builtin        bool `json:"builtin" norman:"nocreate,noupdate"`
+
+	// InheritedClusterRoles are the RoleTemplates bound in every downstream cluster, since rancher 2.8.
+	InheritedClusterRoles []string `json:"inheritedClusterRoles,omitempty"`
+	// NamespacedRules are the rules granted in the namespace of the map key, since rancher 2.8.
+	NamespacedRules map[string][]rbacv1.PolicyRule `json:"namespacedRules,omitempty"`
+
+	Status GlobalRoleStatus `json:"status,omitempty"`
+}
+
+// GlobalRoleStatus is the GlobalRole status reported by rancher.
+type GlobalRoleStatus struct {
+	ObservedGeneration int64              `json:"observedGeneration,omitempty"`
+	LastUpdate         string             `json:"lastUpdateTime,omitempty"`
+	Summary            string             `json:"summary,omitempty"`
+	Conditions         []metav1.Condition `json:"conditions,omitempty"`
 }
 
 // GlobalRoleList contains a list of GlobalRoles.
@@ -25,15 +49,18 @@ type GlobalRoleList struct {
 	Items []GlobalRole `json:"items"`
 }
 
-// GlobalRoleBinding specifies a global role binging to the user.
+// GlobalRoleBinding specifies a global role binging to the user or a group principal.
+// +genclient
+// +genclient:nonNamespaced
 // +kubebuilder:object:root=true
 
 type GlobalRoleBinding struct {
 	metav1.TypeMeta   `json:",inline"`
 	metav1.ObjectMeta `json:"metadata,omitempty"`
 
-	UserName       string `json:"userName,omitempty"`
-	GlobalRoleName string `json:"globalRoleName,omitempty"`
+	UserName           string `json:"userName,omitempty"`
+	GroupPrincipalName string `json:"groupPrincipalName,omitempty"`
+	GlobalRoleName     string `json:"globalRoleName,omitempty"`
 }
 
 // GlobalRoleBindingList contains a list of GlobalRoleBingins.
@@ -5,13 +5,16 @@ import (
 )
 
 // Setting is a rancher setting value
+// +genclient
+// +genclient:nonNamespaced
 // +kubebuilder:object:root=true
 
 type Setting struct {
 	metav1.TypeMeta   `json:",inline"`
 	metav1.ObjectMeta `json:"metadata,omitempty"`
 
-	Value string `json:"value"`
+	Value   string `json:"value"`
+	Default string `json:"default,omitempty"`
 }
 
 // SettingList contains a list of Settings.
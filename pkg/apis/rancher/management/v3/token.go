@@ -0,0 +1,40 @@
+package v3
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// TokenUserIDLabel is set by rancher on the tokens to the name of their user.
+const TokenUserIDLabel = "authn.management.cattle.io/token-userId"
+
+// Token is a rancher API token. Deleting it logs the session out.
+// +kubebuilder:object:root=true
+
+type Token struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	UserID       string       `json:"userId"`
+	AuthProvider string       `json:"authProvider,omitempty"`
+	Description  string       `json:"description,omitempty"`
+	ClusterName  string       `json:"clusterName,omitempty"`
+	TTLMillis    int64        `json:"ttl,omitempty"`
+	ExpiresAt    string       `json:"expiresAt,omitempty"`
+	Expired      bool         `json:"expired,omitempty"`
+	Enabled      *bool        `json:"enabled,omitempty"`
+	LastUsedAt   *metav1.Time `json:"lastUsedAt,omitempty"`
+}
+
+// TokenList contains a list of Tokens.
+// +kubebuilder:object:root=true
+
+type TokenList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+
+	Items []Token `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&Token{}, &TokenList{})
+}
@@ -1,17 +1,54 @@
 package v3
 
 import (
+	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
+// User is a rancher user, authenticating with a local password or an external principal.
+// +genclient
+// +genclient:nonNamespaced
+// +genclient:noStatus
 // +kubebuilder:object:root=true
 
 type User struct {
 	metav1.TypeMeta   `json:",inline"`
 	metav1.ObjectMeta `json:"metadata,omitempty"`
 
-	Username string `json:"username,omitempty"`
-	Password string `json:"password,omitempty" norman:"writeOnly,noupdate"`
+	DisplayName        string     `json:"displayName,omitempty"`
+	Description        string     `json:"description"`
+	Username           string     `json:"username,omitempty"`
+	Password           string     `json:"password,omitempty" norman:"writeOnly,noupdate"`
+	MustChangePassword bool       `json:"mustChangePassword,omitempty"`
+	PrincipalIDs       []string   `json:"principalIds,omitempty" norman:"type=array[reference[principal]]"`
+	Me                 bool       `json:"me,omitempty" norman:"nocreate,noupdate"`
+	Enabled            *bool      `json:"enabled,omitempty" norman:"default=true"`
+	Spec               UserSpec   `json:"spec,omitempty"`
+	Status             UserStatus `json:"status,omitempty"`
+}
+
+// UserSpec is empty upstream, kept for API compatibility.
+type UserSpec struct{}
+
+// UserStatus is the User status reported by rancher.
+type UserStatus struct {
+	Conditions []UserCondition `json:"conditions,omitempty"`
+}
+
+// UserCondition is a condition of the User status.
+type UserCondition struct {
+	// Type of user condition.
+	Type string `json:"type"`
+	// Status of the condition, one of True, False, Unknown.
+	Status corev1.ConditionStatus `json:"status"`
+	// The last time this condition was updated.
+	LastUpdateTime string `json:"lastUpdateTime,omitempty"`
+	// Last time the condition transitioned from one status to another.
+	LastTransitionTime string `json:"lastTransitionTime,omitempty"`
+	// The reason for the condition's last transition.
+	Reason string `json:"reason,omitempty"`
+	// Human-readable message indicating details about last transition
+	Message string `json:"message,omitempty"`
 }
 
 // UserList contains a list of Users.
@@ -0,0 +1,110 @@
+package v1
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	// DefaultNamespace is the fleet workspace rancher provisions downstream clusters in.
+	DefaultNamespace = "fleet-default"
+
+	// ClusterConditionReady is the condition set once the cluster is provisioned and connected.
+	ClusterConditionReady = "Ready"
+	// ClusterConditionProvisioned is the condition set once the cluster infrastructure is provisioned.
+	ClusterConditionProvisioned = "Provisioned"
+)
+
+// Cluster is a rancher provisioned or imported downstream cluster
+// +kubebuilder:object:root=true
+
+type Cluster struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   ClusterSpec   `json:"spec"`
+	Status ClusterStatus `json:"status,omitempty"`
+}
+
+// ClusterSpec contains the subset of the rancher provisioning cluster spec used by rancher-bind.
+type ClusterSpec struct {
+	CloudCredentialSecretName           string                   `json:"cloudCredentialSecretName,omitempty"`
+	KubernetesVersion                   string                   `json:"kubernetesVersion,omitempty"`
+	LocalClusterAuthEndpoint            LocalClusterAuthEndpoint `json:"localClusterAuthEndpoint,omitempty"`
+	AgentEnvVars                        []EnvVar                 `json:"agentEnvVars,omitempty"`
+	DefaultClusterRoleForProjectMembers string                   `json:"defaultClusterRoleForProjectMembers,omitempty"`
+	EnableNetworkPolicy                 *bool                    `json:"enableNetworkPolicy,omitempty"`
+}
+
+// LocalClusterAuthEndpoint is the Authorized Cluster Endpoint configuration,
+// allowing direct access to the downstream cluster API server.
+type LocalClusterAuthEndpoint struct {
+	Enabled bool   `json:"enabled,omitempty"`
+	FQDN    string `json:"fqdn,omitempty"`
+	CACerts string `json:"caCerts,omitempty"`
+}
+
+// EnvVar is an environment variable set on the cluster agent.
+type EnvVar struct {
+	Name  string `json:"name,omitempty"`
+	Value string `json:"value,omitempty"`
+}
+
+// ClusterStatus is the Cluster status reported by rancher.
+type ClusterStatus struct {
+	Ready              bool   `json:"ready,omitempty"`
+	ClusterName        string `json:"clusterName,omitempty"`
+	FleetWorkspaceName string `json:"fleetWorkspaceName,omitempty"`
+	ClientSecretName   string `json:"clientSecretName,omitempty"`
+	AgentDeployed      bool   `json:"agentDeployed,omitempty"`
+	ObservedGeneration int64  `json:"observedGeneration"`
+
+	Conditions []ClusterCondition `json:"conditions,omitempty"`
+}
+
+// ClusterCondition is a condition of the Cluster status.
+type ClusterCondition struct {
+	// Type of cluster condition.
+	Type string `json:"type"`
+	// Status of the condition, one of True, False, Unknown.
+	Status corev1.ConditionStatus `json:"status"`
+	// The last time this condition was updated.
+	LastUpdateTime string `json:"lastUpdateTime,omitempty"`
+	// Last time the condition transitioned from one status to another.
+	LastTransitionTime string `json:"lastTransitionTime,omitempty"`
+	// The reason for the condition's last transition.
+	Reason string `json:"reason,omitempty"`
+	// Human-readable message indicating details about last transition
+	Message string `json:"message,omitempty"`
+}
+
+// ClusterList contains a list of Clusters.
+// +kubebuilder:object:root=true
+
+type ClusterList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+
+	Items []Cluster `json:"items"`
+}
+
+// GetCondition returns the condition of the given type, or nil when it is not reported.
+func (c *Cluster) GetCondition(conditionType string) *ClusterCondition {
+	for i := range c.Status.Conditions {
+		if c.Status.Conditions[i].Type == conditionType {
+			return &c.Status.Conditions[i]
+		}
+	}
+
+	return nil
+}
+
+// IsReady returns true once the cluster is provisioned and its agent is connected.
+func (c *Cluster) IsReady() bool {
+	condition := c.GetCondition(ClusterConditionReady)
+	return c.Status.Ready && (condition == nil || condition.Status == corev1.ConditionTrue)
+}
+
+func init() {
+	SchemeBuilder.Register(&Cluster{}, &ClusterList{})
+}
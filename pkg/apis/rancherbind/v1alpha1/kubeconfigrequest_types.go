@@ -0,0 +1,127 @@
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	// RolePresetLabel marks the GlobalRoles consumers can request by the label value.
+	RolePresetLabel = "rancher.kube-bind.io/preset"
+
+	// PresetClustersAnnotation lists the comma separated rancher cluster IDs or provisioning
+	// cluster names a role preset may be requested for. "*" grants all the clusters but the
+	// local one, which has to be listed explicitly. Presets without it grant no cluster.
+	PresetClustersAnnotation = "rancher.kube-bind.io/preset-clusters"
+
+	// KubeconfigRequestNameLabel links the RancherBind and the kubeconfig Secret to their KubeconfigRequest.
+	KubeconfigRequestNameLabel = "rancher.kube-bind.io/kubeconfigrequest-name"
+
+	// KubeconfigRequestSecretPrefix prefixes the kubeconfig Secrets of the requests in the
+	// provider namespace, so consumers can not name other Secrets.
+	KubeconfigRequestSecretPrefix = "kubeconfigrequest-"
+)
+
+// KubeconfigRequestPhase is the outcome of a KubeconfigRequest.
+type KubeconfigRequestPhase string
+
+const (
+	// KubeconfigRequestPending is set while the kubeconfig is being issued.
+	KubeconfigRequestPending KubeconfigRequestPhase = "Pending"
+	// KubeconfigRequestIssued is set once the kubeconfig Secret is written.
+	KubeconfigRequestIssued KubeconfigRequestPhase = "Issued"
+	// KubeconfigRequestFailed is set when the request can not be fulfilled.
+	KubeconfigRequestFailed KubeconfigRequestPhase = "Failed"
+)
+
+// KubeconfigRequestSpec defines the kubeconfig requested by a consumer.
+type KubeconfigRequestSpec struct {
+	// Cluster is the rancher cluster ID or provisioning cluster name the kubeconfig gives access to.
+	// It has to be granted by the role preset.
+	// +kubebuilder:validation:MinLength=1
+	Cluster string `json:"cluster"`
+
+	// Role is the preset of the access, matching the rancher.kube-bind.io/preset label
+	// of a GlobalRole offered by the provider.
+	// +kubebuilder:validation:MinLength=1
+	Role string `json:"role"`
+
+	// TokenTTL is the lifetime of the issued token. The token is renewed before it
+	// expires. Defaults to the rancher token TTL.
+	// +optional
+	TokenTTL *metav1.Duration `json:"tokenTTL,omitempty"`
+
+	// SecretName is the name of the Secret the kubeconfig is written to in the provider
+	// namespace, prefixed with kubeconfigrequest-. Defaults to <name>-kubeconfig.
+	// +optional
+	SecretName string `json:"secretName,omitempty"`
+}
+
+// KubeconfigRequestStatus defines the observed state of KubeconfigRequest.
+type KubeconfigRequestStatus struct {
+	// Phase is the outcome of the request.
+	// +optional
+	// +kubebuilder:validation:Enum=Pending;Issued;Failed
+	Phase KubeconfigRequestPhase `json:"phase,omitempty"`
+
+	// ObservedGeneration is the last reconciled generation.
+	// +optional
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+
+	// SecretName is the name of the Secret holding the kubeconfig.
+	// +optional
+	SecretName string `json:"secretName,omitempty"`
+
+	// ClusterID is the resolved rancher cluster ID in the kubeconfig.
+	// +optional
+	ClusterID string `json:"clusterID,omitempty"`
+
+	// TokenExpiresAt is the time the issued token expires at.
+	// +optional
+	TokenExpiresAt *metav1.Time `json:"tokenExpiresAt,omitempty"`
+
+	// Conditions describe the state of the KubeconfigRequest.
+	// +optional
+	// +listType=map
+	// +listMapKey=type
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+}
+
+// KubeconfigRequest asks the provider for a rancher kubeconfig of a downstream cluster.
+// +kubebuilder:object:root=true
+// +kubebuilder:subresource:status
+// +kubebuilder:printcolumn:name="Cluster",type="string",JSONPath=`.spec.cluster`
+// +kubebuilder:printcolumn:name="Role",type="string",JSONPath=`.spec.role`
+// +kubebuilder:printcolumn:name="Phase",type="string",JSONPath=`.status.phase`
+// +kubebuilder:printcolumn:name="Secret",type="string",JSONPath=`.status.secretName`
+// +kubebuilder:printcolumn:name="Age",type="date",JSONPath=`.metadata.creationTimestamp`
+
+type KubeconfigRequest struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   KubeconfigRequestSpec   `json:"spec,omitempty"`
+	Status KubeconfigRequestStatus `json:"status,omitempty"`
+}
+
+// KubeconfigRequestList contains a list of KubeconfigRequests.
+// +kubebuilder:object:root=true
+
+type KubeconfigRequestList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+
+	Items []KubeconfigRequest `json:"items"`
+}
+
+// GetSecretName returns the name of the kubeconfig Secret in the provider namespace.
+func (r *KubeconfigRequest) GetSecretName() string {
+	if r.Spec.SecretName != "" {
+		return KubeconfigRequestSecretPrefix + r.Spec.SecretName
+	}
+
+	return KubeconfigRequestSecretPrefix + r.Name + "-kubeconfig"
+}
+
+func init() {
+	SchemeBuilder.Register(&KubeconfigRequest{}, &KubeconfigRequestList{})
+}
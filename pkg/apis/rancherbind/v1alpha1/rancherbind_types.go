@@ -0,0 +1,167 @@
+package v1alpha1
+
+import (
+	rbacv1 "k8s.io/api/rbac/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	// RancherBindFinalizer guards the removal of the rancher objects created for a RancherBind.
+	RancherBindFinalizer = "rancher.kube-bind.io/finalizer"
+
+	// RancherBindNameLabel and RancherBindNamespaceLabel link the rancher objects to their RancherBind.
+	RancherBindNameLabel      = "rancher.kube-bind.io/rancherbind-name"
+	RancherBindNamespaceLabel = "rancher.kube-bind.io/rancherbind-namespace"
+
+	// ConsumerNamespaceAnnotation is set on the rancher objects of a RancherBind synced from a
+	// consumer to the kube-bind namespace of the consumer, holding its ClusterBinding.
+	ConsumerNamespaceAnnotation = "rancher.kube-bind.io/consumer-namespace"
+
+	// APIServiceNamespaceAnnotation is set on the rancher objects of a RancherBind synced from
+	// a consumer to the <namespace>/<name> of the APIServiceNamespace it was synced through.
+	APIServiceNamespaceAnnotation = "rancher.kube-bind.io/apiservicenamespace"
+
+	// ConsumerGoneAnnotation is set on the rancher user and the consumer project to the time
+	// their consumer was found deleted. They are removed once the grace period passed.
+	ConsumerGoneAnnotation = "rancher.kube-bind.io/consumer-gone-since"
+
+	// SuspendedAnnotation is set on the rancher users disabled because the konnector of their
+	// consumer stopped sending heartbeats, to the time they were disabled.
+	SuspendedAnnotation = "rancher.kube-bind.io/suspended-since"
+
+	// KubeconfigSecretKey is the Secret key holding the generated kubeconfig.
+	KubeconfigSecretKey = "kubeconfig"
+)
+
+// Condition types reported on the RancherBind status.
+const (
+	// UserReadyCondition is true once the consumer user exists.
+	UserReadyCondition = "UserReady"
+	// RolesBoundCondition is true once every role is bound to the consumer user.
+	RolesBoundCondition = "RolesBound"
+	// KubeconfigReadyCondition is true once the kubeconfig Secret holds valid tokens.
+	KubeconfigReadyCondition = "KubeconfigReady"
+	// ReadyCondition summarizes the other conditions.
+	ReadyCondition = "Ready"
+)
+
+// RancherBindSpec defines the desired access of a consumer to rancher.
+type RancherBindSpec struct {
+	// Consumer is the name of the consumer a rancher user is created for.
+	// +kubebuilder:validation:MinLength=1
+	// +kubebuilder:validation:XValidation:rule="self == oldSelf",message="consumer is immutable"
+	Consumer string `json:"consumer"`
+
+	// Roles are bound to the consumer user.
+	// +kubebuilder:validation:MinItems=1
+	Roles []RoleRef `json:"roles"`
+
+	// Clusters are the rancher cluster IDs or provisioning cluster names the kubeconfig
+	// gives access to. Defaults to the local cluster.
+	// +optional
+	Clusters []string `json:"clusters,omitempty"`
+
+	// TokenTTL is the lifetime of the issued tokens. The tokens are renewed before they
+	// expire. Defaults to the rancher token TTL.
+	// +optional
+	TokenTTL *metav1.Duration `json:"tokenTTL,omitempty"`
+
+	// SecretName is the name of the Secret the kubeconfig is written to.
+	// Defaults to <name>-kubeconfig.
+	// +optional
+	SecretName string `json:"secretName,omitempty"`
+}
+
+// RoleRef references an existing GlobalRole, or declares the rules of a GlobalRole
+// created for the consumer.
+type RoleRef struct {
+	// Name of an existing GlobalRole.
+	// +optional
+	Name string `json:"name,omitempty"`
+
+	// Rules of a GlobalRole created for the consumer.
+	// +optional
+	Rules []rbacv1.PolicyRule `json:"rules,omitempty"`
+}
+
+// RancherBindStatus defines the observed state of RancherBind.
+type RancherBindStatus struct {
+	// ObservedGeneration is the last reconciled generation.
+	// +optional
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+
+	// UserName is the name of the rancher user created for the consumer.
+	// +optional
+	UserName string `json:"userName,omitempty"`
+
+	// SecretName is the name of the Secret holding the kubeconfig.
+	// +optional
+	SecretName string `json:"secretName,omitempty"`
+
+	// Clusters are the resolved rancher cluster IDs in the kubeconfig.
+	// +optional
+	Clusters []string `json:"clusters,omitempty"`
+
+	// TokenExpiresAt is the time the issued tokens expire at.
+	// +optional
+	TokenExpiresAt *metav1.Time `json:"tokenExpiresAt,omitempty"`
+
+	// TokenNames are the rancher Tokens issued for the kubeconfig, revoked once renewed.
+	// Replaced tokens which could not be revoked are kept until the next renewal.
+	// +optional
+	TokenNames []string `json:"tokenNames,omitempty"`
+
+	// Conditions describe the state of the RancherBind.
+	// +optional
+	// +listType=map
+	// +listMapKey=type
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+}
+
+// RancherBind declares the rancher access of a consumer, issued as a kubeconfig Secret.
+// +kubebuilder:object:root=true
+// +kubebuilder:subresource:status
+// +kubebuilder:printcolumn:name="Consumer",type="string",JSONPath=`.spec.consumer`
+// +kubebuilder:printcolumn:name="Secret",type="string",JSONPath=`.status.secretName`
+// +kubebuilder:printcolumn:name="Ready",type="string",JSONPath=`.status.conditions[?(@.type=="Ready")].status`
+// +kubebuilder:printcolumn:name="Age",type="date",JSONPath=`.metadata.creationTimestamp`
+
+type RancherBind struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   RancherBindSpec   `json:"spec,omitempty"`
+	Status RancherBindStatus `json:"status,omitempty"`
+}
+
+// RancherBindList contains a list of RancherBinds.
+// +kubebuilder:object:root=true
+
+type RancherBindList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+
+	Items []RancherBind `json:"items"`
+}
+
+// GetSecretName returns the name of the kubeconfig Secret.
+func (r *RancherBind) GetSecretName() string {
+	if r.Spec.SecretName != "" {
+		return r.Spec.SecretName
+	}
+
+	return r.Name + "-kubeconfig"
+}
+
+// GetClusters returns the clusters the kubeconfig gives access to.
+func (r *RancherBind) GetClusters() []string {
+	if len(r.Spec.Clusters) == 0 {
+		return []string{"local"}
+	}
+
+	return r.Spec.Clusters
+}
+
+func init() {
+	SchemeBuilder.Register(&RancherBind{}, &RancherBindList{})
+}
@@ -0,0 +1,111 @@
+package v1alpha1
+
+import (
+	"slices"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// ConsumerNamespaceLabel is set on the service namespaces of the consumers to the consumer
+// namespace, selecting them for the policy webhook.
+const ConsumerNamespaceLabel = "rancher.kube-bind.io/consumer-namespace"
+
+// PolicyResource is a resource a policy applies to.
+type PolicyResource struct {
+	// Group is the API group of the resource.
+	// +kubebuilder:validation:MinLength=1
+	Group string `json:"group"`
+
+	// Resource is the plural name of the resource.
+	// +kubebuilder:validation:MinLength=1
+	Resource string `json:"resource"`
+}
+
+// AllowedValues restricts a field to a set of values.
+type AllowedValues struct {
+	// Path of the field, dot separated, e.g. spec.cloudCredentialSecretName. Lists on the
+	// path apply the rest of the path to each of their items.
+	// +kubebuilder:validation:MinLength=1
+	Path string `json:"path"`
+
+	// Values the field may have. Unset fields are allowed.
+	// +kubebuilder:validation:MinItems=1
+	Values []string `json:"values"`
+}
+
+// RequiredLabel is a label objects must have.
+type RequiredLabel struct {
+	// Key of the label.
+	// +kubebuilder:validation:MinLength=1
+	Key string `json:"key"`
+
+	// Values the label may have. Any value is allowed when empty.
+	// +optional
+	Values []string `json:"values,omitempty"`
+}
+
+// RancherBindPolicySpec defines the constraints on the objects consumers write.
+type RancherBindPolicySpec struct {
+	// Resources the policy applies to.
+	// +kubebuilder:validation:MinItems=1
+	Resources []PolicyResource `json:"resources"`
+
+	// Consumers are the kube-bind namespaces of the consumers the policy applies to. Applies
+	// to all consumers when empty.
+	// +optional
+	Consumers []string `json:"consumers,omitempty"`
+
+	// AllowedValues restrict fields to sets of values.
+	// +optional
+	AllowedValues []AllowedValues `json:"allowedValues,omitempty"`
+
+	// RequiredLabels are labels the objects must have.
+	// +optional
+	RequiredLabels []RequiredLabel `json:"requiredLabels,omitempty"`
+
+	// DeniedFields are the paths of fields the objects must not set, in the AllowedValues
+	// path syntax.
+	// +optional
+	DeniedFields []string `json:"deniedFields,omitempty"`
+}
+
+// RancherBindPolicy constrains the objects consumers write into the service namespaces
+// through kube-bind. Objects must satisfy every policy applying to them.
+// +kubebuilder:object:root=true
+// +kubebuilder:resource:scope=Cluster
+
+type RancherBindPolicy struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec RancherBindPolicySpec `json:"spec,omitempty"`
+}
+
+// RancherBindPolicyList contains a list of RancherBindPolicies.
+// +kubebuilder:object:root=true
+
+type RancherBindPolicyList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+
+	Items []RancherBindPolicy `json:"items"`
+}
+
+// AppliesTo returns true if the policy applies to the resource of the consumer.
+func (p *RancherBindPolicy) AppliesTo(group, resource, consumer string) bool {
+	if len(p.Spec.Consumers) > 0 && !slices.Contains(p.Spec.Consumers, consumer) {
+		return false
+	}
+
+	for _, r := range p.Spec.Resources {
+		if r.Group == group && r.Resource == resource {
+			return true
+		}
+	}
+
+	return false
+}
+
+func init() {
+	SchemeBuilder.Register(&RancherBindPolicy{}, &RancherBindPolicyList{})
+}
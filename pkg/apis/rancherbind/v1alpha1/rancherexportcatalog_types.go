@@ -0,0 +1,260 @@
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	// DefaultCatalogName is the name of the catalog created with the default resources.
+	DefaultCatalogName = "default"
+
+	// LegacyCatalogName is the name of the catalog maintained with the CRDs exported through
+	// the deprecated kube-bind.io/exported label or --exported-groups flag.
+	LegacyCatalogName = "legacy-exported"
+)
+
+const (
+	// BundleGroup is the pseudo API group consumers bind bundles with, naming the bundle as
+	// the resource.
+	BundleGroup = "bundles.rancher.kube-bind.io"
+
+	// BundleLabel is set on the APIServiceExports of a bundle to the bundle name.
+	BundleLabel = "rancher.kube-bind.io/bundle"
+
+	// BundleVersionLabel is set on the APIServiceExports of a bundle to the bundle version.
+	BundleVersionLabel = "rancher.kube-bind.io/bundle-version"
+)
+
+// DefaultCatalogResources are the rancher APIs offered by a fresh installation.
+var DefaultCatalogResources = []CatalogResource{
+	{
+		Group:       "provisioning.cattle.io",
+		Resource:    "clusters",
+		Description: "Downstream clusters provisioned and managed by rancher.",
+	},
+	{
+		Group:       "fleet.cattle.io",
+		Resource:    "gitrepos",
+		Description: "Fleet GitRepos deploying git repository contents to downstream clusters.",
+	},
+	{
+		Group:       "rke-machine-config.cattle.io",
+		Resource:    "amazonec2configs",
+		Description: "Amazon EC2 machine pool configuration of provisioned clusters.",
+	},
+	{
+		Group:       "rke-machine-config.cattle.io",
+		Resource:    "azureconfigs",
+		Description: "Azure machine pool configuration of provisioned clusters.",
+	},
+	{
+		Group:       "rke-machine-config.cattle.io",
+		Resource:    "digitaloceanconfigs",
+		Description: "DigitalOcean machine pool configuration of provisioned clusters.",
+	},
+	{
+		Group:       "rke-machine-config.cattle.io",
+		Resource:    "harvesterconfigs",
+		Description: "Harvester machine pool configuration of provisioned clusters.",
+	},
+	{
+		Group:       "rke-machine-config.cattle.io",
+		Resource:    "vmwarevsphereconfigs",
+		Description: "VMware vSphere machine pool configuration of provisioned clusters.",
+	},
+	{
+		Group:       GroupVersion.Group,
+		Resource:    "kubeconfigrequests",
+		Description: "Self-service rancher kubeconfigs for downstream clusters.",
+		PermissionClaims: []PermissionClaim{{
+			Resource: "secrets",
+			Selector: &metav1.LabelSelector{MatchExpressions: []metav1.LabelSelectorRequirement{{
+				Key:      KubeconfigRequestNameLabel,
+				Operator: metav1.LabelSelectorOpExists,
+			}}},
+		}},
+	},
+}
+
+// DefaultCatalogBundles are the bundles offered by a fresh installation.
+//
+// The cloud credentials referenced by the machine pools would need a permission claim on
+// Secrets, which kube-bind does not support yet. Consumers create them in rancher directly.
+var DefaultCatalogBundles = []CatalogBundle{
+	{
+		Name:        "rke2-clusters",
+		Version:     "v1",
+		Description: "Downstream RKE2 clusters together with the machine pool configuration they are provisioned with.",
+		Resources: []BundleResource{
+			{Group: "provisioning.cattle.io", Resource: "clusters"},
+			{Group: "rke-machine-config.cattle.io", Resource: "amazonec2configs"},
+			{Group: "rke-machine-config.cattle.io", Resource: "azureconfigs"},
+			{Group: "rke-machine-config.cattle.io", Resource: "digitaloceanconfigs"},
+			{Group: "rke-machine-config.cattle.io", Resource: "harvesterconfigs"},
+			{Group: "rke-machine-config.cattle.io", Resource: "vmwarevsphereconfigs"},
+		},
+	},
+}
+
+// CatalogResource is a resource offered to consumers.
+type CatalogResource struct {
+	// Group is the API group of the resource.
+	// +kubebuilder:validation:MinLength=1
+	Group string `json:"group"`
+
+	// Resource is the plural name of the resource.
+	// +kubebuilder:validation:MinLength=1
+	Resource string `json:"resource"`
+
+	// Description is shown to consumers choosing the resources to bind.
+	// +optional
+	Description string `json:"description,omitempty"`
+
+	// PermissionClaims are the provider objects the consumers of the resource need besides it,
+	// like the Secrets written for it.
+	// +optional
+	PermissionClaims []PermissionClaim `json:"permissionClaims,omitempty"`
+}
+
+// PermissionClaim claims the objects of a resource in the provider namespace of a consumer.
+// kube-bind v0.3.0 does not sync the claimed objects to the consumer yet.
+type PermissionClaim struct {
+	// Group is the API group of the claimed resource, empty for the core group.
+	// +optional
+	Group string `json:"group,omitempty"`
+
+	// Resource is the plural name of the claimed resource.
+	// +kubebuilder:validation:MinLength=1
+	Resource string `json:"resource"`
+
+	// Selector selects the claimed objects. All the objects are claimed if it is unset.
+	// +optional
+	Selector *metav1.LabelSelector `json:"selector,omitempty"`
+}
+
+// BundleResource is a resource exported as part of a bundle.
+type BundleResource struct {
+	// Group is the API group of the resource.
+	// +kubebuilder:validation:MinLength=1
+	Group string `json:"group"`
+
+	// Resource is the plural name of the resource.
+	// +kubebuilder:validation:MinLength=1
+	Resource string `json:"resource"`
+}
+
+// CatalogBundle is a set of resources that only make sense together, bound with a
+// single export request.
+type CatalogBundle struct {
+	// Name of the bundle, requested as a resource of the bundles.rancher.kube-bind.io group.
+	// +kubebuilder:validation:MinLength=1
+	Name string `json:"name"`
+
+	// Version of the bundle, set on the resulting APIServiceExports. Bump it when
+	// changing the bundle resources.
+	// +kubebuilder:validation:MinLength=1
+	Version string `json:"version"`
+
+	// Description is shown to consumers choosing the resources to bind.
+	// +optional
+	Description string `json:"description,omitempty"`
+
+	// Resources exported together by the bundle.
+	// +kubebuilder:validation:MinItems=1
+	Resources []BundleResource `json:"resources"`
+}
+
+// BundleParameters are the APIServiceExportRequest parameters of the requests for a bundle.
+type BundleParameters struct {
+	// Bundle is the name of the requested bundle.
+	Bundle string `json:"bundle"`
+
+	// Version is the version of the requested bundle.
+	Version string `json:"version"`
+}
+
+// RancherExportCatalogSpec lists the resources the provider offers.
+type RancherExportCatalogSpec struct {
+	// Resources offered to consumers. Export requests for other resources are rejected.
+	// +optional
+	Resources []CatalogResource `json:"resources,omitempty"`
+
+	// Bundles offered to consumers. The bundle resources are offered as part of the
+	// bundle only.
+	// +optional
+	Bundles []CatalogBundle `json:"bundles,omitempty"`
+}
+
+// RancherExportCatalog lists the rancher APIs consumers can bind. The offered resources
+// are the union of all catalogs.
+// +kubebuilder:object:root=true
+// +kubebuilder:resource:scope=Cluster
+
+type RancherExportCatalog struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec RancherExportCatalogSpec `json:"spec,omitempty"`
+}
+
+// RancherExportCatalogList contains a list of RancherExportCatalogs.
+// +kubebuilder:object:root=true
+
+type RancherExportCatalogList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+
+	Items []RancherExportCatalog `json:"items"`
+}
+
+// Find returns the catalog entry of the resource, or nil if it is not offered.
+func (c *RancherExportCatalog) Find(group, resource string) *CatalogResource {
+	for i := range c.Spec.Resources {
+		if c.Spec.Resources[i].Group == group && c.Spec.Resources[i].Resource == resource {
+			return &c.Spec.Resources[i]
+		}
+	}
+
+	return nil
+}
+
+// FindBundle returns the bundle of the name, or nil if it is not offered.
+func (c *RancherExportCatalog) FindBundle(name string) *CatalogBundle {
+	for i := range c.Spec.Bundles {
+		if c.Spec.Bundles[i].Name == name {
+			return &c.Spec.Bundles[i]
+		}
+	}
+
+	return nil
+}
+
+// Offers returns true if the resource is offered on its own or as part of a bundle.
+func (c *RancherExportCatalog) Offers(group, resource string) bool {
+	if c.Find(group, resource) != nil {
+		return true
+	}
+
+	for _, bundle := range c.Spec.Bundles {
+		if bundle.Contains(group, resource) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Contains returns true if the resource is part of the bundle.
+func (b *CatalogBundle) Contains(group, resource string) bool {
+	for _, r := range b.Resources {
+		if r.Group == group && r.Resource == resource {
+			return true
+		}
+	}
+
+	return false
+}
+
+func init() {
+	SchemeBuilder.Register(&RancherExportCatalog{}, &RancherExportCatalogList{})
+}
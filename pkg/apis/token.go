@@ -0,0 +1,9 @@
+package apis
+
+// TokenRequest creates a rancher API token, optionally scoped to a single cluster.
+type TokenRequest struct {
+	Type        string `json:"type"`
+	Description string `json:"description,omitempty"`
+	ClusterID   string `json:"clusterId,omitempty"`
+	TTL         int64  `json:"ttl,omitempty"`
+}
@@ -33,13 +33,41 @@ var (
 	bindAPIServiceExampleUses = `
 	# generate a kubeconfig to access rancher cluster using provided GlobalRole resource
 	%[1]s -f <global-role.yaml>
+
+	# generate a kubeconfig for an existing LDAP user, reading the password from stdin
+	echo "$PASSWORD" | %[1]s --username <user> --auth-provider openldap --password-stdin
+
+	# generate a kubeconfig for the current SAML or OIDC user, logging in in the browser
+	%[1]s --sso
+
+	# generate a kubeconfig refreshing the user token on demand, instead of embedding it
+	%[1]s --username <user> --exec-credential
+
+	# generate a kubeconfig for a downstream cluster, selected by its provisioning cluster name
+	%[1]s -f <global-role.yaml> --cluster <cluster-name> --cluster-namespace fleet-default
+	`
+
+	bindingsExampleUses = `
+	# bind the GlobalRole to a group principal of the identity provider
+	%[1]s -f <global-role.yaml> --group okta_group://platform
+
+	# list the group bindings
+	%[1]s bindings list --group okta_group://platform
+
+	# revoke a role from the group
+	%[1]s bindings revoke --group okta_group://platform --role <global-role>
+	`
+
+	credentialExampleUses = `
+	# print an ExecCredential with a token for the local cluster, obtained with the user password
+	%[1]s credential --server-url https://rancher.example.com --username <user>
 	`
 )
 
 func New(streams genericclioptions.IOStreams) (*cobra.Command, error) {
 	opts := plugin.NewRancherBindOptions(streams)
 	cmd := &cobra.Command{
-		Use:     "-f <file-with-a-GlobalRole>",
+		Use:     "rancher-bind -f <file-with-a-GlobalRole>",
 		Short:   "Generate a kubeconfig for a newly created user matching the provided role",
 		Example: fmt.Sprintf(bindAPIServiceExampleUses, "kubectl rancher-bind"),
 		RunE: func(cmd *cobra.Command, args []string) error {
@@ -63,5 +91,73 @@ func New(streams genericclioptions.IOStreams) (*cobra.Command, error) {
 	}
 	opts.AddCmdFlags(cmd)
 
+	cmd.AddCommand(NewCredential(streams))
+	cmd.AddCommand(NewBindings(streams))
+
 	return cmd, nil
 }
+
+func NewCredential(streams genericclioptions.IOStreams) *cobra.Command {
+	opts := plugin.NewCredentialOptions(streams)
+	cmd := &cobra.Command{
+		Use:     "credential",
+		Short:   "Print a client.authentication.k8s.io/v1 ExecCredential with a rancher token",
+		Example: fmt.Sprintf(credentialExampleUses, "kubectl rancher-bind"),
+		Args:    cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := opts.Validate(); err != nil {
+				return err
+			}
+
+			return opts.Run(cmd.Context())
+		},
+	}
+	opts.AddCmdFlags(cmd)
+
+	return cmd
+}
+
+func NewBindings(streams genericclioptions.IOStreams) *cobra.Command {
+	opts := plugin.NewBindingsOptions(streams)
+	cmd := &cobra.Command{
+		Use:     "bindings",
+		Short:   "Manage the GlobalRole bindings of group principals",
+		Example: fmt.Sprintf(bindingsExampleUses, "kubectl rancher-bind"),
+	}
+
+	list := &cobra.Command{
+		Use:   "list",
+		Short: "List the group bindings created by rancher-bind",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := opts.Complete(); err != nil {
+				return err
+			}
+
+			return opts.RunList(cmd.Context())
+		},
+	}
+	opts.AddCmdFlags(list)
+
+	revoke := &cobra.Command{
+		Use:   "revoke",
+		Short: "Remove the group bindings created by rancher-bind",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := opts.Complete(); err != nil {
+				return err
+			}
+
+			if err := opts.ValidateRevoke(); err != nil {
+				return err
+			}
+
+			return opts.RunRevoke(cmd.Context())
+		},
+	}
+	opts.AddCmdFlags(revoke)
+
+	cmd.AddCommand(list, revoke)
+
+	return cmd
+}
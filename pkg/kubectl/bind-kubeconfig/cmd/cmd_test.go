@@ -0,0 +1,49 @@
+/*
+Copyright 2023 SUSE.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"testing"
+
+	. "github.com/onsi/gomega"
+
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+)
+
+func TestBindingsFlags(t *testing.T) {
+	g := NewWithT(t)
+
+	root, err := New(genericclioptions.NewTestIOStreamsDiscard())
+	g.Expect(err).ToNot(HaveOccurred())
+
+	for _, name := range []string{"list", "revoke"} {
+		cmd, _, err := root.Find([]string{"bindings", name})
+		g.Expect(err).ToNot(HaveOccurred())
+		g.Expect(cmd.Name()).To(Equal(name))
+
+		g.Expect(cmd.ParseFlags([]string{
+			"--kubeconfig", "provider.kubeconfig",
+			"--context", "provider",
+			"--as", "admin",
+			"--group", "okta_group://platform",
+			"--role", "view",
+		})).To(Succeed())
+		g.Expect(cmd.Flags().Lookup("kubeconfig").Value.String()).To(Equal("provider.kubeconfig"))
+		g.Expect(cmd.Flags().Lookup("context").Value.String()).To(Equal("provider"))
+		g.Expect(cmd.Flags().Lookup("as").Value.String()).To(Equal("admin"))
+	}
+}
@@ -29,15 +29,19 @@ import (
 
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/cli-runtime/pkg/genericclioptions"
+	"k8s.io/client-go/discovery"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
 	"k8s.io/component-base/logs"
 	logsv1 "k8s.io/component-base/logs/api/v1"
 
-	"github.com/Danil-Grigorev/rancher-bind/internal/controller"
 	apis "github.com/Danil-Grigorev/rancher-bind/pkg/apis"
 	managementv3 "github.com/Danil-Grigorev/rancher-bind/pkg/apis/rancher/management/v3"
+	provisioningv1 "github.com/Danil-Grigorev/rancher-bind/pkg/apis/rancher/provisioning/v1"
 
 	backend "github.com/Danil-Grigorev/rancher-bind/deploy/backend"
 	"github.com/kube-bind/kube-bind/pkg/kubectl/base"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	apiyaml "k8s.io/apimachinery/pkg/util/yaml"
 	"k8s.io/client-go/dynamic"
 	clientcmdapiv1 "k8s.io/client-go/tools/clientcmd/api/v1"
@@ -51,9 +55,32 @@ type BindAPIServiceOptions struct {
 
 	*runtime.Scheme
 
+	// restConfig is the single client configuration all clients are derived from.
+	restConfig *rest.Config
+
 	file     string
 	insecure bool
 	deploy   bool
+	dryRun   string
+	server   string
+
+	// serverURL is the resolved rancher server URL.
+	serverURL string
+
+	// Login are the options to use an existing user instead of creating one.
+	Login *LoginOptions
+
+	execCredential   bool
+	cluster          string
+	clusterNamespace string
+	group            string
+
+	// clusterID is the resolved rancher cluster ID.
+	clusterID string
+
+	// Created user details.
+	displayName string
+	description string
 }
 
 // NewRancherBindOptions returns new BindAPIServiceOptions.
@@ -62,21 +89,37 @@ func NewRancherBindOptions(streams genericclioptions.IOStreams) *BindAPIServiceO
 		Options: base.NewOptions(streams),
 		Logs:    logs.NewOptions(),
 		Scheme:  runtime.NewScheme(),
+		dryRun:  DryRunNone,
+		Login:   NewLoginOptions(streams),
+		cluster: "local",
+
+		clusterNamespace: provisioningv1.DefaultNamespace,
 	}
 
 	utilruntime.Must(managementv3.AddToScheme(options.Scheme))
+	utilruntime.Must(provisioningv1.AddToScheme(options.Scheme))
 
 	return options
 }
 
 // AddCmdFlags binds fields to cmd's flagset.
 func (b *BindAPIServiceOptions) AddCmdFlags(cmd *cobra.Command) {
-	b.Options.BindFlags(cmd)
+	bindClientFlags(cmd, b.Options)
 	logsv1.AddFlags(b.Logs, cmd.Flags())
 
 	cmd.Flags().StringVarP(&b.file, "file", "f", b.file, "A file with a GlobalRole manifest")
 	cmd.Flags().BoolVarP(&b.insecure, "insecure-skip-tls-verify", "i", b.insecure, "Sets the insecure-skip-tls-verify flag in the generated kubeconfig")
 	cmd.Flags().BoolVarP(&b.deploy, "deploy-backend", "d", b.deploy, "Deploy rancher-bind backend on the provider cluster")
+	cmd.Flags().StringVar(&b.dryRun, "dry-run", b.dryRun, `Must be "none", "server", or "client". If client strategy, only print the objects that would be created, without sending them. If server strategy, submit server-side dry-run requests without persisting the objects.`)
+	cmd.Flags().StringVar(&b.displayName, "display-name", b.displayName, "Display name of the created user")
+	cmd.Flags().StringVar(&b.description, "description", b.description, "Description of the created user")
+	cmd.Flags().StringVar(&b.group, "group", b.group, "Bind the GlobalRole to the group principal, e.g. okta_group://platform, instead of issuing a kubeconfig")
+	cmd.Flags().StringVar(&b.cluster, "cluster", b.cluster, "Rancher cluster ID or provisioning cluster name to generate the kubeconfig for")
+	cmd.Flags().StringVar(&b.clusterNamespace, "cluster-namespace", b.clusterNamespace, "Namespace of the provisioning cluster selected by name")
+	cmd.Flags().StringVar(&b.server, "server-url", b.server, "Rancher server URL, defaults to the server-url setting value")
+
+	b.Login.AddFlags(cmd.Flags(), "Log in as an existing rancher user instead of creating a new one")
+	cmd.Flags().BoolVar(&b.execCredential, "exec-credential", b.execCredential, "Emit a kubeconfig refreshing the existing user token with the credential command instead of embedding the token")
 }
 
 // Complete ensures all fields are initialized.
@@ -86,10 +129,40 @@ func (b *BindAPIServiceOptions) Complete(args []string) error {
 
 // Validate validates the NewRancherBindOptions are complete and usable.
 func (b *BindAPIServiceOptions) Validate() error {
-	if b.file == "" {
+	if b.file == "" && !b.Login.Enabled() {
 		return errors.New("file is required")
 	}
 
+	if err := b.Login.Validate(); err != nil {
+		return err
+	}
+
+	if b.Login.Enabled() && b.dryRun != DryRunNone {
+		return errors.New("dry-run is not supported with an existing user")
+	}
+
+	if b.deploy && b.Login.Enabled() {
+		return errors.New("deploy-backend is not supported with an existing user")
+	}
+
+	if b.execCredential && !b.Login.Enabled() {
+		return errors.New("exec-credential requires username or sso")
+	}
+
+	if b.group != "" && (b.file == "" || b.Login.Enabled()) {
+		return errors.New("group requires file and does not accept an existing user")
+	}
+
+	if (b.displayName != "" || b.description != "") && (b.group != "" || b.Login.Enabled()) {
+		return errors.New("display-name and description only apply to a created user")
+	}
+
+	switch b.dryRun {
+	case DryRunNone, DryRunClient, DryRunServer:
+	default:
+		return fmt.Errorf(`invalid dry-run value %q, must be "none", "server", or "client"`, b.dryRun)
+	}
+
 	return b.Options.Validate()
 }
 
@@ -104,30 +177,51 @@ func (b *BindAPIServiceOptions) Validate() error {
 // - Collect the kubeconfig generated from the given token.
 // - Remove the temporary GlobalRole and binding.
 // - Create the provided ClusterRole from file, add a role binding.
+//
+// With --dry-run the objects are only rendered, see RunDryRun.
+// With --username or --sso an existing user is used instead, see RunLogin.
+// With --group the role is bound to the group principal only, see RunGroup.
 func (b *BindAPIServiceOptions) Run(ctx context.Context) error {
-	cl, err := b.GetClient()
-	if err != nil {
-		return err
+	if b.dryRun != DryRunNone {
+		return b.RunDryRun(ctx)
 	}
 
-	serverUrl, err := GetServer(ctx, cl)
-	if err != nil {
-		return err
+	if b.group != "" {
+		return b.RunGroup(ctx)
 	}
 
-	cfg, err := controller.NewConfig()
+	if b.Login.Enabled() {
+		return b.RunLogin(ctx)
+	}
+
+	cl, err := b.GetClient()
 	if err != nil {
-		return fmt.Errorf("unable to get config: %w", err)
+		return err
 	}
 
-	dynamicClient, err := dynamic.NewForConfig(cfg.ClientConfig)
+	serverUrl, err := b.GetServerURL(ctx)
 	if err != nil {
 		return err
 	}
 
 	if b.deploy {
+		config, err := b.RESTConfig()
+		if err != nil {
+			return err
+		}
+
+		discoveryClient, err := discovery.NewDiscoveryClientForConfig(config)
+		if err != nil {
+			return err
+		}
+
+		dynamicClient, err := dynamic.NewForConfig(config)
+		if err != nil {
+			return err
+		}
+
 		fmt.Fprintf(b.Options.ErrOut, "🚀 Deploying rancher bind backend.\n") // nolint: errcheck
-		if err := backend.Bootstrap(ctx, cfg.KubeClient.Discovery(), dynamicClient); err != nil {
+		if err := backend.Bootstrap(ctx, discoveryClient, dynamicClient); err != nil {
 			return err
 		}
 	}
@@ -137,8 +231,8 @@ func (b *BindAPIServiceOptions) Run(ctx context.Context) error {
 		return err
 	}
 
-	user, err := CreateUser(ctx, cl, hash)
-	if err != nil {
+	user := NewUser(hash, b.displayName, b.description)
+	if err := CreateUser(ctx, cl, user); err != nil {
 		return err
 	}
 
@@ -162,7 +256,7 @@ func (b *BindAPIServiceOptions) Run(ctx context.Context) error {
 		return err
 	}
 
-	config, err := CollectKubeconfig(serverUrl, token.Token)
+	config, err := b.CollectKubeconfig(ctx, serverUrl, token.Token)
 	if err != nil {
 		return err
 	}
@@ -178,8 +272,60 @@ func (b *BindAPIServiceOptions) Run(ctx context.Context) error {
 	return nil
 }
 
+// RunGroup creates the GlobalRole from file and binds it to the group principal.
+func (b *BindAPIServiceOptions) RunGroup(ctx context.Context) error {
+	cl, err := b.GetClient()
+	if err != nil {
+		return err
+	}
+
+	if err := ApplyGroupGlobalRole(ctx, cl, b.group, b.file); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(b.Options.ErrOut, "GlobalRole from %s is bound to group %s\n", b.file, b.group) // nolint: errcheck
+
+	return nil
+}
+
+// RESTConfig returns the client configuration built from the kubeconfig, context and
+// impersonation flags. Every client used by the plugin must be derived from it.
+func (b *BindAPIServiceOptions) RESTConfig() (*rest.Config, error) {
+	if b.restConfig != nil {
+		return b.restConfig, nil
+	}
+
+	config, err := newRESTConfig(b.Options)
+	if err != nil {
+		return nil, err
+	}
+	b.restConfig = config
+
+	return b.restConfig, nil
+}
+
+// bindClientFlags binds the client connection flags of the kube-bind base options, and the
+// kubectl impersonation flags they opt out of.
+func bindClientFlags(cmd *cobra.Command, options *base.Options) {
+	options.BindFlags(cmd)
+
+	impersonateFlags := clientcmd.RecommendedAuthOverrideFlags("")
+	cmd.PersistentFlags().StringVar(&options.KubectlOverrides.AuthInfo.Impersonate, impersonateFlags.Impersonate.LongName, "", impersonateFlags.Impersonate.Description)
+	cmd.PersistentFlags().StringArrayVar(&options.KubectlOverrides.AuthInfo.ImpersonateGroups, impersonateFlags.ImpersonateGroups.LongName, nil, impersonateFlags.ImpersonateGroups.Description)
+}
+
+// newRESTConfig returns the client configuration of the completed base options.
+func newRESTConfig(options *base.Options) (*rest.Config, error) {
+	config, err := options.ClientConfig.ClientConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	return rest.AddUserAgent(rest.CopyConfig(config), "kubectl-rancher-bind"), nil
+}
+
 func (b *BindAPIServiceOptions) GetClient() (client.Client, error) {
-	config, err := b.Options.ClientConfig.ClientConfig()
+	config, err := b.RESTConfig()
 	if err != nil {
 		return nil, err
 	}
@@ -187,14 +333,128 @@ func (b *BindAPIServiceOptions) GetClient() (client.Client, error) {
 	return client.New(config, client.Options{Scheme: b.Scheme})
 }
 
-func (b *BindAPIServiceOptions) DisplayKubeconfig(config *apis.ConfigResponse) error {
+// CollectKubeconfig generates the kubeconfig for the selected cluster. When rancher is
+// configured not to generate kubeconfig tokens, the exec based users are replaced with
+// an explicit cluster scoped token. For downstream clusters with the Authorized Cluster
+// Endpoint enabled, a context for the direct FQDN endpoint is added.
+func (b *BindAPIServiceOptions) CollectKubeconfig(ctx context.Context, serverUrl, token string) (*clientcmdapiv1.Config, error) {
+	clusterID, err := b.GetClusterID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	config, err := CollectClusterKubeconfig(serverUrl, clusterID, token)
+	if err != nil {
+		return nil, err
+	}
+
 	cfg := &clientcmdapiv1.Config{}
 
 	decoder := apiyaml.NewYAMLOrJSONDecoder(bytes.NewReader([]byte(config.Config)), 1000)
 	if err := decoder.Decode(cfg); err != nil {
-		return fmt.Errorf("unable to decode generated kubeconfig: %w", err)
+		return nil, fmt.Errorf("unable to decode generated kubeconfig: %w", err)
+	}
+
+	if !b.execCredential && !b.generatesToken(ctx, cfg) {
+		explicit, err := CreateToken(serverUrl, token, clusterID, "rancher-bind kubeconfig")
+		if err != nil {
+			return nil, err
+		}
+
+		for i := range cfg.AuthInfos {
+			cfg.AuthInfos[i].AuthInfo = clientcmdapiv1.AuthInfo{
+				Token: explicit.Token,
+			}
+		}
 	}
 
+	if err := b.addAuthorizedClusterEndpoint(ctx, clusterID, cfg); err != nil {
+		return nil, err
+	}
+
+	return cfg, nil
+}
+
+// generatesToken returns false when rancher is configured to produce exec based kubeconfigs
+// calling the rancher CLI. Without access to the setting, the kubeconfig users are inspected.
+func (b *BindAPIServiceOptions) generatesToken(ctx context.Context, cfg *clientcmdapiv1.Config) bool {
+	if cl, err := b.GetClient(); err == nil {
+		if value, err := GetSetting(ctx, cl, KubeconfigGenerateTokenSetting); err == nil {
+			return value != "false"
+		}
+	}
+
+	for _, user := range cfg.AuthInfos {
+		if user.AuthInfo.Exec != nil && user.AuthInfo.Token == "" {
+			return false
+		}
+	}
+
+	return true
+}
+
+// addAuthorizedClusterEndpoint adds a context for the FQDN endpoint of a downstream cluster
+// with the Authorized Cluster Endpoint enabled, unless rancher already provided it. Without
+// a provider kubeconfig the cluster is not inspected and the context is skipped.
+func (b *BindAPIServiceOptions) addAuthorizedClusterEndpoint(ctx context.Context, clusterID string, cfg *clientcmdapiv1.Config) error {
+	if clusterID == "local" {
+		return nil
+	}
+
+	cl, err := b.GetClient()
+	if err != nil {
+		return nil
+	}
+
+	// The cluster object is not accessible to every existing user.
+	cluster := &managementv3.Cluster{}
+	if err := cl.Get(ctx, client.ObjectKey{Name: clusterID}, cluster); apierrors.IsNotFound(err) || apierrors.IsForbidden(err) {
+		return nil
+	} else if err != nil {
+		return fmt.Errorf("unable to get cluster %s: %w", clusterID, err)
+	}
+
+	ace := cluster.Spec.LocalClusterAuthEndpoint
+	if !ace.Enabled || ace.FQDN == "" {
+		return nil
+	}
+
+	var current *clientcmdapiv1.Context
+	for i := range cfg.Contexts {
+		if cfg.Contexts[i].Name == cfg.CurrentContext {
+			current = &cfg.Contexts[i].Context
+		}
+	}
+	if current == nil {
+		return nil
+	}
+
+	name := cfg.CurrentContext + "-fqdn"
+	for _, named := range cfg.Contexts {
+		if named.Name == name {
+			return nil
+		}
+	}
+
+	cfg.Clusters = append(cfg.Clusters, clientcmdapiv1.NamedCluster{
+		Name: name,
+		Cluster: clientcmdapiv1.Cluster{
+			Server:                   "https://" + ace.FQDN,
+			CertificateAuthorityData: []byte(ace.CACerts),
+		},
+	})
+	cfg.Contexts = append(cfg.Contexts, clientcmdapiv1.NamedContext{
+		Name: name,
+		Context: clientcmdapiv1.Context{
+			Cluster:  name,
+			AuthInfo: current.AuthInfo,
+		},
+	})
+
+	return nil
+}
+
+func (b *BindAPIServiceOptions) DisplayKubeconfig(cfg *clientcmdapiv1.Config) error {
 	if b.insecure {
 		for i := range cfg.Clusters {
 			cfg.Clusters[i].Cluster.InsecureSkipTLSVerify = true
@@ -202,6 +462,14 @@ func (b *BindAPIServiceOptions) DisplayKubeconfig(config *apis.ConfigResponse) e
 		}
 	}
 
+	if b.execCredential {
+		for i := range cfg.AuthInfos {
+			cfg.AuthInfos[i].AuthInfo = clientcmdapiv1.AuthInfo{
+				Exec: execCredentialUser(b.Login, b.serverURL, b.clusterID),
+			}
+		}
+	}
+
 	if result, err := yaml.Marshal(cfg); err != nil {
 		return fmt.Errorf("unable to display generated kubeconfig: %w", err)
 	} else {
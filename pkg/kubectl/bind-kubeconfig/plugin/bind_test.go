@@ -0,0 +1,38 @@
+/*
+Copyright 2023 SUSE.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package plugin
+
+import (
+	"testing"
+
+	. "github.com/onsi/gomega"
+
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+)
+
+func TestValidateExistingUser(t *testing.T) {
+	g := NewWithT(t)
+
+	b := NewRancherBindOptions(genericclioptions.NewTestIOStreamsDiscard())
+	b.Login.Username = "admin"
+	b.deploy = true
+	g.Expect(b.Validate()).To(MatchError("deploy-backend is not supported with an existing user"))
+
+	b.Login.Username = ""
+	b.Login.SSO = true
+	g.Expect(b.Validate()).To(MatchError("deploy-backend is not supported with an existing user"))
+}
@@ -0,0 +1,129 @@
+/*
+Copyright 2023 SUSE.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package plugin
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"k8s.io/apimachinery/pkg/runtime"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+
+	"github.com/kube-bind/kube-bind/pkg/kubectl/base"
+
+	managementv3 "github.com/Danil-Grigorev/rancher-bind/pkg/apis/rancher/management/v3"
+)
+
+// BindingsOptions are the options for the kubectl-rancher-bind bindings commands.
+type BindingsOptions struct {
+	Options *base.Options
+
+	*runtime.Scheme
+
+	group string
+	role  string
+}
+
+// NewBindingsOptions returns new BindingsOptions.
+func NewBindingsOptions(streams genericclioptions.IOStreams) *BindingsOptions {
+	options := &BindingsOptions{
+		Options: base.NewOptions(streams),
+		Scheme:  runtime.NewScheme(),
+	}
+
+	utilruntime.Must(managementv3.AddToScheme(options.Scheme))
+
+	return options
+}
+
+// AddCmdFlags binds fields to cmd's flagset.
+func (o *BindingsOptions) AddCmdFlags(cmd *cobra.Command) {
+	bindClientFlags(cmd, o.Options)
+
+	cmd.Flags().StringVar(&o.group, "group", o.group, "Group principal name, e.g. okta_group://platform")
+	cmd.Flags().StringVar(&o.role, "role", o.role, "GlobalRole name to limit the bindings to")
+}
+
+// Complete ensures all fields are initialized.
+func (o *BindingsOptions) Complete() error {
+	return o.Options.Complete()
+}
+
+// GetClient returns a client for the configured connection.
+func (o *BindingsOptions) GetClient() (client.Client, error) {
+	config, err := newRESTConfig(o.Options)
+	if err != nil {
+		return nil, err
+	}
+
+	return client.New(config, client.Options{Scheme: o.Scheme})
+}
+
+// ValidateRevoke validates the options are usable for the revocation.
+func (o *BindingsOptions) ValidateRevoke() error {
+	if o.group == "" {
+		return errors.New("group is required")
+	}
+
+	return nil
+}
+
+// RunList prints the group bindings created by rancher-bind.
+func (o *BindingsOptions) RunList(ctx context.Context) error {
+	cl, err := o.GetClient()
+	if err != nil {
+		return err
+	}
+
+	bindings, err := ListGroupBindings(ctx, cl, o.group)
+	if err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(o.Options.Out, 0, 4, 3, ' ', 0)
+	fmt.Fprintln(w, "NAME\tGLOBALROLE\tGROUP") // nolint: errcheck
+	for _, binding := range bindings {
+		if o.role != "" && binding.GlobalRoleName != o.role {
+			continue
+		}
+
+		fmt.Fprintf(w, "%s\t%s\t%s\n", binding.Name, binding.GlobalRoleName, binding.GroupPrincipalName) // nolint: errcheck
+	}
+
+	return w.Flush()
+}
+
+// RunRevoke removes the group bindings created by rancher-bind.
+func (o *BindingsOptions) RunRevoke(ctx context.Context) error {
+	cl, err := o.GetClient()
+	if err != nil {
+		return err
+	}
+
+	revoked, err := RevokeGroupBindings(ctx, cl, o.group, o.role)
+	for _, binding := range revoked {
+		fmt.Fprintf(o.Options.ErrOut, "Revoked GlobalRole %s from group %s\n", binding.GlobalRoleName, binding.GroupPrincipalName) // nolint: errcheck
+	}
+
+	return err
+}
@@ -0,0 +1,105 @@
+/*
+Copyright 2023 SUSE.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package plugin
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	"github.com/spf13/cobra"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+
+	managementv3 "github.com/Danil-Grigorev/rancher-bind/pkg/apis/rancher/management/v3"
+)
+
+const groupRole = `apiVersion: management.cattle.io/v3
+kind: GlobalRole
+metadata:
+  name: view
+rules:
+- apiGroups: ["provisioning.cattle.io"]
+  resources: ["clusters"]
+  verbs: ["get", "list"]
+`
+
+func TestGroupBindings(t *testing.T) {
+	g := NewWithT(t)
+	ctx := context.Background()
+
+	path := filepath.Join(t.TempDir(), "role.yaml")
+	g.Expect(os.WriteFile(path, []byte(groupRole), 0o600)).To(Succeed())
+
+	o := NewBindingsOptions(genericclioptions.NewTestIOStreamsDiscard())
+	userBinding := &managementv3.GlobalRoleBinding{
+		ObjectMeta:     metav1.ObjectMeta{Name: "user-binding", Labels: map[string]string{ManagedByLabel: ManagedByValue}},
+		GlobalRoleName: "view",
+		UserName:       "u-abcde",
+	}
+	cl := fake.NewClientBuilder().WithScheme(o.Scheme).WithObjects(userBinding).Build()
+
+	g.Expect(ApplyGroupGlobalRole(ctx, cl, "okta_group://platform", path)).To(Succeed())
+	g.Expect(ApplyGroupGlobalRole(ctx, cl, "okta_group://sre", path)).To(Succeed())
+	g.Expect(cl.Get(ctx, client.ObjectKey{Name: "view"}, &managementv3.GlobalRole{})).To(Succeed())
+
+	// User bindings are not listed.
+	bindings, err := ListGroupBindings(ctx, cl, "")
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(bindings).To(HaveLen(2))
+
+	bindings, err = ListGroupBindings(ctx, cl, "okta_group://platform")
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(bindings).To(HaveLen(1))
+	g.Expect(bindings[0].GlobalRoleName).To(Equal("view"))
+
+	// Other roles of the group are kept.
+	revoked, err := RevokeGroupBindings(ctx, cl, "okta_group://platform", "edit")
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(revoked).To(BeEmpty())
+
+	revoked, err = RevokeGroupBindings(ctx, cl, "okta_group://platform", "")
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(revoked).To(HaveLen(1))
+
+	bindings, err = ListGroupBindings(ctx, cl, "")
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(bindings).To(HaveLen(1))
+	g.Expect(bindings[0].GroupPrincipalName).To(Equal("okta_group://sre"))
+	g.Expect(cl.Get(ctx, client.ObjectKeyFromObject(userBinding), &managementv3.GlobalRoleBinding{})).To(Succeed())
+}
+
+func TestBindingsOptions(t *testing.T) {
+	g := NewWithT(t)
+
+	o := NewBindingsOptions(genericclioptions.NewTestIOStreamsDiscard())
+	cmd := &cobra.Command{Use: "revoke"}
+	o.AddCmdFlags(cmd)
+
+	g.Expect(cmd.ParseFlags([]string{"--context", "provider", "--as", "admin", "--role", "view"})).To(Succeed())
+	g.Expect(o.Options.KubectlOverrides.CurrentContext).To(Equal("provider"))
+	g.Expect(o.Options.KubectlOverrides.AuthInfo.Impersonate).To(Equal("admin"))
+	g.Expect(o.ValidateRevoke()).To(MatchError("group is required"))
+
+	o.group = "okta_group://platform"
+	g.Expect(o.ValidateRevoke()).To(Succeed())
+}
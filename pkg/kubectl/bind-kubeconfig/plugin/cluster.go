@@ -0,0 +1,79 @@
+/*
+Copyright 2023 SUSE.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package plugin
+
+import (
+	"context"
+	"fmt"
+
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+
+	managementv3 "github.com/Danil-Grigorev/rancher-bind/pkg/apis/rancher/management/v3"
+	provisioningv1 "github.com/Danil-Grigorev/rancher-bind/pkg/apis/rancher/provisioning/v1"
+)
+
+// ResolveClusterID returns the rancher cluster ID for the name, which is either a cluster ID
+// or the name of a provisioning cluster in the namespace. Names which match neither are
+// returned as is, leaving the validation to rancher.
+func ResolveClusterID(ctx context.Context, cl client.Client, namespace, name string) (string, error) {
+	if name == "local" {
+		return name, nil
+	}
+
+	cluster := &managementv3.Cluster{}
+	if err := cl.Get(ctx, client.ObjectKey{Name: name}, cluster); err == nil {
+		return name, nil
+	} else if !apierrors.IsNotFound(err) && !apierrors.IsForbidden(err) && !meta.IsNoMatchError(err) {
+		return "", fmt.Errorf("unable to get cluster %s: %w", name, err)
+	}
+
+	provisioning := &provisioningv1.Cluster{}
+	if err := cl.Get(ctx, client.ObjectKey{Namespace: namespace, Name: name}, provisioning); apierrors.IsNotFound(err) || apierrors.IsForbidden(err) || meta.IsNoMatchError(err) {
+		return name, nil
+	} else if err != nil {
+		return "", fmt.Errorf("unable to get provisioning cluster %s/%s: %w", namespace, name, err)
+	}
+
+	if provisioning.Status.ClusterName == "" {
+		return "", fmt.Errorf("provisioning cluster %s/%s is not registered in rancher yet", namespace, name)
+	}
+
+	return provisioning.Status.ClusterName, nil
+}
+
+// GetClusterID returns the rancher cluster ID for the --cluster value. Without access to
+// the provider cluster, the value is used as is.
+func (b *BindAPIServiceOptions) GetClusterID(ctx context.Context) (string, error) {
+	if b.clusterID != "" {
+		return b.clusterID, nil
+	}
+
+	cl, err := b.GetClient()
+	if err != nil {
+		b.clusterID = b.cluster
+		return b.clusterID, nil
+	}
+
+	if b.clusterID, err = ResolveClusterID(ctx, cl, b.clusterNamespace, b.cluster); err != nil {
+		return "", err
+	}
+
+	return b.clusterID, nil
+}
@@ -0,0 +1,198 @@
+/*
+Copyright 2023 SUSE.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package plugin
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	clientauthenticationv1 "k8s.io/client-go/pkg/apis/clientauthentication/v1"
+	clientcmdapiv1 "k8s.io/client-go/tools/clientcmd/api/v1"
+	"k8s.io/client-go/util/homedir"
+
+	apis "github.com/Danil-Grigorev/rancher-bind/pkg/apis"
+)
+
+// tokenRefreshSkew is how long before the expiration a cached token is refreshed.
+const tokenRefreshSkew = time.Minute
+
+// CredentialOptions are the options for the kubectl-rancher-bind credential command.
+type CredentialOptions struct {
+	genericclioptions.IOStreams
+
+	Login *LoginOptions
+
+	server   string
+	cluster  string
+	cacheDir string
+}
+
+// NewCredentialOptions returns new CredentialOptions.
+func NewCredentialOptions(streams genericclioptions.IOStreams) *CredentialOptions {
+	return &CredentialOptions{
+		IOStreams: streams,
+		Login:     NewLoginOptions(streams),
+		cluster:   "local",
+		cacheDir:  filepath.Join(homedir.HomeDir(), ".kube", "cache", "rancher-bind"),
+	}
+}
+
+// AddCmdFlags binds fields to cmd's flagset.
+func (c *CredentialOptions) AddCmdFlags(cmd *cobra.Command) {
+	c.Login.AddFlags(cmd.Flags(), "Rancher user to obtain the token for")
+
+	cmd.Flags().StringVar(&c.server, "server-url", c.server, "Rancher server URL")
+	cmd.Flags().StringVar(&c.cluster, "cluster", c.cluster, "Rancher cluster ID the token is scoped to")
+	cmd.Flags().StringVar(&c.cacheDir, "cache-dir", c.cacheDir, "Directory to cache the obtained tokens in")
+}
+
+// Validate validates the CredentialOptions are complete and usable.
+func (c *CredentialOptions) Validate() error {
+	if c.server == "" {
+		return errors.New("server-url is required")
+	}
+
+	if !c.Login.Enabled() {
+		return errors.New("username or sso is required")
+	}
+
+	return c.Login.Validate()
+}
+
+// Run prints an ExecCredential with a cached or a freshly obtained rancher token.
+func (c *CredentialOptions) Run(ctx context.Context) error {
+	serverUrl := strings.TrimSuffix(c.server, "/")
+	cachePath := filepath.Join(c.cacheDir, c.cacheKey(serverUrl)+".json")
+
+	token, err := readCachedToken(cachePath, func(token string) error {
+		_, err := GetCurrentUser(serverUrl, token)
+		return err
+	})
+	if err != nil || token == nil {
+		if token, err = c.Login.Login(ctx, serverUrl, "kubeconfig_"+c.cluster); err != nil {
+			return err
+		}
+
+		if err := writeCachedToken(cachePath, token); err != nil {
+			fmt.Fprintf(c.ErrOut, "Unable to cache the token: %v\n", err) // nolint: errcheck
+		}
+	}
+
+	credential := &clientauthenticationv1.ExecCredential{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: clientauthenticationv1.SchemeGroupVersion.String(),
+			Kind:       "ExecCredential",
+		},
+		Status: &clientauthenticationv1.ExecCredentialStatus{
+			Token: token.Token,
+		},
+	}
+
+	if expiresAt, err := time.Parse(time.RFC3339, token.ExpiresAt); err == nil {
+		credential.Status.ExpirationTimestamp = &metav1.Time{Time: expiresAt}
+	}
+
+	return json.NewEncoder(c.Out).Encode(credential)
+}
+
+func (c *CredentialOptions) cacheKey(serverUrl string) string {
+	user := c.Login.AuthProvider + "/" + c.Login.Username
+	if c.Login.SSO {
+		user = "sso"
+	}
+
+	hash := sha256.Sum256([]byte(strings.Join([]string{serverUrl, user, c.cluster}, "|")))
+	return hex.EncodeToString(hash[:])
+}
+
+// readCachedToken returns the cached token, or nil when there is no usable one. Tokens
+// without an expiration, e.g. issued with a zero TTL, are only used after validate accepts
+// them, as they may have been revoked or logged out since.
+func readCachedToken(path string, validate func(token string) error) (*apis.LoginResponse, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	token := &apis.LoginResponse{}
+	if err := json.Unmarshal(data, token); err != nil {
+		return nil, err
+	}
+
+	if token.Token == "" {
+		return nil, nil
+	}
+
+	if token.ExpiresAt == "" {
+		if err := validate(token.Token); err != nil {
+			return nil, nil
+		}
+		return token, nil
+	}
+
+	expiresAt, err := time.Parse(time.RFC3339, token.ExpiresAt)
+	if err != nil || time.Until(expiresAt) < tokenRefreshSkew {
+		return nil, nil
+	}
+
+	return token, nil
+}
+
+func writeCachedToken(path string, token *apis.LoginResponse) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return err
+	}
+
+	data, err := json.Marshal(token)
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(path, data, 0o600)
+}
+
+// execCredentialUser returns a kubeconfig user obtaining rancher tokens through the
+// credential command for the given login.
+func execCredentialUser(login *LoginOptions, serverUrl, cluster string) *clientcmdapiv1.ExecConfig {
+	args := []string{"rancher-bind", "credential", "--server-url", serverUrl, "--cluster", cluster}
+	if login.SSO {
+		args = append(args, "--sso")
+	} else {
+		args = append(args, "--username", login.Username, "--auth-provider", login.AuthProvider)
+	}
+
+	return &clientcmdapiv1.ExecConfig{
+		APIVersion:      clientauthenticationv1.SchemeGroupVersion.String(),
+		Command:         "kubectl",
+		Args:            args,
+		InteractiveMode: clientcmdapiv1.IfAvailableExecInteractiveMode,
+	}
+}
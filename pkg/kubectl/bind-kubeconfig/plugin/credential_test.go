@@ -0,0 +1,81 @@
+/*
+Copyright 2023 SUSE.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package plugin
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+
+	apis "github.com/Danil-Grigorev/rancher-bind/pkg/apis"
+)
+
+func TestCachedToken(t *testing.T) {
+	g := NewWithT(t)
+
+	path := filepath.Join(t.TempDir(), "cache", "token.json")
+	valid := func(string) error { return nil }
+	revoked := func(string) error { return errors.New("401 unauthorized") }
+
+	// No cache yet.
+	token, err := readCachedToken(path, valid)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(token).To(BeNil())
+
+	// Tokens with a future expiration are used without validation.
+	expiring := &apis.LoginResponse{Token: "token-a:secret", ExpiresAt: time.Now().Add(time.Hour).UTC().Format(time.RFC3339)}
+	g.Expect(writeCachedToken(path, expiring)).To(Succeed())
+	info, err := os.Stat(path)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(info.Mode().Perm()).To(Equal(os.FileMode(0o600)))
+
+	token, err = readCachedToken(path, revoked)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(token).To(Equal(expiring))
+
+	// Tokens expiring within the refresh skew are refreshed.
+	g.Expect(writeCachedToken(path, &apis.LoginResponse{Token: "token-a:secret", ExpiresAt: time.Now().Add(tokenRefreshSkew / 2).UTC().Format(time.RFC3339)})).To(Succeed())
+	token, err = readCachedToken(path, valid)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(token).To(BeNil())
+
+	// Unparseable expirations count as expired.
+	g.Expect(writeCachedToken(path, &apis.LoginResponse{Token: "token-a:secret", ExpiresAt: "never"})).To(Succeed())
+	token, err = readCachedToken(path, valid)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(token).To(BeNil())
+
+	// Tokens without an expiration are validated against rancher.
+	forever := &apis.LoginResponse{Token: "token-b:secret"}
+	g.Expect(writeCachedToken(path, forever)).To(Succeed())
+	token, err = readCachedToken(path, valid)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(token).To(Equal(forever))
+
+	token, err = readCachedToken(path, revoked)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(token).To(BeNil())
+
+	// A corrupt cache is an error.
+	g.Expect(os.WriteFile(path, []byte("{"), 0o600)).To(Succeed())
+	_, err = readCachedToken(path, valid)
+	g.Expect(err).To(HaveOccurred())
+}
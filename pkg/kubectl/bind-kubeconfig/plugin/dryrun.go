@@ -0,0 +1,159 @@
+/*
+Copyright 2023 SUSE.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package plugin
+
+import (
+	"context"
+	"fmt"
+
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/apiutil"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	yaml "sigs.k8s.io/yaml"
+
+	backend "github.com/Danil-Grigorev/rancher-bind/deploy/backend"
+	managementv3 "github.com/Danil-Grigorev/rancher-bind/pkg/apis/rancher/management/v3"
+)
+
+const (
+	DryRunNone   = "none"
+	DryRunClient = "client"
+	DryRunServer = "server"
+)
+
+// RunDryRun renders every object the issuance would create, without calling the rancher
+// login and kubeconfig endpoints. In server mode objects are submitted with server-side
+// dry-run, falling back to the local rendering for kinds the cluster does not serve yet.
+func (b *BindAPIServiceOptions) RunDryRun(ctx context.Context) error {
+	var cl client.Client
+	if b.dryRun == DryRunServer {
+		var err error
+		if cl, err = b.GetClient(); err != nil {
+			return err
+		}
+	}
+
+	objects := []client.Object{}
+
+	if b.deploy {
+		manifests, err := backend.Manifests()
+		if err != nil {
+			return fmt.Errorf("unable to render backend manifests: %w", err)
+		}
+
+		for _, obj := range manifests {
+			objects = append(objects, obj)
+		}
+	}
+
+	role, err := LoadGlobalRole(b.file)
+	if err != nil {
+		return err
+	}
+
+	if b.group != "" {
+		objects = append(objects, role, NewGroupGlobalRoleBinding(b.group, role))
+
+		return b.printDryRun(ctx, cl, objects)
+	}
+
+	_, hash, err := GenerateRandomPassword()
+	if err != nil {
+		return err
+	}
+
+	user := NewUser(hash, b.displayName, b.description)
+
+	objects = append(objects,
+		user,
+		NewClusterRole(user),
+		NewRoleBinding(user),
+		role,
+		NewUserGlobalRoleBinding(user.Username, role),
+	)
+
+	return b.printDryRun(ctx, cl, objects)
+}
+
+// printDryRun prints the objects, submitting them with dry-run first when the client is set.
+func (b *BindAPIServiceOptions) printDryRun(ctx context.Context, cl client.Client, objects []client.Object) error {
+	for _, obj := range objects {
+		if cl != nil {
+			if err := dryRunApply(ctx, cl, obj); err != nil {
+				return err
+			}
+		}
+
+		if err := b.printObject(obj); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// dryRunApply submits the object to the server with dry-run, updating it in place
+// with the server response.
+func dryRunApply(ctx context.Context, cl client.Client, obj client.Object) error {
+	err := cl.Create(ctx, obj, client.DryRunAll)
+	if meta.IsNoMatchError(err) {
+		return nil
+	} else if !apierrors.IsAlreadyExists(err) {
+		return err
+	}
+
+	existing, ok := obj.DeepCopyObject().(client.Object)
+	if !ok {
+		return fmt.Errorf("unable to copy %s", obj.GetName())
+	}
+	if err := cl.Get(ctx, client.ObjectKeyFromObject(obj), existing); err != nil {
+		return fmt.Errorf("unable to get object: %w", err)
+	}
+
+	obj.SetResourceVersion(existing.GetResourceVersion())
+	if err := cl.Update(ctx, obj, client.DryRunAll); err != nil {
+		return fmt.Errorf("unable to update existing object: %w", err)
+	}
+
+	return nil
+}
+
+func (b *BindAPIServiceOptions) printObject(obj client.Object) error {
+	if _, ok := obj.(*unstructured.Unstructured); !ok {
+		gvk, err := apiutil.GVKForObject(obj, b.Scheme)
+		if err != nil {
+			return err
+		}
+		obj.GetObjectKind().SetGroupVersionKind(gvk)
+	}
+
+	if user, ok := obj.(*managementv3.User); ok {
+		user.Password = ""
+	}
+
+	data, err := yaml.Marshal(obj)
+	if err != nil {
+		return fmt.Errorf("unable to display %s: %w", obj.GetName(), err)
+	}
+
+	fmt.Fprintf(b.Options.Out, "---\n%s", data) // nolint: errcheck
+
+	return nil
+}
@@ -0,0 +1,105 @@
+/*
+Copyright 2023 SUSE.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package plugin
+
+import (
+	"context"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+
+	managementv3 "github.com/Danil-Grigorev/rancher-bind/pkg/apis/rancher/management/v3"
+)
+
+func TestDryRunApply(t *testing.T) {
+	g := NewWithT(t)
+	ctx := context.Background()
+
+	b := NewRancherBindOptions(genericclioptions.NewTestIOStreamsDiscard())
+	existing := &managementv3.GlobalRole{ObjectMeta: metav1.ObjectMeta{Name: "existing"}}
+
+	var creates, updates []client.Object
+	var updateDryRun []string
+	cl := fake.NewClientBuilder().WithScheme(b.Scheme).WithObjects(existing).WithInterceptorFuncs(interceptor.Funcs{
+		Create: func(ctx context.Context, c client.WithWatch, obj client.Object, opts ...client.CreateOption) error {
+			createOptions := &client.CreateOptions{}
+			createOptions.ApplyOptions(opts)
+			g.Expect(createOptions.DryRun).To(Equal([]string{metav1.DryRunAll}))
+			creates = append(creates, obj)
+
+			switch obj.GetName() {
+			case existing.Name:
+				return apierrors.NewAlreadyExists(schema.GroupResource{Group: managementv3.GroupVersion.Group, Resource: "globalroles"}, obj.GetName())
+			case "unserved":
+				return &meta.NoKindMatchError{GroupKind: schema.GroupKind{Group: managementv3.GroupVersion.Group, Kind: "GlobalRole"}}
+			}
+			return c.Create(ctx, obj, opts...)
+		},
+		Update: func(ctx context.Context, c client.WithWatch, obj client.Object, opts ...client.UpdateOption) error {
+			updateOptions := &client.UpdateOptions{}
+			updateOptions.ApplyOptions(opts)
+			updateDryRun = updateOptions.DryRun
+			updates = append(updates, obj)
+			return c.Update(ctx, obj, opts...)
+		},
+	}).Build()
+
+	// New objects are created with dry-run, and not persisted.
+	created := &managementv3.GlobalRole{ObjectMeta: metav1.ObjectMeta{Name: "created"}}
+	g.Expect(dryRunApply(ctx, cl, created)).To(Succeed())
+	g.Expect(creates).To(HaveLen(1))
+	g.Expect(updates).To(BeEmpty())
+	g.Expect(apierrors.IsNotFound(cl.Get(ctx, client.ObjectKeyFromObject(created), &managementv3.GlobalRole{}))).To(BeTrue())
+
+	// Existing objects fall back to a dry-run update of the current version.
+	g.Expect(cl.Get(ctx, client.ObjectKeyFromObject(existing), existing)).To(Succeed())
+	update := &managementv3.GlobalRole{ObjectMeta: metav1.ObjectMeta{Name: existing.Name}, DisplayName: "changed"}
+	g.Expect(dryRunApply(ctx, cl, update)).To(Succeed())
+	g.Expect(updates).To(HaveLen(1))
+	g.Expect(updateDryRun).To(Equal([]string{metav1.DryRunAll}))
+	g.Expect(update.ResourceVersion).To(Equal(existing.ResourceVersion))
+	g.Expect(cl.Get(ctx, client.ObjectKeyFromObject(existing), existing)).To(Succeed())
+	g.Expect(existing.DisplayName).To(BeEmpty())
+
+	// Kinds the cluster does not serve yet are rendered locally.
+	unserved := &managementv3.GlobalRole{ObjectMeta: metav1.ObjectMeta{Name: "unserved"}}
+	g.Expect(dryRunApply(ctx, cl, unserved)).To(Succeed())
+	g.Expect(updates).To(HaveLen(1))
+}
+
+func TestPrintObject(t *testing.T) {
+	g := NewWithT(t)
+
+	streams, _, out, _ := genericclioptions.NewTestIOStreams()
+	b := NewRancherBindOptions(streams)
+
+	user := NewUser("$2a$10$hash", "consumer", "")
+	g.Expect(b.printObject(user)).To(Succeed())
+	g.Expect(user.Password).To(BeEmpty())
+	g.Expect(out.String()).To(HavePrefix("---\n"))
+	g.Expect(out.String()).To(ContainSubstring("kind: User"))
+	g.Expect(out.String()).ToNot(ContainSubstring("$2a$10$hash"))
+}
@@ -0,0 +1,121 @@
+/*
+Copyright 2023 SUSE.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package plugin
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	managementv3 "github.com/Danil-Grigorev/rancher-bind/pkg/apis/rancher/management/v3"
+)
+
+const (
+	// ManagedByLabel marks the rancher objects created by rancher-bind.
+	ManagedByLabel = "app.kubernetes.io/managed-by"
+	// ManagedByValue is the ManagedByLabel value set by rancher-bind.
+	ManagedByValue = commonName
+)
+
+// NewGroupGlobalRoleBinding returns the binding of the role to the group principal,
+// e.g. okta_group://platform. Principal names are not valid object names, so the
+// binding name is derived from the role name and a hash of the principal.
+func NewGroupGlobalRoleBinding(group string, role *managementv3.GlobalRole) *managementv3.GlobalRoleBinding {
+	hash := sha256.Sum256([]byte(group))
+
+	return &managementv3.GlobalRoleBinding{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: fmt.Sprintf("%s-%s", role.Name, hex.EncodeToString(hash[:])[:10]),
+			Labels: map[string]string{
+				ManagedByLabel: ManagedByValue,
+			},
+		},
+		GlobalRoleName:     role.Name,
+		GroupPrincipalName: group,
+	}
+}
+
+// ApplyGroupGlobalRole creates the GlobalRole from the file and binds it to the group principal.
+func ApplyGroupGlobalRole(ctx context.Context, cl client.Client, group, path string) error {
+	role, err := LoadGlobalRole(path)
+	if err != nil {
+		return err
+	}
+
+	if err := createOrUpdate(ctx, cl, role, false); err != nil {
+		return fmt.Errorf("unable to create a group role: %w", err)
+	}
+
+	if err := createOrUpdate(ctx, cl, NewGroupGlobalRoleBinding(group, role), false); err != nil {
+		return fmt.Errorf("unable to create a group role binding: %w", err)
+	}
+
+	return nil
+}
+
+// ListGroupBindings returns the group bindings created by rancher-bind, optionally
+// limited to a single group principal.
+func ListGroupBindings(ctx context.Context, cl client.Client, group string) ([]managementv3.GlobalRoleBinding, error) {
+	list := &managementv3.GlobalRoleBindingList{}
+	if err := cl.List(ctx, list, client.MatchingLabels{ManagedByLabel: ManagedByValue}); err != nil {
+		return nil, fmt.Errorf("unable to list global role bindings: %w", err)
+	}
+
+	bindings := []managementv3.GlobalRoleBinding{}
+	for _, binding := range list.Items {
+		if binding.GroupPrincipalName == "" {
+			continue
+		}
+		if group != "" && binding.GroupPrincipalName != group {
+			continue
+		}
+
+		bindings = append(bindings, binding)
+	}
+
+	return bindings, nil
+}
+
+// RevokeGroupBindings removes the group bindings created by rancher-bind for the group
+// principal, optionally only those of a single GlobalRole. The GlobalRoles are kept.
+func RevokeGroupBindings(ctx context.Context, cl client.Client, group, role string) ([]managementv3.GlobalRoleBinding, error) {
+	bindings, err := ListGroupBindings(ctx, cl, group)
+	if err != nil {
+		return nil, err
+	}
+
+	revoked := []managementv3.GlobalRoleBinding{}
+	for i := range bindings {
+		if role != "" && bindings[i].GlobalRoleName != role {
+			continue
+		}
+
+		if err := delete(ctx, cl, &bindings[i]); err != nil && !apierrors.IsNotFound(err) {
+			return revoked, fmt.Errorf("unable to revoke role binding %s: %w", bindings[i].Name, err)
+		}
+
+		revoked = append(revoked, bindings[i])
+	}
+
+	return revoked, nil
+}
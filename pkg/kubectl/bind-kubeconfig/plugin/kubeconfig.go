@@ -31,15 +31,46 @@ import (
 
 const commonName = "rancher-bind"
 
+const (
+	// ServerURLSetting holds the rancher server URL.
+	ServerURLSetting = "server-url"
+	// KubeconfigGenerateTokenSetting controls whether generated kubeconfigs embed a token.
+	KubeconfigGenerateTokenSetting = "kubeconfig-generate-token"
+)
+
+// Auth providers supporting the username and password login.
+const (
+	LocalProvider           = "local"
+	ActiveDirectoryProvider = "activedirectory"
+	OpenLDAPProvider        = "openldap"
+	FreeIPAProvider         = "freeipa"
+)
+
+var providerCollections = map[string]string{
+	LocalProvider:           "localProviders",
+	ActiveDirectoryProvider: "activeDirectoryProviders",
+	OpenLDAPProvider:        "openLdapProviders",
+	FreeIPAProvider:         "freeIpaProviders",
+}
+
 func GetServer(ctx context.Context, cl client.Client) (string, error) {
-	serverUrl := &managementv3.Setting{ObjectMeta: metav1.ObjectMeta{
-		Name: "server-url",
+	return GetSetting(ctx, cl, ServerURLSetting)
+}
+
+// GetSetting returns the rancher setting value, or its default when unset.
+func GetSetting(ctx context.Context, cl client.Client, name string) (string, error) {
+	setting := &managementv3.Setting{ObjectMeta: metav1.ObjectMeta{
+		Name: name,
 	}}
-	if err := cl.Get(ctx, client.ObjectKeyFromObject(serverUrl), serverUrl); err != nil {
+	if err := cl.Get(ctx, client.ObjectKeyFromObject(setting), setting); err != nil {
 		return "", err
 	}
 
-	return serverUrl.Value, nil
+	if setting.Value == "" {
+		return setting.Default, nil
+	}
+
+	return setting.Value, nil
 }
 
 func HashPasswordString(password string) (string, error) {
@@ -109,20 +140,24 @@ func delete(ctx context.Context, cl client.Client, obj client.Object) error {
 	return cl.Delete(ctx, obj)
 }
 
-func CreateUser(ctx context.Context, cl client.Client, passwordHash string) (*managementv3.User, error) {
-	user := &managementv3.User{
+func NewUser(passwordHash, displayName, description string) *managementv3.User {
+	return &managementv3.User{
 		ObjectMeta: metav1.ObjectMeta{
 			Name: commonName,
 		},
-		Username: commonName,
-		Password: passwordHash,
+		DisplayName: displayName,
+		Description: description,
+		Username:    commonName,
+		Password:    passwordHash,
 	}
+}
 
+func CreateUser(ctx context.Context, cl client.Client, user *managementv3.User) error {
 	if err := createOrUpdate(ctx, cl, user, true); err != nil {
-		return nil, fmt.Errorf("unable to create a new user: %w", err)
+		return fmt.Errorf("unable to create a new user: %w", err)
 	}
 
-	return user, nil
+	return nil
 }
 
 func ResetPassword(ctx context.Context, cl client.Client) error {
@@ -141,8 +176,8 @@ func ResetPassword(ctx context.Context, cl client.Client) error {
 	return nil
 }
 
-func CreateClusterRole(ctx context.Context, cl client.Client, user *managementv3.User) (*managementv3.GlobalRole, error) {
-	role := &managementv3.GlobalRole{
+func NewClusterRole(user *managementv3.User) *managementv3.GlobalRole {
+	return &managementv3.GlobalRole{
 		ObjectMeta: metav1.ObjectMeta{
 			Name: user.Name,
 		},
@@ -167,6 +202,10 @@ func CreateClusterRole(ctx context.Context, cl client.Client, user *managementv3
 			},
 		},
 	}
+}
+
+func CreateClusterRole(ctx context.Context, cl client.Client, user *managementv3.User) (*managementv3.GlobalRole, error) {
+	role := NewClusterRole(user)
 
 	if err := createOrUpdate(ctx, cl, role, true); err != nil {
 		return nil, fmt.Errorf("unable to create a new role: %w", err)
@@ -175,14 +214,18 @@ func CreateClusterRole(ctx context.Context, cl client.Client, user *managementv3
 	return role, nil
 }
 
-func CreateRoleBinding(ctx context.Context, cl client.Client, user *managementv3.User) (*managementv3.GlobalRoleBinding, error) {
-	binding := &managementv3.GlobalRoleBinding{
+func NewRoleBinding(user *managementv3.User) *managementv3.GlobalRoleBinding {
+	return &managementv3.GlobalRoleBinding{
 		ObjectMeta: metav1.ObjectMeta{
 			Name: user.Name,
 		},
 		GlobalRoleName: user.Name,
 		UserName:       user.Name,
 	}
+}
+
+func CreateRoleBinding(ctx context.Context, cl client.Client, user *managementv3.User) (*managementv3.GlobalRoleBinding, error) {
+	binding := NewRoleBinding(user)
 
 	if err := createOrUpdate(ctx, cl, binding, true); err != nil {
 		return nil, fmt.Errorf("unable to create a new role binding: %w", err)
@@ -192,12 +235,19 @@ func CreateRoleBinding(ctx context.Context, cl client.Client, user *managementv3
 }
 
 func AuthenticateUser(serverUrl string, requestBody *apis.Login) (*apis.LoginResponse, error) {
-	loginURL := serverUrl + "/v3-public/localProviders/local?action=login"
-	client := &http.Client{Transport: &http.Transport{
-		TLSClientConfig: &tls.Config{
-			InsecureSkipVerify: true,
-		},
-	}}
+	return AuthenticateProviderUser(serverUrl, LocalProvider, requestBody)
+}
+
+// AuthenticateProviderUser logs in with username and password against one of the
+// local, Active Directory, OpenLDAP or FreeIPA auth providers.
+func AuthenticateProviderUser(serverUrl, provider string, requestBody *apis.Login) (*apis.LoginResponse, error) {
+	collection, ok := providerCollections[provider]
+	if !ok {
+		return nil, fmt.Errorf("unsupported auth provider %q", provider)
+	}
+
+	loginURL := fmt.Sprintf("%s/v3-public/%s/%s?action=login", serverUrl, collection, provider)
+	client := newHTTPClient()
 
 	requestDataJSON, err := json.Marshal(requestBody)
 	if err != nil {
@@ -215,6 +265,10 @@ func AuthenticateUser(serverUrl string, requestBody *apis.Login) (*apis.LoginRes
 		return nil, fmt.Errorf("reading token: %w", err)
 	}
 
+	if err := responseError(resp.StatusCode, data); err != nil {
+		return nil, fmt.Errorf("login failed: %w", err)
+	}
+
 	response := &apis.LoginResponse{}
 	if err := json.Unmarshal(data, response); err != nil {
 		return nil, fmt.Errorf("error parsing the login response: %w", err)
@@ -223,18 +277,40 @@ func AuthenticateUser(serverUrl string, requestBody *apis.Login) (*apis.LoginRes
 	return response, nil
 }
 
-func prepare(req *http.Request, token string) {
-	req.Header.Add("Authorization", "Basic "+base64.StdEncoding.EncodeToString([]byte(token)))
-	req.Header.Set("Content-Type", "application/json")
+func responseError(status int, data []byte) error {
+	if status < http.StatusBadRequest {
+		return nil
+	}
+
+	response := &apis.ErrorResponse{}
+	if err := json.Unmarshal(data, response); err != nil || response.Message == "" {
+		return fmt.Errorf("unexpected response status %d", status)
+	}
+
+	return fmt.Errorf("%s: %s", response.Code, response.Message)
 }
 
-func CollectKubeconfig(serverUrl, token string) (*apis.ConfigResponse, error) {
-	kubeconfigURL := serverUrl + "/v3/clusters/local?action=generateKubeconfig"
-	client := &http.Client{Transport: &http.Transport{
+func newHTTPClient() *http.Client {
+	return &http.Client{Transport: &http.Transport{
 		TLSClientConfig: &tls.Config{
 			InsecureSkipVerify: true,
 		},
 	}}
+}
+
+func prepare(req *http.Request, token string) {
+	req.Header.Add("Authorization", "Basic "+base64.StdEncoding.EncodeToString([]byte(token)))
+	req.Header.Set("Content-Type", "application/json")
+}
+
+func CollectKubeconfig(serverUrl, token string) (*apis.ConfigResponse, error) {
+	return CollectClusterKubeconfig(serverUrl, "local", token)
+}
+
+// CollectClusterKubeconfig generates the kubeconfig for the rancher cluster with the given ID.
+func CollectClusterKubeconfig(serverUrl, clusterID, token string) (*apis.ConfigResponse, error) {
+	kubeconfigURL := fmt.Sprintf("%s/v3/clusters/%s?action=generateKubeconfig", serverUrl, clusterID)
+	client := newHTTPClient()
 
 	req, err := http.NewRequest("POST", kubeconfigURL, nil)
 	if err != nil {
@@ -254,6 +330,10 @@ func CollectKubeconfig(serverUrl, token string) (*apis.ConfigResponse, error) {
 		return nil, fmt.Errorf("reading kubeconfig: %w", err)
 	}
 
+	if err := responseError(resp.StatusCode, data); err != nil {
+		return nil, fmt.Errorf("generating kubeconfig: %w", err)
+	}
+
 	response := &apis.ConfigResponse{}
 	if err := json.Unmarshal(data, response); err != nil {
 		return nil, fmt.Errorf("error parsing the kubeconfig response: %w", err)
@@ -262,11 +342,132 @@ func CollectKubeconfig(serverUrl, token string) (*apis.ConfigResponse, error) {
 	return response, nil
 }
 
+// CreateToken creates a rancher API token derived from the given one, scoped to the cluster.
+func CreateToken(serverUrl, token, clusterID, description string) (*apis.LoginResponse, error) {
+	return CreateTokenWithTTL(serverUrl, token, clusterID, description, 0)
+}
+
+// CreateTokenWithTTL creates a cluster scoped rancher API token expiring after the ttl.
+// A zero ttl uses the rancher default.
+func CreateTokenWithTTL(serverUrl, token, clusterID, description string, ttl time.Duration) (*apis.LoginResponse, error) {
+	requestDataJSON, err := json.Marshal(&apis.TokenRequest{
+		Type:        "token",
+		Description: description,
+		ClusterID:   clusterID,
+		TTL:         ttl.Milliseconds(),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error marshaling token request: %w", err)
+	}
+
+	req, err := http.NewRequest("POST", serverUrl+"/v3/tokens", bytes.NewBuffer(requestDataJSON))
+	if err != nil {
+		return nil, err
+	}
+
+	prepare(req, token)
+
+	resp, err := newHTTPClient().Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("creating token: %w", err)
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("reading token: %w", err)
+	}
+
+	if err := responseError(resp.StatusCode, data); err != nil {
+		return nil, fmt.Errorf("creating token: %w", err)
+	}
+
+	response := &apis.LoginResponse{}
+	if err := json.Unmarshal(data, response); err != nil {
+		return nil, fmt.Errorf("error parsing the token response: %w", err)
+	}
+
+	return response, nil
+}
+
+// Logout revokes the rancher API token.
+func Logout(serverUrl, token string) error {
+	req, err := http.NewRequest("POST", serverUrl+"/v3/tokens?action=logout", nil)
+	if err != nil {
+		return err
+	}
+
+	prepare(req, token)
+
+	resp, err := newHTTPClient().Do(req)
+	if err != nil {
+		return fmt.Errorf("logging out: %w", err)
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("reading logout response: %w", err)
+	}
+
+	if err := responseError(resp.StatusCode, data); err != nil {
+		return fmt.Errorf("logging out: %w", err)
+	}
+
+	return nil
+}
+
+// GetCurrentUser returns the rancher user owning the token.
+func GetCurrentUser(serverUrl, token string) (*apis.User, error) {
+	req, err := http.NewRequest("GET", serverUrl+"/v3/users?me=true", nil)
+	if err != nil {
+		return nil, err
+	}
+
+	prepare(req, token)
+
+	resp, err := newHTTPClient().Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("fetching current user: %w", err)
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("reading current user: %w", err)
+	}
+
+	if err := responseError(resp.StatusCode, data); err != nil {
+		return nil, fmt.Errorf("fetching current user: %w", err)
+	}
+
+	users := &apis.UserCollection{}
+	if err := json.Unmarshal(data, users); err != nil {
+		return nil, fmt.Errorf("error parsing the users response: %w", err)
+	}
+
+	if len(users.Data) != 1 {
+		return nil, errors.New("unable to identify the token user")
+	}
+
+	return &users.Data[0], nil
+}
+
 func ApplyUserGlobalRole(ctx context.Context, cl client.Client, username, path string) error {
-	file, err := os.Open(path)
+	role, err := LoadGlobalRole(path)
 	if err != nil {
 		return err
 	}
+
+	return applyUserGlobalRole(ctx, cl, username, role)
+}
+
+// LoadGlobalRole reads the GlobalRole manifest from the given file.
+func LoadGlobalRole(path string) (*managementv3.GlobalRole, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
 	defer file.Close()
 
 	decoder := utilyaml.NewYAMLDecoder(file)
@@ -275,29 +476,34 @@ func ApplyUserGlobalRole(ctx context.Context, cl client.Client, username, path s
 	u := &unstructured.Unstructured{}
 	_, gvk, err := decoder.Decode(nil, u)
 	if err != nil {
-		return fmt.Errorf("unable to decode provided manifest: %w", err)
+		return nil, fmt.Errorf("unable to decode provided manifest: %w", err)
 	}
 
 	switch gvk.Kind {
 	case "GlobalRole":
 		role := &managementv3.GlobalRole{}
 		if err := runtime.DefaultUnstructuredConverter.FromUnstructured(u.Object, role); err != nil {
-			return fmt.Errorf("cannot convert object to GlobalRole: %w", err)
+			return nil, fmt.Errorf("cannot convert object to GlobalRole: %w", err)
 		}
 
-		return applyUserGlobalRole(ctx, cl, username, role)
+		return role, nil
 	default:
-		return errors.New("unknown resource kind provided")
+		return nil, errors.New("unknown resource kind provided")
 	}
 }
 
-func applyUserGlobalRole(ctx context.Context, cl client.Client, username string, role *managementv3.GlobalRole) error {
-	roleBinding := &managementv3.GlobalRoleBinding{ObjectMeta: metav1.ObjectMeta{
-		Name: role.Name,
-	},
+func NewUserGlobalRoleBinding(username string, role *managementv3.GlobalRole) *managementv3.GlobalRoleBinding {
+	return &managementv3.GlobalRoleBinding{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: role.Name,
+		},
 		GlobalRoleName: role.Name,
 		UserName:       username,
 	}
+}
+
+func applyUserGlobalRole(ctx context.Context, cl client.Client, username string, role *managementv3.GlobalRole) error {
+	roleBinding := NewUserGlobalRoleBinding(username, role)
 
 	if err := createOrUpdate(ctx, cl, role, false); err != nil {
 		return fmt.Errorf("unable to create a user role: %w", err)
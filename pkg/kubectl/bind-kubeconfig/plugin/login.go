@@ -0,0 +1,228 @@
+/*
+Copyright 2023 SUSE.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package plugin
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/spf13/pflag"
+	"golang.org/x/term"
+
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+
+	apis "github.com/Danil-Grigorev/rancher-bind/pkg/apis"
+)
+
+// PasswordEnv is the environment variable the existing user password is read from.
+const PasswordEnv = "RANCHER_PASSWORD"
+
+// LoginOptions are the options to authenticate as an existing rancher user.
+type LoginOptions struct {
+	genericclioptions.IOStreams
+
+	Username      string
+	AuthProvider  string
+	PasswordStdin bool
+	SSO           bool
+	NoBrowser     bool
+}
+
+// NewLoginOptions returns new LoginOptions.
+func NewLoginOptions(streams genericclioptions.IOStreams) *LoginOptions {
+	return &LoginOptions{
+		IOStreams:    streams,
+		AuthProvider: LocalProvider,
+	}
+}
+
+// AddFlags binds fields to the flagset.
+func (l *LoginOptions) AddFlags(flags *pflag.FlagSet, usernameUsage string) {
+	flags.StringVar(&l.Username, "username", l.Username, usernameUsage)
+	flags.StringVar(&l.AuthProvider, "auth-provider", l.AuthProvider, "Auth provider of the existing user, one of local, activedirectory, openldap or freeipa")
+	flags.BoolVar(&l.PasswordStdin, "password-stdin", l.PasswordStdin, "Read the existing user password from stdin instead of "+PasswordEnv+" or a prompt")
+	flags.BoolVar(&l.SSO, "sso", l.SSO, "Log in as an existing user in the browser, for SAML or OIDC backed installations")
+	flags.BoolVar(&l.NoBrowser, "no-browser", l.NoBrowser, "Only print the --sso login URL instead of opening the browser")
+}
+
+// Enabled returns true when an existing user login is requested.
+func (l *LoginOptions) Enabled() bool {
+	return l.Username != "" || l.SSO
+}
+
+// Validate validates the LoginOptions are consistent.
+func (l *LoginOptions) Validate() error {
+	switch {
+	case l.SSO && (l.Username != "" || l.PasswordStdin):
+		return errors.New("sso login does not accept username or password")
+	case l.Username == "" && l.PasswordStdin:
+		return errors.New("password-stdin requires username")
+	}
+
+	if _, ok := providerCollections[l.AuthProvider]; !ok {
+		return fmt.Errorf("unsupported auth provider %q", l.AuthProvider)
+	}
+
+	return nil
+}
+
+// Login authenticates the user in the browser or with the password. A non-empty
+// responseType is passed to rancher to request a token of that kind, e.g. scoped
+// to a single cluster.
+func (l *LoginOptions) Login(ctx context.Context, serverUrl, responseType string) (*apis.LoginResponse, error) {
+	if l.SSO {
+		ctx, cancel := context.WithTimeout(ctx, DefaultSSOTimeout)
+		defer cancel()
+
+		return SSOLogin(ctx, serverUrl, responseType, l.openLogin, DefaultSSOPollInterval)
+	}
+
+	password, err := l.readPassword()
+	if err != nil {
+		return nil, err
+	}
+
+	return AuthenticateProviderUser(serverUrl, l.AuthProvider, &apis.Login{
+		Username:     l.Username,
+		Password:     password,
+		ResponseType: responseType,
+	})
+}
+
+// openLogin prints the login url and opens it in the browser, if allowed.
+func (l *LoginOptions) openLogin(url string) error {
+	fmt.Fprintf(l.ErrOut, "\nTo authenticate, visit in your browser:\n\n\t%s\n\n", url) // nolint: errcheck
+
+	if l.NoBrowser {
+		return nil
+	}
+
+	if err := OpenBrowser(url); err != nil {
+		fmt.Fprintf(l.ErrOut, "Unable to open the browser: %v\n", err) // nolint: errcheck
+	}
+
+	return nil
+}
+
+// readPassword returns the user password from stdin, the environment or an interactive prompt.
+func (l *LoginOptions) readPassword() (string, error) {
+	if l.PasswordStdin {
+		line, err := bufio.NewReader(l.In).ReadString('\n')
+		if err != nil && !errors.Is(err, io.EOF) {
+			return "", fmt.Errorf("unable to read password from stdin: %w", err)
+		}
+
+		return strings.TrimRight(line, "\r\n"), nil
+	}
+
+	if password, ok := os.LookupEnv(PasswordEnv); ok {
+		return password, nil
+	}
+
+	stdin, ok := l.In.(*os.File)
+	if !ok || !term.IsTerminal(int(stdin.Fd())) {
+		return "", fmt.Errorf("no password provided, use --password-stdin or %s", PasswordEnv)
+	}
+
+	fmt.Fprintf(l.ErrOut, "Password for %s: ", l.Username) // nolint: errcheck
+	password, err := term.ReadPassword(int(stdin.Fd()))
+	fmt.Fprintln(l.ErrOut) // nolint: errcheck
+	if err != nil {
+		return "", fmt.Errorf("unable to read password: %w", err)
+	}
+
+	return string(password), nil
+}
+
+// RunLogin generates a kubeconfig for an existing rancher user.
+//
+// Flow:
+// - Fetch the setting pointing to the rancher url, unless provided.
+// - Authenticate as the user with the configured auth provider, or in the browser with --sso.
+// - Collect the kubeconfig generated from the given token.
+// - Optionally bind the provided GlobalRole to the user.
+// - Log out the login token, the kubeconfig holds its own token.
+func (b *BindAPIServiceOptions) RunLogin(ctx context.Context) error {
+	serverUrl, err := b.GetServerURL(ctx)
+	if err != nil {
+		return err
+	}
+
+	token, err := b.Login.Login(ctx, serverUrl, "")
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err := Logout(serverUrl, token.Token); err != nil {
+			fmt.Fprintf(b.Options.ErrOut, "Unable to log out the login token: %v\n", err) // nolint: errcheck
+		}
+	}()
+
+	config, err := b.CollectKubeconfig(ctx, serverUrl, token.Token)
+	if err != nil {
+		return err
+	}
+
+	if b.file != "" {
+		if token.UserID == "" {
+			user, err := GetCurrentUser(serverUrl, token.Token)
+			if err != nil {
+				return err
+			}
+			token.UserID = user.ID
+		}
+
+		cl, err := b.GetClient()
+		if err != nil {
+			return err
+		}
+
+		if err := ApplyUserGlobalRole(ctx, cl, token.UserID, b.file); err != nil {
+			return err
+		}
+	}
+
+	return b.DisplayKubeconfig(config)
+}
+
+// GetServerURL returns the --server-url value, or the rancher server-url setting.
+func (b *BindAPIServiceOptions) GetServerURL(ctx context.Context) (string, error) {
+	if b.serverURL != "" {
+		return b.serverURL, nil
+	}
+
+	if b.server != "" {
+		b.serverURL = strings.TrimSuffix(b.server, "/")
+		return b.serverURL, nil
+	}
+
+	cl, err := b.GetClient()
+	if err != nil {
+		return "", err
+	}
+
+	if b.serverURL, err = GetServer(ctx, cl); err != nil {
+		return "", err
+	}
+
+	return b.serverURL, nil
+}
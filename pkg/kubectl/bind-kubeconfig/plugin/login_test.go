@@ -0,0 +1,108 @@
+/*
+Copyright 2023 SUSE.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package plugin
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	. "github.com/onsi/gomega"
+
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+
+	apis "github.com/Danil-Grigorev/rancher-bind/pkg/apis"
+)
+
+const generatedKubeconfig = `apiVersion: v1
+kind: Config
+clusters:
+- name: downstream
+  cluster:
+    server: https://rancher.example.com/k8s/clusters/c-abcde
+users:
+- name: downstream
+  user:
+    token: kubeconfig-u-abcde:secret
+contexts:
+- name: downstream
+  context:
+    cluster: downstream
+    user: downstream
+current-context: downstream
+`
+
+// fakePasswordRancher stands in for the rancher password login and kubeconfig API.
+type fakePasswordRancher struct {
+	sync.Mutex
+
+	requests []string
+}
+
+func (f *fakePasswordRancher) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.Lock()
+	defer f.Unlock()
+	f.requests = append(f.requests, r.Method+" "+r.URL.RequestURI())
+
+	switch r.URL.RequestURI() {
+	case "/v3-public/localProviders/local?action=login":
+		json.NewEncoder(w).Encode(&apis.LoginResponse{Token: "token-login:secret", UserID: "u-abcde"}) // nolint: errcheck
+	case "/v3/tokens?action=logout":
+		if r.Header.Get("Authorization") != "Basic "+base64.StdEncoding.EncodeToString([]byte("token-login:secret")) {
+			w.WriteHeader(http.StatusUnauthorized)
+		}
+	case "/v3/clusters/c-abcde?action=generateKubeconfig":
+		json.NewEncoder(w).Encode(&apis.ConfigResponse{Config: generatedKubeconfig}) // nolint: errcheck
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func TestRunLoginWithoutProviderKubeconfig(t *testing.T) {
+	g := NewWithT(t)
+
+	rancher := &fakePasswordRancher{}
+	server := httptest.NewTLSServer(rancher)
+	defer server.Close()
+
+	streams := genericclioptions.IOStreams{In: strings.NewReader("password\n"), Out: &bytes.Buffer{}, ErrOut: &bytes.Buffer{}}
+	b := NewRancherBindOptions(streams)
+	b.Options.ClientConfig = clientcmd.NewDefaultClientConfig(*clientcmdapi.NewConfig(), &clientcmd.ConfigOverrides{})
+	b.Login.Username = "admin"
+	b.Login.PasswordStdin = true
+	b.server = server.URL
+	b.cluster = "c-abcde"
+
+	// Without a client the setting and the cluster can not be read, the kubeconfig is used as generated.
+	g.Expect(b.RunLogin(context.Background())).To(Succeed())
+	g.Expect(rancher.requests).To(ContainElements(
+		"POST /v3-public/localProviders/local?action=login",
+		"POST /v3/clusters/c-abcde?action=generateKubeconfig",
+	))
+	// The login token is logged out once the kubeconfig is collected.
+	g.Expect(rancher.requests[len(rancher.requests)-1]).To(Equal("POST /v3/tokens?action=logout"))
+	g.Expect(streams.ErrOut.(*bytes.Buffer).String()).ToNot(ContainSubstring("Unable to log out"))
+	g.Expect(rancher.requests).ToNot(ContainElement("POST /v3/tokens"))
+}
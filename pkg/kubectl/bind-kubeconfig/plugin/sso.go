@@ -0,0 +1,205 @@
+/*
+Copyright 2023 SUSE.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package plugin
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os/exec"
+	"runtime"
+	"time"
+
+	"github.com/sethvargo/go-password/password"
+
+	apis "github.com/Danil-Grigorev/rancher-bind/pkg/apis"
+)
+
+const (
+	// DefaultSSOPollInterval is how often the auth token is checked during a browser login.
+	DefaultSSOPollInterval = 5 * time.Second
+	// DefaultSSOTimeout is how long the user has to complete the browser login.
+	DefaultSSOTimeout = 30 * time.Minute
+
+	// maxSSOPollBackoff caps the poll interval while the auth token endpoint keeps failing.
+	maxSSOPollBackoff = time.Minute
+)
+
+// transientError is a failure of the auth token endpoint worth retrying.
+type transientError struct {
+	error
+}
+
+// SSOLogin performs the browser based login, the same way the rancher CLI does.
+//
+// Flow:
+// - Generate a key pair and a random auth token request id.
+// - Open the rancher login page for the request id and public key.
+// - Poll the public auth token endpoint until the login is completed.
+// - Decrypt the token with the private key and remove the auth token.
+//
+// The responseType defaults to a kubeconfig token.
+func SSOLogin(ctx context.Context, serverUrl, responseType string, open func(string) error, interval time.Duration) (*apis.LoginResponse, error) {
+	if responseType == "" {
+		responseType = "kubeconfig"
+	}
+
+	privateKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		return nil, fmt.Errorf("problem generating key: %w", err)
+	}
+
+	publicKey, err := json.Marshal(privateKey.PublicKey)
+	if err != nil {
+		return nil, fmt.Errorf("problem encoding key: %w", err)
+	}
+
+	id, err := password.Generate(16, 4, 0, true, true)
+	if err != nil {
+		return nil, fmt.Errorf("problem generating request id: %w", err)
+	}
+
+	loginURL := fmt.Sprintf("%s/dashboard/auth/login?%s", serverUrl, url.Values{
+		"requestId":    []string{id},
+		"publicKey":    []string{base64.StdEncoding.EncodeToString(publicKey)},
+		"responseType": []string{responseType},
+	}.Encode())
+	if err := open(loginURL); err != nil {
+		return nil, err
+	}
+
+	tokenURL := fmt.Sprintf("%s/v3-public/authTokens/%s", serverUrl, id)
+	client := newHTTPClient()
+
+	authToken := &apis.AuthToken{}
+	if err := pollAuthToken(ctx, client, tokenURL, interval, authToken); err != nil {
+		return nil, fmt.Errorf("waiting for the browser login: %w", err)
+	}
+
+	encrypted, err := base64.StdEncoding.DecodeString(authToken.Token)
+	if err != nil {
+		return nil, fmt.Errorf("error decoding the auth token: %w", err)
+	}
+
+	token, err := privateKey.Decrypt(nil, encrypted, &rsa.OAEPOptions{Hash: crypto.SHA256})
+	if err != nil {
+		return nil, fmt.Errorf("error decrypting the auth token: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodDelete, tokenURL, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("removing auth token: %w", err)
+	}
+	resp.Body.Close()
+
+	return &apis.LoginResponse{Token: string(token), ExpiresAt: authToken.ExpiresAt}, nil
+}
+
+// pollAuthToken polls the auth token endpoint until the login is completed. Network errors,
+// server errors and throttling are retried with an exponential backoff until the context
+// is done, other errors abort the login.
+func pollAuthToken(ctx context.Context, client *http.Client, tokenURL string, interval time.Duration, authToken *apis.AuthToken) error {
+	delay := interval
+	var lastErr error
+	for {
+		select {
+		case <-ctx.Done():
+			if lastErr != nil {
+				return fmt.Errorf("%w, last error: %v", ctx.Err(), lastErr)
+			}
+			return ctx.Err()
+		case <-time.After(delay):
+		}
+
+		done, err := fetchAuthToken(ctx, client, tokenURL, authToken)
+		var transient transientError
+		switch {
+		case err == nil:
+			delay, lastErr = interval, nil
+		case errors.As(err, &transient):
+			delay, lastErr = min(2*delay, max(interval, maxSSOPollBackoff)), err
+		default:
+			return err
+		}
+
+		if done {
+			return nil
+		}
+	}
+}
+
+// fetchAuthToken fetches the auth token, returning true once the login is completed.
+func fetchAuthToken(ctx context.Context, client *http.Client, tokenURL string, authToken *apis.AuthToken) (bool, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, tokenURL, nil)
+	if err != nil {
+		return false, err
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return false, transientError{fmt.Errorf("fetching auth token: %w", err)}
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return false, nil
+	}
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return false, transientError{fmt.Errorf("reading auth token: %w", err)}
+	}
+
+	if err := responseError(resp.StatusCode, data); err != nil {
+		err = fmt.Errorf("fetching auth token: %w", err)
+		if resp.StatusCode >= http.StatusInternalServerError || resp.StatusCode == http.StatusTooManyRequests {
+			return false, transientError{err}
+		}
+		return false, err
+	}
+
+	if err := json.Unmarshal(data, authToken); err != nil {
+		return false, fmt.Errorf("error parsing the auth token response: %w", err)
+	}
+
+	return authToken.Token != "", nil
+}
+
+// OpenBrowser opens the url in the default browser.
+func OpenBrowser(url string) error {
+	switch runtime.GOOS {
+	case "darwin":
+		return exec.Command("open", url).Start()
+	case "windows":
+		return exec.Command("rundll32", "url.dll,FileProtocolHandler", url).Start()
+	default:
+		return exec.Command("xdg-open", url).Start()
+	}
+}
@@ -0,0 +1,155 @@
+/*
+Copyright 2023 SUSE.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package plugin
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+
+	apis "github.com/Danil-Grigorev/rancher-bind/pkg/apis"
+)
+
+// fakeRancher stands in for the rancher public auth token API during a browser login.
+type fakeRancher struct {
+	sync.Mutex
+
+	requestID string
+	publicKey *rsa.PublicKey
+	token     string
+	deleted   bool
+
+	// failures is the number of auth token requests failing before the token is served.
+	failures int
+}
+
+func (f *fakeRancher) login(loginURL string) error {
+	u, err := url.Parse(loginURL)
+	if err != nil {
+		return err
+	}
+
+	raw, err := base64.StdEncoding.DecodeString(u.Query().Get("publicKey"))
+	if err != nil {
+		return err
+	}
+
+	key := &rsa.PublicKey{}
+	if err := json.Unmarshal(raw, key); err != nil {
+		return err
+	}
+
+	f.Lock()
+	defer f.Unlock()
+	f.requestID = u.Query().Get("requestId")
+	f.publicKey = key
+
+	return nil
+}
+
+func (f *fakeRancher) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.Lock()
+	defer f.Unlock()
+
+	if f.publicKey == nil || r.URL.Path != "/v3-public/authTokens/"+f.requestID {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodDelete:
+		f.deleted = true
+	case http.MethodGet:
+		if f.failures > 0 {
+			f.failures--
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+
+		encrypted, err := rsa.EncryptOAEP(sha256.New(), rand.Reader, f.publicKey, []byte(f.token), nil)
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		json.NewEncoder(w).Encode(&apis.AuthToken{ // nolint: errcheck
+			ID:    f.requestID,
+			Token: base64.StdEncoding.EncodeToString(encrypted),
+		})
+	}
+}
+
+func TestSSOLogin(t *testing.T) {
+	g := NewWithT(t)
+
+	rancher := &fakeRancher{token: "token-abcde:secret"}
+	server := httptest.NewServer(rancher)
+	defer server.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var opened string
+	token, err := SSOLogin(ctx, server.URL, "", func(loginURL string) error {
+		opened = loginURL
+		return rancher.login(loginURL)
+	}, 10*time.Millisecond)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(token.Token).To(Equal("token-abcde:secret"))
+	g.Expect(opened).To(HavePrefix(server.URL + "/dashboard/auth/login?"))
+	g.Expect(rancher.deleted).To(BeTrue())
+}
+
+func TestSSOLoginTimeout(t *testing.T) {
+	g := NewWithT(t)
+
+	server := httptest.NewServer(&fakeRancher{})
+	defer server.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	_, err := SSOLogin(ctx, server.URL, "", func(string) error { return nil }, 10*time.Millisecond)
+	g.Expect(err).To(HaveOccurred())
+}
+
+func TestSSOLoginRetriesTransientErrors(t *testing.T) {
+	g := NewWithT(t)
+
+	rancher := &fakeRancher{token: "token-abcde:secret", failures: 3}
+	server := httptest.NewServer(rancher)
+	defer server.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	token, err := SSOLogin(ctx, server.URL, "", rancher.login, 10*time.Millisecond)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(token.Token).To(Equal("token-abcde:secret"))
+	g.Expect(rancher.failures).To(BeZero())
+}